@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"rpi-metrics/internal/hub"
+	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/secret"
+	"rpi-metrics/internal/webui"
+)
+
+func main() {
+	port := flag.Int("port", 8081, "HTTP server port")
+	bind := flag.String("bind", "", "address to listen on, e.g. 127.0.0.1 (default: all interfaces)")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file; serves HTTPS when set with -tls-key")
+	tlsKey := flag.String("tls-key", "", "TLS private key file")
+	tlsSelfSigned := flag.Bool("tls-self-signed", false, "serve HTTPS with a self-signed certificate, generated on first run at -tls-cert/-tls-key (default: in the user config dir)")
+	authFile := flag.String("auth-file", "", "JSON file of basic auth users and bearer tokens for the dashboard and read API; pushes use -tokens")
+	corsOrigins := flag.String("cors-origins", "", "comma separated origins allowed to read the API from other pages (\"*\" for any; default: same origin only)")
+	tokensPath := flag.String("tokens", "", `JSON file of per-agent push tokens: {"host": "token", ...} (required)`)
+	history := flag.Duration("history", time.Hour, "how much history to keep per series")
+	staleAfter := flag.Duration("stale-after", 2*time.Minute, "mark a host stale after this long without a push")
+	flag.Parse()
+
+	if *tokensPath == "" {
+		log.Fatalf("-tokens is required")
+	}
+	tokens, err := hub.LoadTokens(*tokensPath)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := secret.CheckFileMode(*tokensPath); err != nil {
+		log.Printf("warning: -tokens: %v", err)
+	}
+
+	if *tlsSelfSigned && (*tlsCert == "" || *tlsKey == "") {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			log.Fatalf("-tls-self-signed: %v; set -tls-cert and -tls-key", err)
+		}
+		*tlsCert = filepath.Join(dir, "rpi-metrics", "hub-cert.pem")
+		*tlsKey = filepath.Join(dir, "rpi-metrics", "hub-key.pem")
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Fatalf("-tls-cert and -tls-key must be set together")
+	}
+	if *tlsSelfSigned {
+		fingerprint, created, err := webui.EnsureSelfSignedCert(*tlsCert, *tlsKey)
+		if err != nil {
+			log.Fatalf("self-signed certificate: %v", err)
+		}
+		if created {
+			log.Printf("created self-signed certificate %s", *tlsCert)
+		}
+		log.Printf("certificate SHA-256 fingerprint: %s", fingerprint)
+	}
+
+	var creds *webui.Credentials
+	if *authFile != "" {
+		c, err := webui.LoadCredentials(*authFile)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if err := secret.CheckFileMode(*authFile); err != nil {
+			log.Printf("warning: -auth-file: %v", err)
+		}
+		creds = &c
+	} else {
+		log.Printf("warning: no -auth-file; anyone who can reach the hub can read every host's metrics")
+	}
+
+	h := &hub.Hub{Tokens: tokens, History: *history, StaleAfter: *staleAfter}
+
+	// Dashboard and read API, behind -auth-file
+	mux := http.NewServeMux()
+	mux.Handle("/api/", h.Handler())
+
+	// Metric descriptors, so the frontend can format values by canonical unit
+	mux.HandleFunc("/api/descriptors", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(metrics.Descriptors()); err != nil {
+			log.Printf("json encode error: %v", err)
+		}
+	})
+
+	// Fleet overview at /, single-host view at /?host=<name>
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		page := "fleet.html"
+		if r.URL.Query().Get("host") != "" {
+			page = "index.html"
+		}
+		http.ServeFileFS(w, r, webui.FS(), page)
+	})
+	mux.Handle("/", http.FileServer(http.FS(webui.FS())))
+
+	var read http.Handler = mux
+	if creds != nil {
+		read = webui.RequireAuth(*creds, nil, read)
+	}
+	// Agents authenticate pushes with their own -tokens, not -auth-file.
+	root := http.NewServeMux()
+	root.Handle("POST /api/push", h.Handler())
+	root.Handle("/", read)
+	// Outermost, so preflight requests are answered without credentials.
+	handler := webui.CORS(metrics.SplitCSV(*corsOrigins), root)
+
+	// Create server
+	server := &http.Server{
+		Addr:         net.JoinHostPort(*bind, strconv.Itoa(*port)),
+		Handler:      handler,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	// Graceful shutdown
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		var err error
+		if *tlsCert != "" {
+			log.Printf("Starting hub on https://%s (%d agents)", server.Addr, len(tokens))
+			err = server.ListenAndServeTLS(*tlsCert, *tlsKey)
+		} else {
+			log.Printf("Starting hub on http://%s (%d agents)", server.Addr, len(tokens))
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("server error: %v", err)
+		}
+	}()
+
+	<-done
+	log.Println("Shutting down hub...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("server shutdown error: %v", err)
+	}
+	log.Println("Hub stopped")
+}
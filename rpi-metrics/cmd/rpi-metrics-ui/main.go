@@ -2,25 +2,24 @@ package main
 
 import (
 	"context"
-	"embed"
 	"encoding/json"
 	"flag"
-	"fmt"
-	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
 	"rpi-metrics/internal/collectors"
 	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/secret"
+	"rpi-metrics/internal/webui"
 )
 
-//go:embed frontend/*
-var frontendFS embed.FS
-
 type MetricsResponse struct {
 	Timestamp time.Time                   `json:"timestamp"`
 	Metrics   map[string][]metrics.Sample `json:"metrics"`
@@ -29,15 +28,67 @@ type MetricsResponse struct {
 
 func main() {
 	port := flag.Int("port", 8080, "HTTP server port")
+	bind := flag.String("bind", "", "address to listen on, e.g. 127.0.0.1 (default: all interfaces)")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file; serves HTTPS when set with -tls-key")
+	tlsKey := flag.String("tls-key", "", "TLS private key file")
+	tlsSelfSigned := flag.Bool("tls-self-signed", false, "serve HTTPS with a self-signed certificate, generated on first run at -tls-cert/-tls-key (default: in the user config dir)")
+	authFile := flag.String("auth-file", "", "JSON file of basic auth users and bearer tokens; every request needs one of them when set")
+	corsOrigins := flag.String("cors-origins", "", "comma separated origins allowed to read the API from other pages (\"*\" for any; default: same origin only)")
+	rateLimit := flag.Float64("rate-limit", 5, "requests per second allowed per client IP (0 disables)")
+	rateBurst := flag.Int("rate-burst", 20, "requests a client may burst above -rate-limit")
 	flag.Parse()
 
+	if *tlsSelfSigned && (*tlsCert == "" || *tlsKey == "") {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			log.Fatalf("-tls-self-signed: %v; set -tls-cert and -tls-key", err)
+		}
+		*tlsCert = filepath.Join(dir, "rpi-metrics", "ui-cert.pem")
+		*tlsKey = filepath.Join(dir, "rpi-metrics", "ui-key.pem")
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Fatalf("-tls-cert and -tls-key must be set together")
+	}
+	if *tlsSelfSigned {
+		fingerprint, created, err := webui.EnsureSelfSignedCert(*tlsCert, *tlsKey)
+		if err != nil {
+			log.Fatalf("self-signed certificate: %v", err)
+		}
+		if created {
+			log.Printf("created self-signed certificate %s", *tlsCert)
+		}
+		log.Printf("certificate SHA-256 fingerprint: %s", fingerprint)
+	}
+
+	var creds *webui.Credentials
+	if *authFile != "" {
+		c, err := webui.LoadCredentials(*authFile)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if err := secret.CheckFileMode(*authFile); err != nil {
+			log.Printf("warning: -auth-file: %v", err)
+		}
+		creds = &c
+	}
+
 	// Initialize collectors
 	cpuTemp := &collectors.CPUTempSysfs{}
 	cpuUtil := &collectors.CPUUtilizationProcfs{}
 	cpuCooling := &collectors.CPUCoolingDevicefs{}
 	storage := &collectors.StorageStatfs{Paths: []string{"/", "/boot"}}
+	mmcHealth := &collectors.MMCHealthSysfs{}
+	network := &collectors.NetworkSysfs{}
+
+	allCollectors := []metrics.Collector{cpuTemp, cpuUtil, cpuCooling, storage, mmcHealth, network}
 
-	allCollectors := []metrics.Collector{cpuTemp, cpuUtil, cpuCooling, storage}
+	// Derive cpu_utilization from the CPU time counters, as the agent's pipeline does.
+	// Both endpoints share the rate stage, so utilization covers the time since the
+	// last request of either.
+	cpuRates := &metrics.RateStage{Names: []string{metrics.CPUTimeCounter}}
+	processCPU := func(res metrics.Result) metrics.Result {
+		return metrics.CPUUtilization{}.Process(cpuRates.Process(res))
+	}
 
 	// Create router
 	mux := http.NewServeMux()
@@ -45,7 +96,6 @@ func main() {
 	// API endpoint for metrics
 	mux.HandleFunc("/api/metrics", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 
 		ctx := r.Context()
 		response := MetricsResponse{
@@ -59,6 +109,10 @@ func main() {
 				log.Printf("collector %s error: %v", c.ID(), err)
 				continue
 			}
+			if c == cpuUtil {
+				// The frontend shows the utilization, not the counters behind it.
+				samples = filterSamples(processCPU(metrics.Result{Samples: samples}).Samples, "cpu_utilization")
+			}
 			response.Metrics[c.ID()] = samples
 		}
 
@@ -67,17 +121,58 @@ func main() {
 		}
 	})
 
+	// Metric descriptors, so the frontend can format values by canonical unit
+	mux.HandleFunc("/api/descriptors", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(metrics.Descriptors()); err != nil {
+			log.Printf("json encode error: %v", err)
+		}
+	})
+
+	// Prometheus scrape endpoint
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		res := processCPU(metrics.Runner{Collectors: allCollectors}.CollectOnce(r.Context()))
+		for _, e := range res.Errors {
+			log.Printf("collector %s error: %s", e.CollectorID, e.Error)
+		}
+		if err := metrics.WritePrometheusText(w, res.Samples); err != nil {
+			log.Printf("prometheus write error: %v", err)
+		}
+	})
+
 	// Serve frontend static files
-	frontendContent, err := fs.Sub(frontendFS, "frontend")
-	if err != nil {
-		log.Fatalf("failed to get frontend subdirectory: %v", err)
+	mux.Handle("/", http.FileServer(http.FS(webui.FS())))
+
+	// The API and /metrics run the collectors, so they are rate limited; static files
+	// are not, or loading the page would use up a client's burst. Failed logins count
+	// against the limit on every path, so passwords cannot be guessed through the
+	// static files either.
+	var limit *webui.RateLimiter
+	if *rateLimit > 0 {
+		limit = &webui.RateLimiter{Rate: *rateLimit, Burst: *rateBurst}
+	}
+	var handler http.Handler = mux
+	if creds != nil {
+		handler = webui.RequireAuth(*creds, limit, handler)
 	}
-	mux.Handle("/", http.FileServer(http.FS(frontendContent)))
+	if limit != nil {
+		limited := limit.Middleware(handler)
+		root := http.NewServeMux()
+		root.Handle("/", handler)
+		root.Handle("/api/", limited)
+		root.Handle("/metrics", limited)
+		handler = root
+	}
+	// Outermost, so preflight requests are answered without credentials.
+	handler = webui.CORS(metrics.SplitCSV(*corsOrigins), handler)
 
 	// Create server
 	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", *port),
-		Handler:      mux,
+		Addr:         net.JoinHostPort(*bind, strconv.Itoa(*port)),
+		Handler:      handler,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
@@ -87,8 +182,15 @@ func main() {
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		log.Printf("Starting server on http://localhost:%d", *port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if *tlsCert != "" {
+			log.Printf("Starting server on https://%s", server.Addr)
+			err = server.ListenAndServeTLS(*tlsCert, *tlsKey)
+		} else {
+			log.Printf("Starting server on http://%s", server.Addr)
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("server error: %v", err)
 		}
 	}()
@@ -104,3 +206,14 @@ func main() {
 	}
 	log.Println("Server stopped")
 }
+
+// filterSamples keeps the samples called name.
+func filterSamples(samples []metrics.Sample, name string) []metrics.Sample {
+	var out []metrics.Sample
+	for _, s := range samples {
+		if s.Name == name {
+			out = append(out, s)
+		}
+	}
+	return out
+}
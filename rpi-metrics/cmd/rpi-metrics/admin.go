@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"rpi-metrics/internal/secret"
+)
+
+// serveAdmin serves the agent's admin API on addr until ctx is done:
+//
+//	POST /-/reload   reload the config, as SIGHUP does; 200 when applied, 422 with
+//	                 the reason when rejected (the old config keeps running)
+//
+// Reload requests are sent on reloads with a channel for the outcome. There is no
+// authentication: listen on loopback or a trusted network only.
+func serveAdmin(ctx context.Context, addr string, reloads chan<- chan error) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("admin: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/-/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		done := make(chan error, 1)
+		select {
+		case reloads <- done:
+		case <-r.Context().Done():
+			return
+		}
+		select {
+		case err := <-done:
+			if err != nil {
+				http.Error(w, secret.Redact(err.Error()), http.StatusUnprocessableEntity)
+				return
+			}
+			fmt.Fprintln(w, "reloaded")
+		case <-r.Context().Done():
+		}
+	})
+
+	server := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: time.Minute, // a reload waits for the current collection cycle
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("admin server error: %v", err)
+		}
+	}()
+	log.Printf("admin API listening on %s", ln.Addr())
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"rpi-metrics/internal/check"
+	"rpi-metrics/internal/metrics"
+)
+
+// runCheck is a Nagios/Icinga plugin: it collects once with the agent's collectors and
+// stages, evaluates the -w/-c thresholds, prints the status line with perfdata and
+// exits 0/1/2/3 for OK/WARNING/CRITICAL/UNKNOWN.
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: rpi-metrics check -w 'metric{label=\"value\"}=range' -c 'metric=range' [agent flags]\n\n")
+		fs.PrintDefaults()
+	}
+	var warnSpecs, critSpecs stringList
+	fs.Var(&warnSpecs, "w", "warning threshold metric{labels}=range in Nagios range syntax (repeatable)")
+	fs.Var(&critSpecs, "c", "critical threshold metric{labels}=range in Nagios range syntax (repeatable)")
+	warmup := fs.Duration("warmup", onceWarmup, "pause between the two readings that delta metrics (CPU utilization, rates) need; 0 collects once")
+	f := registerAgentFlags(fs)
+	// Usage errors are UNKNOWN, not CRITICAL (flag's exit code 2).
+	if err := f.parse(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return int(check.Unknown)
+		}
+		return checkUnknown("%v", err)
+	}
+
+	rules, err := parseCheckRules(warnSpecs, critSpecs)
+	if err != nil {
+		return checkUnknown("%v", err)
+	}
+	if len(rules) == 0 {
+		return checkUnknown("no thresholds; pass -w and/or -c")
+	}
+
+	stats := &metrics.AgentStats{}
+	collectorList, err := f.collectors(stats)
+	if err != nil {
+		return checkUnknown("%v", err)
+	}
+	stages, _, err := f.stages()
+	if err != nil {
+		return checkUnknown("%v", err)
+	}
+	runner := metrics.Runner{Collectors: collectorList, Stages: stages, Stats: stats}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	res, err := collectWarm(ctx, runner, *warmup)
+	if err != nil {
+		return checkUnknown("interrupted")
+	}
+
+	out := check.Evaluate(res, rules)
+	printCheckOutcome(out, res.Errors)
+	return int(out.State)
+}
+
+// parseCheckRules merges -w and -c thresholds for the same selector into one rule,
+// keeping the order of first appearance.
+func parseCheckRules(warnSpecs, critSpecs []string) ([]*check.Rule, error) {
+	var rules []*check.Rule
+	bySelector := map[string]*check.Rule{}
+	add := func(spec string, critical bool) error {
+		sel, r, err := check.ParseRuleSpec(spec)
+		if err != nil {
+			return err
+		}
+		rule, ok := bySelector[sel.Raw]
+		if !ok {
+			rule = &check.Rule{Selector: sel}
+			bySelector[sel.Raw] = rule
+			rules = append(rules, rule)
+		}
+		if critical {
+			rule.Crit = &r
+		} else {
+			rule.Warn = &r
+		}
+		return nil
+	}
+	for _, spec := range warnSpecs {
+		if err := add(spec, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, spec := range critSpecs {
+		if err := add(spec, true); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// printCheckOutcome prints the status line with perfdata, then one line per problem
+// (the plugin's long output).
+func printCheckOutcome(out check.Outcome, errs []metrics.CollectorError) {
+	var problems, details, perfdata []string
+	for _, r := range out.Results {
+		perfdata = append(perfdata, r.Perfdata())
+		if r.State == check.OK {
+			continue
+		}
+		msg := fmt.Sprintf("%s is %s", r.Label, metrics.FormatSample(r.Sample))
+		problems = append(problems, msg)
+		details = append(details, fmt.Sprintf("%s: %s", r.State, msg))
+	}
+	for _, rule := range out.Missing {
+		msg := fmt.Sprintf("no samples for %s", rule.Selector.Raw)
+		problems = append(problems, msg)
+		details = append(details, "UNKNOWN: "+msg)
+	}
+	for _, e := range errs {
+		details = append(details, fmt.Sprintf("collector %s: %s", e.CollectorID, e.Error))
+	}
+
+	summary := fmt.Sprintf("%d series within thresholds", len(out.Results))
+	if len(problems) > 0 {
+		summary = strings.Join(problems, ", ")
+	}
+	line := fmt.Sprintf("RPI-METRICS %s - %s", out.State, summary)
+	if len(perfdata) > 0 {
+		line += " | " + strings.Join(perfdata, " ")
+	}
+	fmt.Println(line)
+	for _, d := range details {
+		fmt.Println(d)
+	}
+}
+
+func checkUnknown(format string, args ...any) int {
+	fmt.Printf("RPI-METRICS UNKNOWN - %s\n", fmt.Sprintf(format, args...))
+	return int(check.Unknown)
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/collectors"
+	"rpi-metrics/internal/email"
+	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/schedule"
+	"rpi-metrics/internal/secret"
+	"rpi-metrics/internal/telegram"
+)
+
+// agentFlags are the agent's command-line flags. They are registered on a FlagSet so
+// subcommands that inspect the agent's setup (doctor) accept the same flags as the
+// agent itself: copy the flags from the service unit and add the subcommand name.
+type agentFlags struct {
+	interval        *time.Duration
+	tempPath        *string
+	coolingPath     *string
+	storagePaths    *string
+	storageDiscover *bool
+	mmcStatePath    *string
+	forecastWindow  *time.Duration
+	forecastMinR2   *float64
+	rates           *bool
+	execCommands    stringList
+	execTimeout     *time.Duration
+	execMaxOutput   *int
+	execFormat      *string
+	iwPath          *string
+	powerSupplyPath *string
+	throttledPath   *string
+	shutdownBelow   *float64
+	shutdownAfter   *time.Duration
+	shutdownGrace   *time.Duration
+	shutdownCommand *string
+	shutdownDryRun  *bool
+	textfileDir     *string
+	staticLabels    *string
+	hostLabels      *string
+	relabelConfig   *string
+	agentMetrics    *bool
+
+	heartbeatURL          *string
+	heartbeatMethod       *string
+	heartbeatEvery        *time.Duration
+	heartbeatFailOnErrors *bool
+
+	pushURL      *string
+	pushToken    *string
+	pushBatch    *int
+	pushMaxQueue *int
+
+	discordWebhook *string
+	discordEvery   *time.Duration
+	discordDigest  *string
+	alsoConsole    *bool
+
+	notifyFlags
+	telegramEvery    *time.Duration
+	telegramCommands *bool
+
+	config      *string
+	adminListen *string
+}
+
+// notifyFlags configure the webhook, Telegram and email exporters and their retries;
+// replay takes them too.
+type notifyFlags struct {
+	webhookConfig  *string
+	notifyAttempts *int
+	notifyBackoff  *time.Duration
+
+	telegramToken   *string
+	telegramChatIDs *string
+	telegramAPIURL  *string
+
+	smtpAddr      *string
+	smtpTLS       *string
+	smtpUser      *string
+	smtpPassword  *string
+	emailFrom     *string
+	emailTo       *string
+	emailDigestAt *string
+}
+
+func (f *notifyFlags) register(fs *flag.FlagSet) {
+	f.webhookConfig = fs.String(constants.FlagWebhookConfig, constants.DefaultWebhookConfig, constants.FlagUsageWebhookConfig)
+	f.notifyAttempts = fs.Int(constants.FlagNotifyAttempts, constants.DefaultNotifyAttempts, constants.FlagUsageNotifyAttempts)
+	f.notifyBackoff = fs.Duration(constants.FlagNotifyBackoff, constants.DefaultNotifyBackoff, constants.FlagUsageNotifyBackoff)
+	f.telegramToken = secretString(fs, constants.FlagTelegramToken, constants.FlagUsageTelegramToken)
+	f.telegramChatIDs = fs.String(constants.FlagTelegramChatIDs, constants.DefaultTelegramChatIDs, constants.FlagUsageTelegramChatIDs)
+	f.telegramAPIURL = fs.String(constants.FlagTelegramAPIURL, constants.DefaultTelegramAPIURL, constants.FlagUsageTelegramAPIURL)
+	f.smtpAddr = fs.String(constants.FlagSMTPAddr, constants.DefaultSMTPAddr, constants.FlagUsageSMTPAddr)
+	f.smtpTLS = fs.String(constants.FlagSMTPTLS, constants.DefaultSMTPTLS, constants.FlagUsageSMTPTLS)
+	f.smtpUser = fs.String(constants.FlagSMTPUser, constants.DefaultSMTPUser, constants.FlagUsageSMTPUser)
+	f.smtpPassword = secretString(fs, constants.FlagSMTPPassword, constants.FlagUsageSMTPPassword)
+	f.emailFrom = fs.String(constants.FlagEmailFrom, constants.DefaultEmailFrom, constants.FlagUsageEmailFrom)
+	f.emailTo = fs.String(constants.FlagEmailTo, constants.DefaultEmailTo, constants.FlagUsageEmailTo)
+	f.emailDigestAt = fs.String(constants.FlagEmailDigestAt, constants.DefaultEmailDigestAt, constants.FlagUsageEmailDigestAt)
+}
+
+// collectorFlags names the flag that points each collector at its source, for hints.
+var collectorFlags = map[string]string{
+	"cpu_temp":           constants.FlagTempPath,
+	"cpu_cooling_device": constants.FlagCoolingPath,
+	"storage_usage":      constants.FlagStoragePaths,
+	"mmc_health":         constants.FlagMMCStatePath,
+	"power_supply":       constants.FlagPowerSupplyPath,
+	"throttled":          constants.FlagThrottledPath,
+	"network":            constants.FlagIwPath,
+	"textfile":           constants.FlagTextfileDir,
+}
+
+func registerAgentFlags(fs *flag.FlagSet) *agentFlags {
+	f := &agentFlags{}
+	f.interval = fs.Duration(constants.FlagInterval, constants.DefaultCollectionInterval, constants.FlagUsageInterval)
+	f.tempPath = fs.String(constants.FlagTempPath, constants.DefaultCPUTempSysfsPath, constants.FlagUsageTempPath)
+	f.coolingPath = fs.String(constants.FlagCoolingPath, constants.DefaultCPUCoolingDevicefsPath, constants.FlagUsageCoolingPath)
+	f.storagePaths = fs.String(constants.FlagStoragePaths, constants.DefaultStoragePathsCSV, constants.FlagUsageStoragePaths)
+	f.storageDiscover = fs.Bool(constants.FlagStorageDiscover, constants.DefaultStorageDiscover, constants.FlagUsageStorageDiscover)
+	f.mmcStatePath = fs.String(constants.FlagMMCStatePath, constants.DefaultMMCStatePath, constants.FlagUsageMMCStatePath)
+	f.forecastWindow = fs.Duration(constants.FlagForecastWindow, constants.DefaultStorageForecastWindow, constants.FlagUsageForecastWindow)
+	f.forecastMinR2 = fs.Float64(constants.FlagForecastMinR2, constants.DefaultStorageForecastMinR2, constants.FlagUsageForecastMinR2)
+	f.rates = fs.Bool(constants.FlagRates, constants.DefaultRates, constants.FlagUsageRates)
+	fs.Var(&f.execCommands, constants.FlagExec, constants.FlagUsageExec)
+	f.execTimeout = fs.Duration(constants.FlagExecTimeout, constants.DefaultExecTimeout, constants.FlagUsageExecTimeout)
+	f.execMaxOutput = fs.Int(constants.FlagExecMaxOutput, constants.DefaultExecMaxOutputBytes, constants.FlagUsageExecMaxOutput)
+	f.execFormat = fs.String(constants.FlagExecFormat, constants.DefaultExecFormat, constants.FlagUsageExecFormat)
+	f.iwPath = fs.String(constants.FlagIwPath, constants.DefaultIwPath, constants.FlagUsageIwPath)
+	f.powerSupplyPath = fs.String(constants.FlagPowerSupplyPath, constants.DefaultPowerSupplyPath, constants.FlagUsagePowerSupplyPath)
+	f.throttledPath = fs.String(constants.FlagThrottledPath, constants.DefaultThrottledPath, constants.FlagUsageThrottledPath)
+	f.shutdownBelow = fs.Float64(constants.FlagShutdownBelow, constants.DefaultShutdownBelowPercent, constants.FlagUsageShutdownBelow)
+	f.shutdownAfter = fs.Duration(constants.FlagShutdownAfter, constants.DefaultShutdownAfter, constants.FlagUsageShutdownAfter)
+	f.shutdownGrace = fs.Duration(constants.FlagShutdownGrace, constants.DefaultShutdownGrace, constants.FlagUsageShutdownGrace)
+	f.shutdownCommand = fs.String(constants.FlagShutdownCommand, constants.DefaultShutdownCommand, constants.FlagUsageShutdownCommand)
+	f.shutdownDryRun = fs.Bool(constants.FlagShutdownDryRun, constants.DefaultShutdownDryRun, constants.FlagUsageShutdownDryRun)
+	f.textfileDir = fs.String(constants.FlagTextfileDir, constants.DefaultTextfileDir, constants.FlagUsageTextfileDir)
+	f.staticLabels = fs.String(constants.FlagLabels, constants.DefaultStaticLabelsCSV, constants.FlagUsageLabels)
+	f.hostLabels = fs.String(constants.FlagHostLabels, constants.DefaultHostLabelsCSV, constants.FlagUsageHostLabels)
+	f.relabelConfig = fs.String(constants.FlagRelabelConfig, constants.DefaultRelabelConfig, constants.FlagUsageRelabelConfig)
+	f.agentMetrics = fs.Bool(constants.FlagAgentMetrics, constants.DefaultAgentMetrics, constants.FlagUsageAgentMetrics)
+	f.heartbeatURL = secretString(fs, constants.FlagHeartbeatURL, constants.FlagUsageHeartbeatURL)
+	f.heartbeatMethod = fs.String(constants.FlagHeartbeatMethod, constants.DefaultHeartbeatMethod, constants.FlagUsageHeartbeatMethod)
+	f.heartbeatEvery = fs.Duration(constants.FlagHeartbeatEvery, constants.DefaultHeartbeatEvery, constants.FlagUsageHeartbeatEvery)
+	f.heartbeatFailOnErrors = fs.Bool(constants.FlagHeartbeatFailOnErrors, constants.DefaultHeartbeatFailOnErrors, constants.FlagUsageHeartbeatFailOnErrors)
+	f.pushURL = fs.String(constants.FlagPushURL, constants.DefaultPushURL, constants.FlagUsagePushURL)
+	f.pushToken = secretString(fs, constants.FlagPushToken, constants.FlagUsagePushToken)
+	f.pushBatch = fs.Int(constants.FlagPushBatch, constants.DefaultPushBatch, constants.FlagUsagePushBatch)
+	f.pushMaxQueue = fs.Int(constants.FlagPushMaxQueue, constants.DefaultPushMaxQueue, constants.FlagUsagePushMaxQueue)
+	f.discordWebhook = secretString(fs, constants.FlagDiscordWebhook, constants.FlagUsageDiscordWebhook)
+	f.discordEvery = fs.Duration(constants.FlagDiscordEvery, constants.DefaultDiscordPostEvery, constants.FlagUsageDiscordEvery)
+	f.discordDigest = fs.String(constants.FlagDiscordDigest, constants.DefaultDiscordDigest, constants.FlagUsageDiscordDigest)
+	f.alsoConsole = fs.Bool(constants.FlagAlsoConsole, constants.DefaultAlsoConsoleWhenDiscordOn, constants.FlagUsageAlsoConsole)
+	f.notifyFlags.register(fs)
+	f.telegramEvery = fs.Duration(constants.FlagTelegramEvery, constants.DefaultTelegramEvery, constants.FlagUsageTelegramEvery)
+	f.telegramCommands = fs.Bool(constants.FlagTelegramCommands, constants.DefaultTelegramCommands, constants.FlagUsageTelegramCommands)
+	f.config = fs.String(constants.FlagConfig, constants.DefaultConfigPath, constants.FlagUsageConfig)
+	f.adminListen = fs.String(constants.FlagAdminListen, constants.DefaultAdminListen, constants.FlagUsageAdminListen)
+	return f
+}
+
+// retry is the retry policy of the Discord, webhook, Telegram and email exporters.
+func (f *notifyFlags) retry() metrics.Retry {
+	return metrics.Retry{Attempts: *f.notifyAttempts, Backoff: *f.notifyBackoff}
+}
+
+// webhooks returns the webhooks of the -webhook-config file.
+func (f *notifyFlags) webhooks() ([]metrics.WebhookConfig, error) {
+	if *f.webhookConfig == "" {
+		return nil, nil
+	}
+	return metrics.LoadWebhookConfig(*f.webhookConfig)
+}
+
+// telegram returns the Telegram client and chat ids, or a zero client when
+// -telegram-token is not set.
+func (f *notifyFlags) telegram() (telegram.Client, []int64, error) {
+	if *f.telegramToken == "" {
+		return telegram.Client{}, nil, nil
+	}
+	var chats []int64
+	for _, s := range metrics.SplitCSV(*f.telegramChatIDs) {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return telegram.Client{}, nil, fmt.Errorf("invalid -%s entry %q: want a numeric chat id", constants.FlagTelegramChatIDs, s)
+		}
+		chats = append(chats, id)
+	}
+	if len(chats) == 0 {
+		return telegram.Client{}, nil, fmt.Errorf("-%s is set but -%s is empty", constants.FlagTelegramToken, constants.FlagTelegramChatIDs)
+	}
+	client := telegram.Client{BaseURL: *f.telegramAPIURL, Token: *f.telegramToken, Retry: f.retry()}
+	return client, chats, nil
+}
+
+// email returns the email exporter configured by the SMTP flags, or nil when
+// -smtp-addr is not set.
+func (f *notifyFlags) email() (*email.Exporter, error) {
+	if *f.smtpAddr == "" {
+		return nil, nil
+	}
+	switch *f.smtpTLS {
+	case email.TLSStartTLS, email.TLSImplicit, email.TLSNone:
+	default:
+		return nil, fmt.Errorf("invalid -%s %q: want %s, %s or %s", constants.FlagSMTPTLS, *f.smtpTLS, email.TLSStartTLS, email.TLSImplicit, email.TLSNone)
+	}
+	if *f.emailFrom == "" || *f.emailTo == "" {
+		return nil, fmt.Errorf("-%s needs -%s and -%s", constants.FlagSMTPAddr, constants.FlagEmailFrom, constants.FlagEmailTo)
+	}
+	e := &email.Exporter{Sender: email.Sender{
+		Addr:     *f.smtpAddr,
+		TLS:      *f.smtpTLS,
+		Username: *f.smtpUser,
+		Password: *f.smtpPassword,
+		From:     *f.emailFrom,
+		To:       metrics.SplitCSV(*f.emailTo),
+		Retry:    f.retry(),
+	}}
+	if spec := *f.emailDigestAt; spec != "" {
+		if _, err := time.Parse("15:04", spec); err == nil {
+			spec = "every day " + spec // the HH:MM the flag took before schedules
+		}
+		sched, err := schedule.Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -%s: %w", constants.FlagEmailDigestAt, err)
+		}
+		e.Digest = sched
+	}
+	return e, nil
+}
+
+// parse parses args and then applies the -config file, if any. Flags given in args
+// win over the file.
+func (f *agentFlags) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *f.config == "" {
+		return nil
+	}
+	given := map[string]bool{}
+	fs.Visit(func(fl *flag.Flag) { given[fl.Name] = true })
+	return applyConfigFile(fs, *f.config, given)
+}
+
+// applyConfigFile sets flags from a file of name=value lines, skipping those in
+// given. Blank lines and lines starting with # are ignored, a leading - on the name
+// and quotes around the value are optional, and a bool flag may be given bare.
+// Repeatable flags (-exec) take one line per value.
+func applyConfigFile(fs *flag.FlagSet, path string, given map[string]bool) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	warned := false
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(line, "=")
+		name = strings.TrimLeft(strings.TrimSpace(name), "-")
+		value = unquote(strings.TrimSpace(value))
+
+		fl := fs.Lookup(name)
+		if fl == nil {
+			return fmt.Errorf("%s:%d: unknown flag -%s", path, i+1, name)
+		}
+		if name == constants.FlagConfig {
+			return fmt.Errorf("%s:%d: -%s can't be set in a config file", path, i+1, name)
+		}
+		if given[name] {
+			continue
+		}
+		if !hasValue {
+			if bf, ok := fl.Value.(interface{ IsBoolFlag() bool }); !ok || !bf.IsBoolFlag() {
+				return fmt.Errorf("%s:%d: -%s needs a value", path, i+1, name)
+			}
+			value = "true"
+		}
+		// A credential written into the file makes the file a secret too.
+		if _, ok := fl.Value.(secret.Flag); ok && !warned && !strings.HasPrefix(value, "env:") && !strings.HasPrefix(value, "file:") {
+			if err := secret.CheckFileMode(path); err != nil {
+				log.Printf("warning: -%s: %v", constants.FlagConfig, err)
+			}
+			warned = true
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s:%d: -%s: %w", path, i+1, name, err)
+		}
+	}
+	return nil
+}
+
+// unquote strips one pair of matching quotes, as a shell would.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// collectors returns the configured collectors in collection order. stats feeds the
+// agent self-metrics collector, which runs last so it reports the others' stats.
+func (f *agentFlags) collectors(stats *metrics.AgentStats) ([]metrics.Collector, error) {
+	collectorList, _, err := f.collectorsWithKeys(stats)
+	return collectorList, err
+}
+
+// collectorsWithKeys also returns each collector's config key (see keep): the flag
+// values it was built from.
+func (f *agentFlags) collectorsWithKeys(stats *metrics.AgentStats) ([]metrics.Collector, []string, error) {
+	var collectorList []metrics.Collector
+	var keys []string
+	add := func(c metrics.Collector, config ...any) {
+		collectorList = append(collectorList, c)
+		keys = append(keys, configKey(config...))
+	}
+	add(collectors.CPUTempSysfs{Path: *f.tempPath}, *f.tempPath)
+	add(&collectors.CPUUtilizationProcfs{})
+	add(collectors.CPUCoolingDevicefs{Path: *f.coolingPath}, *f.coolingPath)
+	add(collectors.StorageStatfs{Paths: metrics.SplitCSV(*f.storagePaths), Discover: *f.storageDiscover}, *f.storagePaths, *f.storageDiscover)
+	add(&collectors.MMCHealthSysfs{StatePath: *f.mmcStatePath}, *f.mmcStatePath)
+	add(collectors.PowerSupplySysfs{Path: *f.powerSupplyPath}, *f.powerSupplyPath)
+	add(collectors.ThrottledSysfs{Path: *f.throttledPath}, *f.throttledPath)
+	add(collectors.NetworkSysfs{IwPath: *f.iwPath}, *f.iwPath)
+	if *f.textfileDir != "" {
+		add(collectors.TextfileCollector{Dir: *f.textfileDir}, *f.textfileDir)
+	}
+	for _, spec := range f.execCommands {
+		name, command, ok := strings.Cut(spec, "=")
+		argv, err := splitWords(command)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid -%s %q: %w", constants.FlagExec, spec, err)
+		}
+		if !ok || strings.TrimSpace(name) == "" || len(argv) == 0 {
+			return nil, nil, fmt.Errorf("invalid -%s %q: want name=command args...", constants.FlagExec, spec)
+		}
+		c := &collectors.ExecCollector{
+			Name:           strings.TrimSpace(name),
+			Command:        argv,
+			Timeout:        *f.execTimeout,
+			MaxOutputBytes: *f.execMaxOutput,
+			Format:         *f.execFormat,
+		}
+		add(c, c.Name, c.Command, c.Timeout, c.MaxOutputBytes, c.Format)
+	}
+	if *f.agentMetrics {
+		// stats is the agent's, the same for every pipeline.
+		add(collectors.AgentSelf{Stats: stats, Version: version, Commit: commit}, version, commit)
+	}
+	return collectorList, keys, nil
+}
+
+// stages returns the configured pipeline stages and the relabel config, whose
+// per-exporter rules the caller applies with wrapExporter.
+func (f *agentFlags) stages() ([]metrics.Stage, metrics.RelabelConfig, error) {
+	stages, _, relabel, err := f.stagesWithKeys()
+	return stages, relabel, err
+}
+
+// stagesWithKeys also returns each stage's config key (see keep).
+func (f *agentFlags) stagesWithKeys() ([]metrics.Stage, []string, metrics.RelabelConfig, error) {
+	var stages []metrics.Stage
+	var keys []string
+	add := func(s metrics.Stage, config ...any) {
+		stages = append(stages, s)
+		keys = append(keys, configKey(config...))
+	}
+	if *f.forecastWindow > 0 {
+		add(&metrics.StorageForecaster{Window: *f.forecastWindow, MinR2: *f.forecastMinR2}, *f.forecastWindow, *f.forecastMinR2)
+	}
+	// CPU utilization comes from the rate of the CPU time counters, so those get a
+	// rate even with -rates off.
+	rates := &metrics.RateStage{}
+	if !*f.rates {
+		rates.Names = []string{metrics.CPUTimeCounter}
+	}
+	add(rates, *f.rates)
+	add(metrics.CPUUtilization{})
+
+	labels, err := parseLabelsCSV(*f.staticLabels)
+	if err != nil {
+		return nil, nil, metrics.RelabelConfig{}, fmt.Errorf("-%s: %w", constants.FlagLabels, err)
+	}
+	detected := collectors.DetectHostLabels()
+	for _, name := range metrics.SplitCSV(*f.hostLabels) {
+		if v, ok := detected[name]; ok {
+			if _, set := labels[name]; !set {
+				labels[name] = v
+			}
+		}
+	}
+	add(metrics.StaticLabels{Labels: labels}, labels)
+
+	var relabel metrics.RelabelConfig
+	if *f.relabelConfig != "" {
+		relabel, err = metrics.LoadRelabelConfig(*f.relabelConfig)
+		if err != nil {
+			return nil, nil, metrics.RelabelConfig{}, err
+		}
+		add(metrics.Relabeler{Rules: relabel.Relabel}, relabel.Relabel)
+	}
+	return stages, keys, relabel, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/doctor"
+	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/secret"
+)
+
+// runDoctor checks the setup described by the agent's flags: collector sources,
+// optional sources on this board and exporter endpoints (as dry runs). It exits 1
+// when any check fails.
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: rpi-metrics doctor [-json] [agent flags]\n\n")
+		fs.PrintDefaults()
+	}
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	timeout := fs.Duration("timeout", constants.DefaultDoctorTimeout, "timeout for each exporter endpoint check")
+	f := registerAgentFlags(fs)
+	if err := f.parse(fs, args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx := context.Background()
+	var report doctor.Report
+
+	collectorList, err := f.collectors(&metrics.AgentStats{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	for _, c := range collectorList {
+		report.Add(doctor.CheckCollector(ctx, c, collectorFlags[c.ID()]))
+	}
+
+	report.Add(doctor.ProbeThrottled(ctx, *f.throttledPath, constants.DefaultVcgencmdPath))
+	report.Add(doctor.ProbeThermalZones(constants.DefaultThermalClassPath)...)
+	report.Add(
+		doctor.ProbeHwmon(constants.DefaultHwmonClassPath),
+		doctor.ProbePowerSupplies(*f.powerSupplyPath),
+		doctor.ProbeWireless(constants.DefaultWirelessPath, *f.iwPath),
+	)
+
+	client := &http.Client{Timeout: *timeout}
+	endpointCtx := func() (context.Context, context.CancelFunc) { return context.WithTimeout(ctx, *timeout) }
+	if *f.discordWebhook != "" {
+		cctx, cancel := endpointCtx()
+		report.Add(doctor.CheckDiscordWebhook(cctx, client, *f.discordWebhook))
+		cancel()
+	} else {
+		report.Add(doctor.NotConfigured("discord", constants.FlagDiscordWebhook))
+	}
+	if *f.webhookConfig != "" {
+		report.Add(doctor.CheckWebhookConfig(*f.webhookConfig)...)
+	}
+	tgClient, tgChats, err := f.telegram()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if tgClient.Token != "" {
+		tgClient.HTTP = client
+		cctx, cancel := endpointCtx()
+		report.Add(doctor.CheckTelegram(cctx, tgClient, tgChats)...)
+		cancel()
+	} else {
+		report.Add(doctor.NotConfigured("telegram", constants.FlagTelegramToken))
+	}
+	mail, err := f.email()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if mail != nil {
+		mail.Sender.Timeout = *timeout
+		report.Add(doctor.CheckEmail(ctx, mail.Sender))
+	} else {
+		report.Add(doctor.NotConfigured("email", constants.FlagSMTPAddr))
+	}
+	if *f.pushURL != "" {
+		cctx, cancel := endpointCtx()
+		report.Add(doctor.CheckPushURL(cctx, client, *f.pushURL, *f.pushToken))
+		cancel()
+	} else {
+		report.Add(doctor.NotConfigured("push", constants.FlagPushURL))
+	}
+	if *f.heartbeatURL != "" {
+		cctx, cancel := endpointCtx()
+		report.Add(doctor.CheckHeartbeatURL(cctx, *f.heartbeatURL))
+		cancel()
+	} else {
+		report.Add(doctor.NotConfigured("heartbeat", constants.FlagHeartbeatURL))
+	}
+
+	// Endpoint errors may quote a server's response; keep credentials out of the report
+	// people paste into issues.
+	var out bytes.Buffer
+	if *asJSON {
+		enc := json.NewEncoder(&out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteText(&out)
+	}
+	if err == nil {
+		_, err = io.WriteString(os.Stdout, secret.Redact(out.String()))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
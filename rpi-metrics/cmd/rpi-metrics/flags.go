@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/secret"
+)
+
+// stringList is a repeatable string flag (e.g. -exec a=... -exec b=...).
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// secretString defines a credential-bearing string flag. Its value may be an env:VAR
+// or file:/path reference, is redacted from log output and never shown by -h.
+func secretString(fs *flag.FlagSet, name, usage string) *string {
+	p := new(string)
+	warn := func(err error) { log.Printf("warning: -%s: %v", name, err) }
+	fs.Var(secret.Flag{Dest: p, Warn: warn}, name, usage)
+	return p
+}
+
+// parseLabelsCSV parses "k=v,k2=v2" into a label map.
+func parseLabelsCSV(s string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, pair := range metrics.SplitCSV(s) {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid label %q: want name=value", pair)
+		}
+		out[k] = strings.TrimSpace(v)
+	}
+	return out, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/heartbeat"
+	"rpi-metrics/internal/hub"
+	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/secret"
+)
+
+// runHeartbeatReceiver tracks heartbeats pinged by other agents
+// (-heartbeat-url=http://<name>:<token>@<this host>:8090/ping/<name>) and alerts when
+// one goes quiet.
+func runHeartbeatReceiver(args []string) int {
+	fs := flag.NewFlagSet("heartbeat-receiver", flag.ExitOnError)
+	listen := fs.String("listen", constants.DefaultHeartbeatListen, "address to accept pings on")
+	timeout := fs.Duration("timeout", constants.DefaultHeartbeatTimeout, "report a host down after this long without a ping")
+	checkEvery := fs.Duration("check-every", constants.DefaultHeartbeatCheckEvery, "how often to look for quiet hosts")
+	tokensPath := fs.String("tokens", "", `JSON file of per-host ping tokens: {"host": "token", ...} (required)`)
+	discordWebhook := secretString(fs, constants.FlagDiscordWebhook, "Discord webhook URL for down/recovered notices (optional; or env:VAR, file:/path)")
+	webhookConfig := fs.String(constants.FlagWebhookConfig, constants.DefaultWebhookConfig, "JSON file of templated webhooks for down/recovered notices (optional)")
+	fs.Parse(args)
+
+	if *tokensPath == "" {
+		log.Printf("-tokens is required")
+		return 2
+	}
+	tokens, err := hub.LoadTokens(*tokensPath)
+	if err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	if err := secret.CheckFileMode(*tokensPath); err != nil {
+		log.Printf("warning: -tokens: %v", err)
+	}
+
+	recv := &heartbeat.Receiver{Tokens: tokens, Timeout: *timeout}
+	retry := metrics.Retry{Attempts: constants.DefaultNotifyAttempts, Backoff: constants.DefaultNotifyBackoff}
+	var notifiers []func(context.Context, string) error
+	if *discordWebhook != "" {
+		notifiers = append(notifiers, (&metrics.DiscordWebhookExporter{WebhookURL: *discordWebhook, Retry: retry}).Notify)
+	}
+	if *webhookConfig != "" {
+		webhooks, err := metrics.LoadWebhookConfig(*webhookConfig)
+		if err != nil {
+			log.Printf("%v", err)
+			return 2
+		}
+		for _, c := range webhooks {
+			notifiers = append(notifiers, (&metrics.WebhookExporter{Config: c, Retry: retry}).Notify)
+		}
+	}
+	if len(notifiers) > 0 {
+		recv.Notify = notifyAll(notifiers)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	server := &http.Server{
+		Addr:         *listen,
+		Handler:      recv.Handler(),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go recv.Run(ctx, *checkEvery)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("heartbeat receiver listening on %s (%d hosts, timeout %s)", *listen, len(tokens), *timeout)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("server error: %v", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"rpi-metrics/internal/collectors"
+	"rpi-metrics/internal/metrics"
+)
+
+type collectorInfo struct {
+	ID     string `json:"id"`
+	Source string `json:"source,omitempty"`
+}
+
+// runListCollectors prints the collectors the agent flags configure, in collection
+// order, with the flag that points each at its source.
+func runListCollectors(args []string) int {
+	fs := flag.NewFlagSet("list-collectors", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print collectors as JSON")
+	f := registerAgentFlags(fs)
+	if err := f.parse(fs, args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	collectorList, err := f.collectors(&metrics.AgentStats{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	infos := make([]collectorInfo, 0, len(collectorList))
+	for _, c := range collectorList {
+		info := collectorInfo{ID: c.ID()}
+		if name, ok := collectorFlags[c.ID()]; ok {
+			info.Source = fmt.Sprintf("-%s=%s", name, fs.Lookup(name).Value)
+		} else if e, ok := c.(*collectors.ExecCollector); ok {
+			info.Source = strings.Join(e.Command, " ")
+		}
+		infos = append(infos, info)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(infos); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSOURCE")
+	for _, info := range infos {
+		source := info.Source
+		if source == "" {
+			source = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", info.ID, source)
+	}
+	if err := tw.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
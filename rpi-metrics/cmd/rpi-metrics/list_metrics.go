@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"rpi-metrics/internal/metrics"
+)
+
+// runListMetrics prints the descriptor of every metric this build can emit.
+// Metrics from -exec commands and textfiles are not known ahead of time and are not listed.
+func runListMetrics(args []string) int {
+	fs := flag.NewFlagSet("list-metrics", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print descriptors as JSON")
+	fs.Parse(args)
+
+	descs := metrics.Descriptors()
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(descs); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tUNIT\tRANGE\tLABELS\tHELP")
+	for _, d := range descs {
+		unit := d.Unit
+		if unit == "" {
+			unit = "-"
+		}
+		rng := "-"
+		if d.Range != nil {
+			rng = fmt.Sprintf("%g..%g", d.Range.Min, d.Range.Max)
+		}
+		labels := "-"
+		if len(d.Labels) > 0 {
+			labels = strings.Join(d.Labels, ",")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", d.Name, d.Type, unit, rng, labels, d.Help)
+	}
+	if err := tw.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
@@ -11,49 +14,97 @@ import (
 	"syscall"
 	"time"
 
-	"rpi-metrics/constants"
-	"rpi-metrics/internal/collectors"
 	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/secret"
+	"rpi-metrics/internal/telegram"
 )
 
+// Set at build time: go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse HEAD)"
+var (
+	version = ""
+	commit  = ""
+)
+
+type command struct {
+	name, help string
+	run        func(args []string) int
+}
+
+// commands are the subcommands. The agent (run) is the default, so invocations that
+// start with a flag keep working.
+func commands() []command {
+	return []command{
+		{"run", "collect and export on an interval (default)", runAgent},
+		{"once", "collect once, print the result and exit (non-zero on collector errors)", runOnce},
+		{"check", "Nagios/Icinga plugin: evaluate -w/-c thresholds against one collection", runCheck},
+		{"top", "terminal dashboard with live values and a process table", runTop},
+		{"list-collectors", "list the collectors the given flags configure", runListCollectors},
+		{"list-metrics", "list the descriptors of every built-in metric", runListMetrics},
+		{"doctor", "check collector sources, optional sources and exporter endpoints", runDoctor},
+		{"replay", "feed recorded console output through alerts and exporters", runReplay},
+		{"heartbeat-receiver", "receive heartbeat pings from agents and alert on silence", runHeartbeatReceiver},
+		{"version", "print version information", runVersion},
+	}
+}
+
 func main() {
-	interval := flag.Duration(constants.FlagInterval, constants.DefaultCollectionInterval, constants.FlagUsageInterval)
-	tempPath := flag.String(constants.FlagTempPath, constants.DefaultCPUTempSysfsPath, constants.FlagUsageTempPath)
-	coolingPath := flag.String(constants.FlagCoolingPath, constants.DefaultCPUCoolingDevicefsPath, constants.FlagUsageCoolingPath)
-	storagePaths := flag.String(constants.FlagStoragePaths, constants.DefaultStoragePathsCSV, constants.FlagUsageStoragePaths)
-	discordWebhook := flag.String(constants.FlagDiscordWebhook, constants.DefaultDiscordWebhookURL, constants.FlagUsageDiscordWebhook)
-	discordEvery := flag.Duration(constants.FlagDiscordEvery, constants.DefaultDiscordPostEvery, constants.FlagUsageDiscordEvery)
-	alsoConsole := flag.Bool(constants.FlagAlsoConsole, constants.DefaultAlsoConsoleWhenDiscordOn, constants.FlagUsageAlsoConsole)
-	flag.Parse()
+	log.SetOutput(secret.Writer{W: os.Stderr})
 
-	// Set up ordered collectors
-	collectorList := []metrics.Collector{
-		collectors.CPUTempSysfs{Path: *tempPath},
-		&collectors.CPUUtilizationProcfs{},
-		collectors.CPUCoolingDevicefs{Path: *coolingPath},
-		collectors.StorageStatfs{Paths: splitCSV(*storagePaths)},
+	name, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
+	for _, c := range commands() {
+		if c.name == name {
+			os.Exit(c.run(args))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "rpi-metrics: unknown command %q\n\n", name)
+	printCommands(os.Stderr)
+	os.Exit(2)
+}
 
-	runner := metrics.Runner{Collectors: collectorList}
-	discordEnabled := *discordWebhook != "" && *discordEvery > 0
-	consoleEnabled := !discordEnabled || *alsoConsole
+func printCommands(w io.Writer) {
+	fmt.Fprintf(w, "Usage: rpi-metrics [command] [flags]\n\nCommands:\n")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-20s %s\n", c.name, c.help)
+	}
+	fmt.Fprintf(w, "\nRun 'rpi-metrics <command> -h' for the command's flags.\n")
+}
+
+// runAgent is the agent: collect every -interval and export until SIGINT/SIGTERM.
+// SIGHUP and the admin API reload the config between cycles.
+func runAgent(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.Usage = func() {
+		printCommands(fs.Output())
+		fmt.Fprintf(fs.Output(), "\nFlags of run:\n")
+		fs.PrintDefaults()
+	}
+	f := registerAgentFlags(fs)
+	if err := f.parse(fs, args); err != nil {
+		log.Fatalf("%v", err)
+	}
 
-	var consoleExporter metrics.Exporter
-	if consoleEnabled {
-		consoleExporter = metrics.ConsoleExporter{Out: os.Stdout}
+	stats := &metrics.AgentStats{}
+	p, err := buildPipeline(f, stats, nil)
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
+	stats.ObserveConfigLoad(nil)
 
 	var (
 		latestMu  sync.RWMutex
 		latestRes metrics.Result
 		haveRes   bool
 	)
-
-	var discordExporter metrics.Exporter
-	if discordEnabled {
-		discordExporter = &metrics.DiscordWebhookExporter{
-			WebhookURL: *discordWebhook,
-		}
+	latest := func() (metrics.Result, bool) {
+		latestMu.RLock()
+		defer latestMu.RUnlock()
+		return metrics.Result{
+			Samples: append([]metrics.Sample(nil), latestRes.Samples...),
+			Errors:  append([]metrics.CollectorError(nil), latestRes.Errors...),
+		}, haveRes
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -67,73 +118,226 @@ func main() {
 		cancel()
 	}()
 
-	if discordExporter != nil {
-		discordTicker := time.NewTicker(*discordEvery)
-		defer discordTicker.Stop()
+	// Reload requests carry a channel for the outcome; SIGHUP's is nil.
+	reloads := make(chan chan error)
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			select {
+			case reloads <- nil:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	if *f.adminListen != "" {
+		if err := serveAdmin(ctx, *f.adminListen, reloads); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
 
-		go func() {
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case <-discordTicker.C:
-					latestMu.RLock()
-					if !haveRes {
-						latestMu.RUnlock()
-						continue
-					}
-					res := metrics.Result{
-						Samples: append([]metrics.Sample(nil), latestRes.Samples...),
-						Errors:  append([]metrics.CollectorError(nil), latestRes.Errors...),
-					}
-					latestMu.RUnlock()
-
-					if err := discordExporter.Export(ctx, res); err != nil {
-						log.Printf("discord export error: %v", err)
-					}
-				}
+	// Exporters run off the collection loop, so retries and timeouts of one endpoint
+	// hold up neither collection nor the other exporters.
+	queues := &exportQueues{ctx: ctx, queues: map[string]chan func(context.Context){}}
+	startHeartbeat := func(h *metrics.HeartbeatExporter) {
+		queues.send("heartbeat", func(ctx context.Context) {
+			if err := h.Start(ctx, "version="+version); err != nil {
+				log.Printf("heartbeat start error: %v", err)
 			}
-		}()
+		})
 	}
+	if p.heartbeat != nil {
+		startHeartbeat(p.heartbeat)
+	}
+
+	// The Telegram bot answers commands from the latest result until a reload replaces it.
+	stopBot := func() {}
+	startBot := func(bot *telegram.Bot) {
+		stopBot()
+		stopBot = func() {}
+		if bot == nil {
+			return
+		}
+		bot.Latest = latest
+		botCtx, cancelBot := context.WithCancel(ctx)
+		stopBot = cancelBot
+		go bot.Run(botCtx)
+	}
+	startBot(p.bot)
 
-	ticker := time.NewTicker(*interval)
+	// The Discord poster runs on its own ticker; it is sent every new pipeline.
+	discordUpdates := make(chan *pipeline, 1)
+	discordUpdates <- p
+	go postDiscord(ctx, discordUpdates, latest)
+
+	ticker := time.NewTicker(*p.flags.interval)
 	defer ticker.Stop()
 
 	// Collect immediately once, then on interval
 	for {
-		res := runner.CollectOnce(ctx)
+		res := p.runner.CollectOnce(ctx)
 
 		latestMu.Lock()
 		latestRes = res
 		haveRes = true
 		latestMu.Unlock()
 
-		if consoleExporter != nil {
-			if err := consoleExporter.Export(ctx, res); err != nil {
-				log.Printf("export error: %v", err)
+		for _, e := range p.exporters {
+			queues.send(e.name, func(ctx context.Context) {
+				if err := e.Export(ctx, res); err != nil {
+					log.Printf("%s export error: %v", e.name, err)
+				}
+			})
+		}
+
+		for waiting := true; waiting; {
+			select {
+			case <-ctx.Done():
+				return 0
+			case <-ticker.C:
+				waiting = false
+			case done := <-reloads:
+				next, err := reloadPipeline(args, stats, p)
+				stats.ObserveConfigLoad(err)
+				if done != nil {
+					done <- err
+				}
+				if err != nil {
+					log.Printf("config reload rejected, keeping the running config: %v", err)
+					continue
+				}
+				log.Printf("config reloaded: %d of %d collectors, stages and exporters rebuilt", next.rebuilt, next.rebuilt+next.kept)
+
+				if next.heartbeat != nil && next.heartbeat != p.heartbeat {
+					startHeartbeat(next.heartbeat)
+				}
+				if next.bot != p.bot {
+					startBot(next.bot)
+				}
+				if *next.flags.interval != *p.flags.interval {
+					ticker.Reset(*next.flags.interval)
+				}
+				select {
+				case <-discordUpdates: // not picked up yet; replaced
+				default:
+				}
+				discordUpdates <- next
+				p = next
 			}
 		}
+	}
+}
 
+// exportQueueLen bounds the exports waiting for one exporter. An exporter that falls
+// further behind (an unreachable endpoint being retried) loses its oldest ones.
+const exportQueueLen = 8
+
+// exportQueues runs the exports of each exporter name in order on a goroutine of its
+// own. A reload hands the new pipeline's exporter of a name to the same goroutine, so
+// a kept exporter never runs twice at once.
+type exportQueues struct {
+	ctx    context.Context
+	queues map[string]chan func(context.Context)
+}
+
+// send queues an export for name, dropping the oldest queued one when full.
+func (q *exportQueues) send(name string, export func(context.Context)) {
+	ch, ok := q.queues[name]
+	if !ok {
+		ch = make(chan func(context.Context), exportQueueLen)
+		q.queues[name] = ch
+		go func() {
+			for {
+				select {
+				case <-q.ctx.Done():
+					return
+				case export := <-ch:
+					export(q.ctx)
+				}
+			}
+		}()
+	}
+	for {
 		select {
-		case <-ctx.Done():
+		case ch <- export:
 			return
-		case <-ticker.C:
+		default:
+		}
+		select {
+		case <-ch:
+			log.Printf("%s export is falling behind; dropped the oldest queued result", name)
+		default:
 		}
 	}
 }
 
-func splitCSV(s string) []string {
-	if s == "" {
-		return nil
+type namedExporter struct {
+	name string
+	metrics.Exporter
+}
+
+// wrapExporter instruments an exporter for agent self-metrics and applies the relabel
+// rules configured for its name.
+func wrapExporter(name string, e metrics.Exporter, cfg metrics.RelabelConfig, stats *metrics.AgentStats) metrics.Exporter {
+	e = metrics.InstrumentedExporter{Name: name, Next: e, Stats: stats}
+	if rules := cfg.Exporters[name]; len(rules) > 0 {
+		e = metrics.RelabelingExporter{Next: e, Rules: rules}
 	}
-	parts := strings.Split(s, ",")
-	out := make([]string, 0, len(parts))
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p == "" {
-			continue
+	return e
+}
+
+// splitWords splits a command line into words the way a POSIX shell does, without
+// expansions: blanks separate words, '...' is literal, "..." keeps blanks and lets \
+// escape \ and ", and \ outside quotes escapes the next character.
+func splitWords(s string) ([]string, error) {
+	var (
+		words []string
+		word  strings.Builder
+		inArg bool // word has started, even if it is still empty ('')
+	)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				words = append(words, word.String())
+				word.Reset()
+				inArg = false
+			}
+		case c == '\\':
+			if i+1 == len(s) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			word.WriteByte(s[i])
+			inArg = true
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated ' quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '\\' || s[i+1] == '"') {
+					i++
+				}
+				word.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil, errors.New("unterminated \" quote")
+			}
+			inArg = true
+		default:
+			word.WriteByte(c)
+			inArg = true
 		}
-		out = append(out, p)
 	}
-	return out
+	if inArg {
+		words = append(words, word.String())
+	}
+	return words, nil
 }
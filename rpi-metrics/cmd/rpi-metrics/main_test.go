@@ -0,0 +1,43 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr string
+	}{
+		{"", nil, ""},
+		{"  \t\n ", nil, ""},
+		{"systemctl poweroff", []string{"systemctl", "poweroff"}, ""},
+		{"  sudo\tsystemctl \n poweroff  ", []string{"sudo", "systemctl", "poweroff"}, ""},
+		{`echo 'a b' "c d"`, []string{"echo", "a b", "c d"}, ""},
+		{`echo '' ""`, []string{"echo", "", ""}, ""},
+		{`echo a'b'"c"`, []string{"echo", "abc"}, ""},
+		{`echo a\ b`, []string{"echo", "a b"}, ""},
+		{`echo \'x\"`, []string{"echo", `'x"`}, ""},
+		// Single quotes take everything literally, double quotes only unescape \\ and \".
+		{`echo '\n "x"'`, []string{"echo", `\n "x"`}, ""},
+		{`echo "a \"b\" \\ \n $HOME"`, []string{"echo", `a "b" \ \n $HOME`}, ""},
+		{`echo *.log ~ $PATH`, []string{"echo", "*.log", "~", "$PATH"}, ""},
+		{`echo 'a`, nil, "unterminated ' quote"},
+		{`echo "a`, nil, `unterminated " quote`},
+		{`echo "a\"`, nil, `unterminated " quote`},
+		{`echo a\`, nil, "trailing backslash"},
+	}
+	for _, tt := range tests {
+		got, err := splitWords(tt.in)
+		switch {
+		case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+			t.Errorf("splitWords(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("splitWords(%q): %v", tt.in, err)
+		case !slices.Equal(got, tt.want):
+			t.Errorf("splitWords(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+)
+
+// Formats of `once`.
+const (
+	onceFormatJSON       = "json"
+	onceFormatText       = "text"
+	onceFormatPrometheus = "prometheus"
+)
+
+// onceWarmup is the pause between the two collections of `once`: CPU utilization and
+// rates are deltas between consecutive readings.
+const onceWarmup = time.Second
+
+// runOnce collects a single Result with the agent's collectors and stages, prints it
+// and exits 1 when any collector errored, for cron jobs, Ansible and monitoring checks.
+func runOnce(args []string) int {
+	fs := flag.NewFlagSet("once", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: rpi-metrics once [-format json|text|prometheus] [agent flags]\n\n")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", onceFormatJSON, "output format: json (one console envelope), text or prometheus")
+	warmup := fs.Duration("warmup", onceWarmup, "pause between the two readings that delta metrics (CPU utilization, rates) need; 0 collects once")
+	f := registerAgentFlags(fs)
+	if err := f.parse(fs, args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var out metrics.Exporter
+	switch *format {
+	case onceFormatJSON:
+		out = metrics.ConsoleExporter{Out: os.Stdout}
+	case onceFormatText, onceFormatPrometheus:
+		out = onceWriter{Out: os.Stdout, Format: *format}
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -format %q: want json, text or prometheus\n", *format)
+		return 2
+	}
+
+	stats := &metrics.AgentStats{}
+	collectorList, err := f.collectors(stats)
+	if err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	stages, relabel, err := f.stages()
+	if err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	runner := metrics.Runner{Collectors: collectorList, Stages: stages, Stats: stats}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	res, err := collectWarm(ctx, runner, *warmup)
+	if err != nil {
+		return 1
+	}
+
+	// Printed like the console exporter, so its relabel rules apply.
+	if err := wrapExporter("console", out, relabel, stats).Export(ctx, res); err != nil {
+		log.Printf("write result: %v", err)
+		return 2
+	}
+	if len(res.Errors) > 0 {
+		if *format != onceFormatJSON { // the JSON envelope carries the errors itself
+			for _, e := range res.Errors {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", e.CollectorID, e.Error)
+			}
+		}
+		return 1
+	}
+	return 0
+}
+
+// onceWriter prints a Result as a table (text) or in the Prometheus text format.
+type onceWriter struct {
+	Out    io.Writer
+	Format string
+}
+
+func (w onceWriter) Export(ctx context.Context, res metrics.Result) error {
+	_ = ctx
+	if w.Format == onceFormatPrometheus {
+		return metrics.WritePrometheusText(w.Out, res.Samples)
+	}
+
+	tw := tabwriter.NewWriter(w.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tLABELS\tVALUE")
+	for _, s := range res.Samples {
+		labels := joinLabels(s.Labels)
+		if labels == "" {
+			labels = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, labels, metrics.FormatSample(s))
+	}
+	return tw.Flush()
+}
+
+// collectWarm collects twice, warmup apart, and returns the second Result: CPU
+// utilization and rates are deltas between consecutive readings.
+func collectWarm(ctx context.Context, runner metrics.Runner, warmup time.Duration) (metrics.Result, error) {
+	res := runner.CollectOnce(ctx)
+	if warmup <= 0 {
+		return res, nil
+	}
+	select {
+	case <-ctx.Done():
+		return metrics.Result{}, ctx.Err()
+	case <-time.After(warmup):
+	}
+	return runner.CollectOnce(ctx), nil
+}
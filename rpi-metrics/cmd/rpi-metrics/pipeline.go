@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/schedule"
+	"rpi-metrics/internal/telegram"
+)
+
+// pipeline is what the agent builds from its flags, and what a reload replaces.
+type pipeline struct {
+	flags     *agentFlags
+	runner    metrics.Runner
+	exporters []namedExporter  // run after every collection cycle
+	discord   metrics.Exporter // nil when periodic Discord posts are off (or digests replace them)
+	heartbeat *metrics.HeartbeatExporter
+	bot       *telegram.Bot // nil when -telegram-commands is off
+
+	// parts are the built collectors, stages and exporters by config key (see keep).
+	parts         map[string]any
+	kept, rebuilt int
+}
+
+// keep returns the part of prev built from the same config as fresh, so a reload
+// keeps its state (CPU counters, rate and forecast history, the push queue), or
+// fresh when the config changed. config is the configKey of the values fresh was
+// built from; fresh's type is added to it.
+func keep[T any](p, prev *pipeline, config string, fresh T) T {
+	key := fmt.Sprintf("%T %s", fresh, config)
+	if prev != nil {
+		if old, ok := prev.parts[key].(T); ok {
+			p.parts[key] = old
+			p.kept++
+			return old
+		}
+	}
+	p.parts[key] = fresh
+	p.rebuilt++
+	return fresh
+}
+
+// configKey joins plain config values (numbers, strings, durations and slices, maps
+// and structs of them) into a key for keep. Unexported struct fields are state and
+// are skipped, and nil pointers, funcs and channels are "nil". It panics on other
+// pointers, funcs and channels: they print as addresses, which would make a part look
+// changed on every reload, so pass their config instead.
+func configKey(values ...any) string {
+	var b strings.Builder
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		writeConfigKey(&b, reflect.ValueOf(v))
+	}
+	return b.String()
+}
+
+func writeConfigKey(b *strings.Builder, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		b.WriteString("nil")
+	case reflect.Pointer, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if !v.IsNil() {
+			panic(fmt.Sprintf("configKey: %s is not a plain config value", v.Type()))
+		}
+		b.WriteString("nil")
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		fmt.Fprintf(b, "%v", v)
+	case reflect.String:
+		b.WriteString(strconv.Quote(v.String()))
+	case reflect.Interface:
+		writeConfigKey(b, v.Elem())
+	case reflect.Slice, reflect.Array:
+		b.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			writeConfigKey(b, v.Index(i))
+		}
+		b.WriteByte(']')
+	case reflect.Map:
+		entries := make([]string, 0, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			var e strings.Builder
+			writeConfigKey(&e, it.Key())
+			e.WriteByte(':')
+			writeConfigKey(&e, it.Value())
+			entries = append(entries, e.String())
+		}
+		sort.Strings(entries)
+		b.WriteString("map[" + strings.Join(entries, " ") + "]")
+	case reflect.Struct:
+		b.WriteByte('{')
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			b.WriteString(field.Name + ":")
+			writeConfigKey(b, v.Field(i))
+			b.WriteByte(' ')
+		}
+		b.WriteByte('}')
+	default:
+		panic(fmt.Sprintf("configKey: %s is not a plain config value", v.Type()))
+	}
+}
+
+// buildPipeline builds the collectors, stages and exporters configured by f, reusing
+// the unchanged ones of prev (nil at startup).
+func buildPipeline(f *agentFlags, stats *metrics.AgentStats, prev *pipeline) (*pipeline, error) {
+	p := &pipeline{flags: f, parts: map[string]any{}}
+
+	// Checked here, not by the ticker that would panic on them: a reload with a bad
+	// value must fail and keep the running pipeline.
+	if *f.interval <= 0 {
+		return nil, fmt.Errorf("invalid -%s %s: must be positive", constants.FlagInterval, *f.interval)
+	}
+	for name, d := range map[string]time.Duration{constants.FlagDiscordEvery: *f.discordEvery, constants.FlagHeartbeatEvery: *f.heartbeatEvery} {
+		if d < 0 {
+			return nil, fmt.Errorf("invalid -%s %s: must not be negative", name, d)
+		}
+	}
+
+	collectorList, collectorKeys, err := f.collectorsWithKeys(stats)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range collectorList {
+		collectorList[i] = keep(p, prev, collectorKeys[i], c)
+	}
+	stages, stageKeys, relabel, err := f.stagesWithKeys()
+	if err != nil {
+		return nil, err
+	}
+	for i, s := range stages {
+		stages[i] = keep(p, prev, stageKeys[i], s)
+	}
+	p.runner = metrics.Runner{Collectors: collectorList, Stages: stages, Stats: stats}
+
+	var digest schedule.Schedule
+	if *f.discordDigest != "" {
+		var err error
+		if digest, err = schedule.Parse(*f.discordDigest); err != nil {
+			return nil, fmt.Errorf("invalid -%s: %w", constants.FlagDiscordDigest, err)
+		}
+	}
+	discordEnabled := *f.discordWebhook != "" && (*f.discordEvery > 0 || !digest.IsZero())
+	consoleEnabled := !discordEnabled || *f.alsoConsole
+
+	if consoleEnabled {
+		console := keep(p, prev, configKey(), metrics.ConsoleExporter{Out: os.Stdout})
+		p.exporters = append(p.exporters, namedExporter{"console", wrapExporter("console", console, relabel, stats)})
+	}
+
+	if *f.pushURL != "" {
+		push := keep(p, prev, configKey(*f.pushURL, *f.pushToken, *f.pushBatch, *f.pushMaxQueue), &metrics.PushExporter{
+			URL:       *f.pushURL,
+			Token:     *f.pushToken,
+			BatchSize: *f.pushBatch,
+			MaxQueue:  *f.pushMaxQueue,
+		})
+		p.exporters = append(p.exporters, namedExporter{"push", wrapExporter("push", push, relabel, stats)})
+	}
+
+	webhooks, err := f.webhooks()
+	if err != nil {
+		return nil, err
+	}
+	var notifiers []func(context.Context, string) error
+	if *f.discordWebhook != "" {
+		notifiers = append(notifiers, (&metrics.DiscordWebhookExporter{WebhookURL: *f.discordWebhook, Retry: f.retry()}).Notify)
+	}
+	for _, c := range webhooks {
+		webhook := keep(p, prev, configKey(c, f.retry()), &metrics.WebhookExporter{Config: c, Retry: f.retry()})
+		name := "webhook:" + c.Name
+		p.exporters = append(p.exporters, namedExporter{name, wrapExporter(name, webhook, relabel, stats)})
+		notifiers = append(notifiers, webhook.Notify)
+	}
+
+	tgClient, tgChats, err := f.telegram()
+	if err != nil {
+		return nil, err
+	}
+	tgKey := configKey(tgClient.BaseURL, tgClient.Token, tgClient.Retry, tgChats)
+	if tgClient.Token != "" {
+		tg := keep(p, prev, configKey(tgKey, *f.telegramEvery), &telegram.Exporter{Client: tgClient, ChatIDs: tgChats, Every: *f.telegramEvery})
+		p.exporters = append(p.exporters, namedExporter{"telegram", wrapExporter("telegram", tg, relabel, stats)})
+		notifiers = append(notifiers, tg.Notify)
+		if *f.telegramCommands {
+			p.bot = keep(p, prev, tgKey, &telegram.Bot{Client: tgClient, ChatIDs: tgChats})
+		}
+	}
+
+	mail, err := f.email()
+	if err != nil {
+		return nil, err
+	}
+	var mailKey string
+	if mail != nil {
+		s := mail.Sender
+		mailKey = configKey(s.Addr, s.TLS, s.Username, s.Password, s.From, s.To, s.Retry, mail.Digest.String())
+		mail = keep(p, prev, mailKey, mail)
+		p.exporters = append(p.exporters, namedExporter{"email", wrapExporter("email", mail, relabel, stats)})
+		notifiers = append(notifiers, mail.Notify)
+	}
+
+	if *f.shutdownBelow > 0 {
+		argv, err := splitWords(*f.shutdownCommand)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -%s %q: %w", constants.FlagShutdownCommand, *f.shutdownCommand, err)
+		}
+		if len(argv) == 0 {
+			return nil, fmt.Errorf("-%s is empty", constants.FlagShutdownCommand)
+		}
+		// The notifiers are part of the config: a changed one rebuilds the action.
+		notifyKey := configKey(*f.discordWebhook, webhooks, f.retry(), tgKey, mailKey)
+		config := configKey(*f.shutdownBelow, *f.shutdownAfter, *f.shutdownGrace, argv, *f.shutdownDryRun, notifyKey)
+		action := keep(p, prev, config, &metrics.ShutdownAction{
+			BelowPercent: *f.shutdownBelow,
+			After:        *f.shutdownAfter,
+			Grace:        *f.shutdownGrace,
+			Command:      argv,
+			DryRun:       *f.shutdownDryRun,
+		})
+		// The notice goes to Discord, every webhook, Telegram and email, even when
+		// periodic Discord posts are off.
+		if action.Notify == nil && len(notifiers) > 0 {
+			action.Notify = notifyAll(notifiers)
+		}
+		p.exporters = append(p.exporters, namedExporter{"shutdown", wrapExporter("shutdown", action, relabel, stats)})
+	}
+
+	if *f.heartbeatURL != "" {
+		method := strings.ToUpper(*f.heartbeatMethod)
+		if method != "POST" && method != "GET" {
+			return nil, fmt.Errorf("invalid -%s %q: want POST or GET", constants.FlagHeartbeatMethod, *f.heartbeatMethod)
+		}
+		p.heartbeat = keep(p, prev, configKey(*f.heartbeatURL, method, *f.heartbeatFailOnErrors, *f.heartbeatEvery), &metrics.HeartbeatExporter{
+			URL:          *f.heartbeatURL,
+			Method:       method,
+			FailOnErrors: *f.heartbeatFailOnErrors,
+			MinInterval:  *f.heartbeatEvery,
+		})
+		p.exporters = append(p.exporters, namedExporter{"heartbeat", wrapExporter("heartbeat", p.heartbeat, relabel, stats)})
+	}
+
+	switch {
+	case discordEnabled && !digest.IsZero():
+		// Digests summarize every cycle, so they run with the other exporters.
+		d := keep(p, prev, configKey(digest.String(), *f.discordWebhook, f.retry()), &metrics.DigestExporter{Schedule: digest})
+		if d.Notify == nil {
+			d.Notify = (&metrics.DiscordWebhookExporter{WebhookURL: *f.discordWebhook, Retry: f.retry()}).Notify
+		}
+		p.exporters = append(p.exporters, namedExporter{"discord", wrapExporter("discord", d, relabel, stats)})
+	case discordEnabled:
+		discord := keep(p, prev, configKey(*f.discordWebhook, f.retry()), &metrics.DiscordWebhookExporter{WebhookURL: *f.discordWebhook, Retry: f.retry()})
+		p.discord = wrapExporter("discord", discord, relabel, stats)
+	}
+	return p, nil
+}
+
+// notifyAll sends a notice to every notifier and returns the first error.
+func notifyAll(notifiers []func(context.Context, string) error) func(context.Context, string) error {
+	return func(ctx context.Context, msg string) error {
+		var first error
+		for _, notify := range notifiers {
+			if err := notify(ctx, msg); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	}
+}
+
+// reloadPipeline parses args and the -config file again and builds the pipeline they
+// describe from cur. The caller keeps running cur when it returns an error.
+func reloadPipeline(args []string, stats *metrics.AgentStats, cur *pipeline) (*pipeline, error) {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	f := registerAgentFlags(fs)
+	if err := f.parse(fs, args); err != nil {
+		return nil, err
+	}
+	next, err := buildPipeline(f, stats, cur)
+	if err != nil {
+		return nil, err
+	}
+	if *f.adminListen != *cur.flags.adminListen {
+		log.Printf("warning: -%s changed; restart the agent to apply it", constants.FlagAdminListen)
+	}
+	return next, nil
+}
+
+// postDiscord posts the latest result every -discord-every of the pipelines sent on
+// updates, until ctx is done. A pipeline without Discord stops the posts.
+func postDiscord(ctx context.Context, updates <-chan *pipeline, latest func() (metrics.Result, bool)) {
+	var (
+		discord metrics.Exporter
+		every   time.Duration
+		ticker  *time.Ticker
+		tick    <-chan time.Time
+	)
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p := <-updates:
+			discord = p.discord
+			switch {
+			case discord == nil && ticker != nil:
+				ticker.Stop()
+				ticker, tick = nil, nil
+			case discord != nil && (ticker == nil || every != *p.flags.discordEvery):
+				if ticker != nil {
+					ticker.Stop()
+				}
+				every = *p.flags.discordEvery
+				ticker = time.NewTicker(every)
+				tick = ticker.C
+			}
+		case <-tick:
+			res, ok := latest()
+			if !ok {
+				continue
+			}
+			if err := discord.Export(ctx, res); err != nil {
+				log.Printf("discord export error: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/metrics"
+)
+
+// allExporterArgs turns on every exporter, notifier and optional collector.
+func allExporterArgs(t *testing.T) []string {
+	dir := t.TempDir()
+	hooks := filepath.Join(dir, "webhooks.json")
+	if err := os.WriteFile(hooks, []byte(`{"webhooks":[{"name":"ntfy","url":"http://127.0.0.1:1/ntfy","body":"{{ .Text }}","every":"10m"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	flag := func(name, value string) string { return "-" + name + "=" + value }
+	return []string{
+		flag(constants.FlagAlsoConsole, "true"),
+		flag(constants.FlagExec, `backup=/bin/echo "backup_ok 1"`),
+		flag(constants.FlagTextfileDir, dir),
+		flag(constants.FlagPushURL, "http://127.0.0.1:1/api/push"),
+		flag(constants.FlagPushToken, "push-token"),
+		flag(constants.FlagDiscordWebhook, "http://127.0.0.1:1/discord"),
+		flag(constants.FlagDiscordEvery, "10m"),
+		flag(constants.FlagWebhookConfig, hooks),
+		flag(constants.FlagTelegramToken, "123:abc"),
+		flag(constants.FlagTelegramChatIDs, "42"),
+		flag(constants.FlagTelegramCommands, "true"),
+		flag(constants.FlagSMTPAddr, "127.0.0.1:2525"),
+		flag(constants.FlagSMTPTLS, "none"),
+		flag(constants.FlagSMTPUser, "pi"),
+		flag(constants.FlagSMTPPassword, "s3cret"),
+		flag(constants.FlagEmailFrom, "pi@example.com"),
+		flag(constants.FlagEmailTo, "ops@example.com"),
+		flag(constants.FlagEmailDigestAt, "08:00"),
+		flag(constants.FlagShutdownBelow, "10"),
+		flag(constants.FlagHeartbeatURL, "http://127.0.0.1:1/ping/pi"),
+	}
+}
+
+func parseAgentFlags(t *testing.T, args []string) *agentFlags {
+	t.Helper()
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	f := registerAgentFlags(fs)
+	if err := f.parse(fs, args); err != nil {
+		t.Fatalf("parse %q: %v", args, err)
+	}
+	return f
+}
+
+func exporterNames(p *pipeline) string {
+	var names []string
+	for _, e := range p.exporters {
+		names = append(names, e.name)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestBuildPipelineAllExporters(t *testing.T) {
+	args := allExporterArgs(t)
+	stats := &metrics.AgentStats{}
+	p, err := buildPipeline(parseAgentFlags(t, args), stats, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := exporterNames(p), "console push webhook:ntfy telegram email shutdown heartbeat"; got != want {
+		t.Errorf("exporters = %q, want %q", got, want)
+	}
+	if p.discord == nil || p.heartbeat == nil || p.bot == nil {
+		t.Errorf("discord %v, heartbeat %v, bot %v: want all three", p.discord, p.heartbeat, p.bot)
+	}
+
+	// The same config rebuilds nothing, so every part keeps its state.
+	same, err := reloadPipeline(args, stats, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.rebuilt != 0 || same.kept != p.rebuilt {
+		t.Errorf("reload with the same config rebuilt %d, kept %d of %d", same.rebuilt, same.kept, p.rebuilt)
+	}
+
+	// A new recipient rebuilds the email exporter and the shutdown action it notifies.
+	changed, err := reloadPipeline(append(args, "-"+constants.FlagEmailTo+"=owner@example.com"), stats, same)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.rebuilt != 2 {
+		t.Errorf("changing -%s rebuilt %d parts, want 2", constants.FlagEmailTo, changed.rebuilt)
+	}
+}
+
+func TestBuildPipelineDigest(t *testing.T) {
+	args := append(allExporterArgs(t), "-"+constants.FlagDiscordDigest+"=every day 08:00")
+	p, err := buildPipeline(parseAgentFlags(t, args), &metrics.AgentStats{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := exporterNames(p), "console push webhook:ntfy telegram email shutdown heartbeat discord"; got != want {
+		t.Errorf("exporters = %q, want %q", got, want)
+	}
+	if p.discord != nil {
+		t.Error("periodic Discord posts are on alongside the digest")
+	}
+}
+
+func TestConfigKey(t *testing.T) {
+	type config struct {
+		Name  string
+		Every int
+		Hook  *int
+		state int
+	}
+	a := configKey("x", 1.5, []string{"a", "b"}, map[string]int{"b": 2, "a": 1}, config{Name: "n", Every: 3, state: 1})
+	b := configKey("x", 1.5, []string{"a", "b"}, map[string]int{"a": 1, "b": 2}, config{Name: "n", Every: 3, state: 2})
+	if a != b {
+		t.Errorf("equal configs give different keys:\n%s\n%s", a, b)
+	}
+	if a == configKey("x", 1.5, []string{"a", "b"}, map[string]int{"a": 1, "b": 3}, config{Name: "n", Every: 3}) {
+		t.Error("a changed map value keeps the key")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("configKey of a non-nil pointer did not panic")
+		}
+	}()
+	n := 1
+	configKey(config{Hook: &n})
+}
+
+func TestBuildPipelineRejectsBadDurations(t *testing.T) {
+	stats := &metrics.AgentStats{}
+	cur, err := buildPipeline(parseAgentFlags(t, nil), stats, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, arg := range []string{
+		"-" + constants.FlagInterval + "=0",
+		"-" + constants.FlagInterval + "=-5s",
+		"-" + constants.FlagDiscordEvery + "=-1m",
+		"-" + constants.FlagHeartbeatEvery + "=-1m",
+	} {
+		if _, err := buildPipeline(parseAgentFlags(t, []string{arg}), stats, nil); err == nil {
+			t.Errorf("buildPipeline(%s) succeeded", arg)
+		}
+
+		// A reload from a config file fails the same way, before anything panics.
+		config := filepath.Join(t.TempDir(), "agent.conf")
+		if err := os.WriteFile(config, []byte(strings.TrimPrefix(arg, "-")+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := reloadPipeline([]string{"-" + constants.FlagConfig + "=" + config}, stats, cur); err == nil {
+			t.Errorf("reload with %s in the config file succeeded", arg)
+		}
+	}
+}
+
+func TestBuildPipelineShutdownCommand(t *testing.T) {
+	stats := &metrics.AgentStats{}
+	below := "-" + constants.FlagShutdownBelow + "=10"
+	command := "-" + constants.FlagShutdownCommand + "="
+
+	p, err := buildPipeline(parseAgentFlags(t, []string{below, command + `sudo /usr/local/bin/notify-off "battery low"`}), stats, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var action *metrics.ShutdownAction
+	for _, e := range p.exporters {
+		if e.name == "shutdown" {
+			action, _ = e.Exporter.(metrics.InstrumentedExporter).Next.(*metrics.ShutdownAction)
+		}
+	}
+	if want := []string{"sudo", "/usr/local/bin/notify-off", "battery low"}; action == nil || !slices.Equal(action.Command, want) {
+		t.Errorf("shutdown action %+v, want command %q", action, want)
+	}
+
+	for _, value := range []string{`"`, `sudo 'poweroff`, `poweroff\`, "  "} {
+		if _, err := buildPipeline(parseAgentFlags(t, []string{below, command + value}), stats, nil); err == nil {
+			t.Errorf("buildPipeline(-%s=%q) succeeded", constants.FlagShutdownCommand, value)
+		}
+	}
+}
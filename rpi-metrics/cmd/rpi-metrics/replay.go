@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/schedule"
+	"rpi-metrics/internal/telegram"
+)
+
+// replayTarget is an exporter fed by replay. every throttles it by the recorded time of
+// the envelopes (not wall time), so -discord-every=10m posts what the agent would have.
+type replayTarget struct {
+	namedExporter
+	every time.Duration
+	last  time.Time
+}
+
+// runReplay feeds recorded console output (JSON Lines of metrics.ConsoleEnvelope)
+// through exporters and the built-in alert rules.
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: rpi-metrics replay [flags] FILE.jsonl (- for stdin)\n\n")
+		fs.PrintDefaults()
+	}
+	speed := fs.Float64("speed", 0, "replay at this multiple of the recorded pace (1 = real time; 0 = as fast as possible)")
+	retime := fs.Bool("retime", false, "shift timestamps so the first envelope happened now")
+	alerts := fs.Bool("alerts", true, "print alerts as they fire and resolve")
+	console := fs.Bool("console", false, "print each envelope as JSON to stdout (after relabeling)")
+	relabelConfig := fs.String(constants.FlagRelabelConfig, constants.DefaultRelabelConfig, constants.FlagUsageRelabelConfig)
+	discordWebhook := secretString(fs, constants.FlagDiscordWebhook, constants.FlagUsageDiscordWebhook)
+	discordEvery := fs.Duration(constants.FlagDiscordEvery, constants.DefaultReplayDiscordEvery, "Post to Discord at most this often, in recorded time (0 posts every envelope, which Discord rate limits unless -speed is slow)")
+	pushURL := fs.String(constants.FlagPushURL, constants.DefaultPushURL, constants.FlagUsagePushURL)
+	pushToken := secretString(fs, constants.FlagPushToken, constants.FlagUsagePushToken)
+	pushBatch := fs.Int(constants.FlagPushBatch, 20, constants.FlagUsagePushBatch)
+	var notify notifyFlags
+	notify.register(fs)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *discordEvery < 0 {
+		log.Printf("replay: invalid -%s %s: must not be negative", constants.FlagDiscordEvery, *discordEvery)
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Printf("replay: %v", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var relabel metrics.RelabelConfig
+	if *relabelConfig != "" {
+		var err error
+		relabel, err = metrics.LoadRelabelConfig(*relabelConfig)
+		if err != nil {
+			log.Printf("replay: %v", err)
+			return 1
+		}
+	}
+
+	stats := &metrics.AgentStats{}
+	var targets []*replayTarget
+	if *console {
+		targets = append(targets, &replayTarget{namedExporter: namedExporter{"console",
+			wrapExporter("console", metrics.ConsoleExporter{Out: os.Stdout}, relabel, stats)}})
+	}
+	if *discordWebhook != "" {
+		targets = append(targets, &replayTarget{namedExporter: namedExporter{"discord",
+			wrapExporter("discord", &metrics.DiscordWebhookExporter{WebhookURL: *discordWebhook}, relabel, stats)},
+			every: *discordEvery})
+	}
+	webhooks, err := notify.webhooks()
+	if err != nil {
+		log.Printf("replay: %v", err)
+		return 1
+	}
+	for _, c := range webhooks {
+		name := "webhook:" + c.Name
+		targets = append(targets, &replayTarget{namedExporter: namedExporter{name,
+			wrapExporter(name, &metrics.WebhookExporter{Config: c, Retry: notify.retry(), IgnoreEvery: true}, relabel, stats)},
+			every: c.Interval()})
+	}
+	tgClient, tgChats, err := notify.telegram()
+	if err != nil {
+		log.Printf("replay: %v", err)
+		return 1
+	}
+	if tgClient.Token != "" {
+		// Alerts only: Every counts wall time, so snapshots would not match the recording.
+		targets = append(targets, &replayTarget{namedExporter: namedExporter{"telegram",
+			wrapExporter("telegram", &telegram.Exporter{Client: tgClient, ChatIDs: tgChats}, relabel, stats)}})
+	}
+	mail, err := notify.email()
+	if err != nil {
+		log.Printf("replay: %v", err)
+		return 1
+	}
+	if mail != nil {
+		mail.Digest = schedule.Schedule{} // digests follow the wall clock; mail alerts only
+		targets = append(targets, &replayTarget{namedExporter: namedExporter{"email",
+			wrapExporter("email", mail, relabel, stats)}})
+	}
+	var push *metrics.PushExporter
+	if *pushURL != "" {
+		push = &metrics.PushExporter{URL: *pushURL, Token: *pushToken, BatchSize: *pushBatch, MaxQueue: max(*pushBatch, constants.DefaultPushMaxQueue)}
+		targets = append(targets, &replayTarget{namedExporter: namedExporter{"push",
+			wrapExporter("push", push, relabel, stats)}})
+	}
+	if len(targets) == 0 && !*alerts {
+		log.Printf("replay: nothing to do; enable -alerts, -console, -%s, -%s, -%s, -%s or -%s",
+			constants.FlagDiscordWebhook, constants.FlagWebhookConfig, constants.FlagTelegramToken, constants.FlagSMTPAddr, constants.FlagPushURL)
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	var (
+		n, failed    int
+		offset       time.Duration
+		first, wall  time.Time
+		firingAlerts = map[string]metrics.Alert{}
+	)
+
+	dec := json.NewDecoder(bufio.NewReader(in))
+	for {
+		var env metrics.ConsoleEnvelope
+		if err := dec.Decode(&env); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			log.Printf("replay: envelope %d: %v", n+1, err)
+			return 1
+		}
+		n++
+
+		if first.IsZero() {
+			first, wall = env.CollectedAt, time.Now()
+			if *retime {
+				offset = wall.Sub(first)
+			}
+		}
+
+		// Pace against the recorded time since the first envelope.
+		if *speed > 0 {
+			due := wall.Add(time.Duration(float64(env.CollectedAt.Sub(first)) / *speed))
+			select {
+			case <-ctx.Done():
+				return 1
+			case <-time.After(time.Until(due)):
+			}
+		} else if ctx.Err() != nil {
+			return 1
+		}
+
+		at := env.CollectedAt.Add(offset)
+		res := metrics.Result{Samples: env.Samples, Errors: env.Errors}
+		if offset != 0 {
+			for i := range res.Samples {
+				res.Samples[i].Timestamp = res.Samples[i].Timestamp.Add(offset)
+			}
+		}
+
+		// The global rules, as the agent's Relabeler stage applies them before any exporter.
+		res = metrics.Relabeler{Rules: relabel.Relabel}.Process(res)
+
+		if *alerts {
+			printAlertChanges(at, metrics.DetectAlerts(res), firingAlerts)
+		}
+		for _, t := range targets {
+			if t.every > 0 && !t.last.IsZero() && at.Sub(t.last) < t.every {
+				continue
+			}
+			t.last = at
+			if err := t.Export(ctx, res); err != nil {
+				failed++
+				log.Printf("%s export error (envelope %d, %s): %v", t.name, n, at.Format(time.RFC3339), err)
+			}
+		}
+	}
+
+	// Send the last partial batch.
+	if push != nil {
+		if err := push.Flush(ctx); err != nil {
+			failed++
+			log.Printf("push export error: %v", err)
+		}
+	}
+
+	log.Printf("replayed %d envelopes (%d export errors)", n, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printAlertChanges prints alerts that started or stopped firing since the last envelope.
+func printAlertChanges(at time.Time, current []metrics.Alert, firing map[string]metrics.Alert) {
+	now := make(map[string]metrics.Alert, len(current))
+	for _, a := range current {
+		key := a.Name + "|" + joinLabels(a.Labels)
+		now[key] = a
+		if _, ok := firing[key]; !ok {
+			fmt.Printf("%s FIRING   %-8s %s: %s\n", at.UTC().Format(time.RFC3339), a.Severity, a.Name, a.Message)
+		}
+	}
+
+	var resolved []string
+	for key := range firing {
+		if _, ok := now[key]; !ok {
+			resolved = append(resolved, key)
+		}
+	}
+	sort.Strings(resolved)
+	for _, key := range resolved {
+		a := firing[key]
+		fmt.Printf("%s RESOLVED %-8s %s: %s\n", at.UTC().Format(time.RFC3339), a.Severity, a.Name, a.Message)
+		delete(firing, key)
+	}
+	for key, a := range now {
+		firing[key] = a
+	}
+}
+
+// joinLabels renders labels as sorted k=v pairs.
+func joinLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
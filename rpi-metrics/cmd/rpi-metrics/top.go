@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/top"
+)
+
+// Bounds of the +/- keys of top.
+const (
+	topMinInterval = 500 * time.Millisecond
+	topMaxInterval = time.Minute
+)
+
+const (
+	ansiAltScreenOn  = "\x1b[?1049h\x1b[?25l" // alternate screen, hide cursor
+	ansiAltScreenOff = "\x1b[?25h\x1b[?1049l"
+)
+
+// runTop is the terminal dashboard: the agent's collectors, redrawn every -interval.
+// Without a terminal that understands ANSI (or with -plain) frames are printed one
+// after another and keys are read a line at a time.
+func runTop(args []string) int {
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	plain := fs.Bool("plain", false, "print plain frames without colors or cursor control")
+	f := registerAgentFlags(fs)
+	if err := f.parse(fs, args); err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+
+	stats := &metrics.AgentStats{}
+	collectorList, err := f.collectors(stats)
+	if err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	stages, _, err := f.stages()
+	if err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	toggles := make([]top.Toggle, len(collectorList))
+	for i, c := range collectorList {
+		toggles[i] = top.Toggle{ID: c.ID(), Enabled: true}
+	}
+
+	stdin, stdout := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	term := os.Getenv("TERM")
+	ansi := !*plain && top.IsTerminal(stdout) && term != "" && term != "dumb"
+	if top.IsTerminal(stdin) {
+		if restore, err := top.EnableKeys(stdin); err == nil {
+			defer restore()
+		}
+	}
+	if ansi {
+		fmt.Print(ansiAltScreenOn)
+		defer fmt.Print(ansiAltScreenOff)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	keys := make(chan byte)
+	go func() {
+		r := bufio.NewReader(os.Stdin)
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				return // no keyboard (e.g. stdin is /dev/null): just keep redrawing
+			}
+			select {
+			case keys <- b:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+
+	host, _ := os.Hostname()
+	var (
+		procs    top.ProcSampler
+		interval = *f.interval
+		sortBy   top.ProcSort
+		paused   bool
+		res      metrics.Result
+		procList []top.Proc
+	)
+	collect := func() {
+		var enabled []metrics.Collector
+		for i, c := range collectorList {
+			if toggles[i].Enabled {
+				enabled = append(enabled, c)
+			}
+		}
+		runner := metrics.Runner{Collectors: enabled, Stages: stages, Stats: stats}
+		res = runner.CollectOnce(ctx)
+		if p, err := procs.Sample(); err == nil {
+			procList = p
+		}
+	}
+	draw := func() {
+		frame := top.Frame{
+			Host:       host,
+			Result:     res,
+			Procs:      procList,
+			Interval:   interval,
+			Sort:       sortBy,
+			Paused:     paused,
+			Collectors: toggles,
+		}
+		if ansi {
+			frame.Width, frame.Height, _ = top.Size(stdout)
+		}
+		if err := top.Render(os.Stdout, frame, ansi); err != nil {
+			log.Printf("top: %v", err)
+		}
+	}
+
+	collect()
+	draw()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-winch:
+		case <-ticker.C:
+			if paused {
+				continue
+			}
+			collect()
+		case k := <-keys:
+			switch {
+			case k == 'q' || k == 'Q':
+				return 0
+			case k == '+' || k == '=':
+				interval = min(interval*2, topMaxInterval)
+				ticker.Reset(interval)
+			case k == '-':
+				interval = max(interval/2, topMinInterval)
+				ticker.Reset(interval)
+			case k == 's' || k == 'S':
+				sortBy = sortBy.Next()
+			case k == 'p' || k == 'P' || k == ' ':
+				paused = !paused
+			case k >= '1' && k <= '9' && int(k-'1') < len(toggles):
+				toggles[k-'1'].Enabled = !toggles[k-'1'].Enabled
+				collect()
+			default:
+				continue // includes the newline after each key in line mode
+			}
+		}
+		draw()
+	}
+}
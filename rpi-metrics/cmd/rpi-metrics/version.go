@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"rpi-metrics/internal/collectors"
+)
+
+// runVersion prints the version and commit set at build time (see version and commit).
+func runVersion(args []string) int {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print version information as JSON")
+	fs.Parse(args)
+
+	info := collectors.AgentSelf{Version: version, Commit: commit}.BuildLabels()
+	if *asJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(info); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Printf("rpi-metrics %s (commit %s, %s)\n", info["version"], info["commit"], info["go_version"])
+	return 0
+}
@@ -8,8 +8,131 @@ const (
 	DefaultCPUTempSysfsPath       = "/sys/class/thermal/thermal_zone0/temp"
 	DefaultCPUCoolingDevicefsPath = "/sys/class/thermal/cooling_device0/cur_state"
 	DefaultStoragePathsCSV        = "/"
+	DefaultStorageDiscover        = false
+
+	DefaultAgentMetrics = true
 
 	DefaultDiscordWebhookURL        = ""
 	DefaultDiscordPostEvery         = time.Duration(0)
 	DefaultAlsoConsoleWhenDiscordOn = false
 )
+
+const (
+	DefaultSysBlockPath  = "/sys/block"
+	DefaultDiskstatsPath = "/proc/diskstats"
+	DefaultUptimePath    = "/proc/uptime"
+	DefaultProcStatPath  = "/proc/stat"
+	DefaultMMCStatePath  = ""
+)
+
+const (
+	DefaultStorageForecastWindow = 6 * time.Hour
+	DefaultStorageForecastMinR2  = 0.8
+
+	// DefaultStorageFullAlertHorizon raises a "will be full" alert when the predicted
+	// time until a mount is full drops below it.
+	DefaultStorageFullAlertHorizon = 72 * time.Hour
+)
+
+const (
+	DefaultExecTimeout        = 10 * time.Second
+	DefaultExecMaxOutputBytes = 1 << 20
+	DefaultExecFormat         = "auto"
+)
+
+const (
+	DefaultTextfileDir      = ""
+	DefaultTextfileMaxBytes = 1 << 20
+)
+
+const (
+	DefaultDeviceTreeModelPath  = "/proc/device-tree/model"
+	DefaultDeviceTreeSerialPath = "/proc/device-tree/serial-number"
+	DefaultCPUInfoPath          = "/proc/cpuinfo"
+
+	DefaultStaticLabelsCSV = ""
+	DefaultHostLabelsCSV   = "host"
+	DefaultRelabelConfig   = ""
+)
+
+const (
+	DefaultHeartbeatURL          = ""
+	DefaultHeartbeatMethod       = "POST"
+	DefaultHeartbeatEvery        = time.Minute
+	DefaultHeartbeatFailOnErrors = false
+
+	DefaultHeartbeatListen     = ":8090"
+	DefaultHeartbeatTimeout    = 5 * time.Minute
+	DefaultHeartbeatCheckEvery = 30 * time.Second
+)
+
+const (
+	DefaultPushURL      = ""
+	DefaultPushToken    = ""
+	DefaultPushBatch    = 1
+	DefaultPushMaxQueue = 100
+)
+
+const (
+	DefaultPowerSupplyPath = "/sys/class/power_supply"
+
+	DefaultShutdownBelowPercent = 0 // disabled
+	DefaultShutdownAfter        = 2 * time.Minute
+	DefaultShutdownGrace        = time.Minute
+	DefaultShutdownCommand      = "systemctl poweroff"
+	DefaultShutdownDryRun       = false
+)
+
+const (
+	DefaultSysClassNetPath = "/sys/class/net"
+	DefaultWirelessPath    = "/proc/net/wireless"
+	DefaultIwPath          = "iw"
+)
+
+const DefaultRates = true
+
+const (
+	DefaultThermalClassPath = "/sys/class/thermal"
+	DefaultHwmonClassPath   = "/sys/class/hwmon"
+	DefaultThrottledPath    = "/sys/devices/platform/soc/soc:firmware/get_throttled"
+	DefaultVcgencmdPath     = "vcgencmd"
+
+	// DefaultDoctorTimeout bounds each exporter endpoint check of `rpi-metrics doctor`.
+	DefaultDoctorTimeout = 10 * time.Second
+)
+
+const (
+	DefaultConfigPath  = ""
+	DefaultAdminListen = ""
+)
+
+const (
+	DefaultWebhookConfig  = ""
+	DefaultNotifyAttempts = 3
+	DefaultNotifyBackoff  = 2 * time.Second
+)
+
+const (
+	DefaultTelegramChatIDs  = ""
+	DefaultTelegramEvery    = time.Duration(0)
+	DefaultTelegramCommands = false
+	DefaultTelegramAPIURL   = "https://api.telegram.org"
+)
+
+const (
+	DefaultSMTPAddr      = ""
+	DefaultSMTPTLS       = "starttls"
+	DefaultSMTPUser      = ""
+	DefaultEmailFrom     = ""
+	DefaultEmailTo       = ""
+	DefaultEmailDigestAt = ""
+)
+
+const (
+	DefaultDiscordDigest = ""
+)
+
+const (
+	// Replays post as fast as they read; a post per envelope would be rate limited.
+	DefaultReplayDiscordEvery = 10 * time.Minute
+)
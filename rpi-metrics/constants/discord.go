@@ -4,4 +4,8 @@ const (
 	// DiscordMessageSeparatorLen controls the number of hyphens used as a visible
 	// separator line in Discord messages.
 	DiscordMessageSeparatorLen = 74
+
+	// DiscordMessageMaxLen is Discord's limit, in characters, on the content of a single message;
+	// longer posts are split into several messages.
+	DiscordMessageMaxLen = 2000
 )
@@ -1,21 +1,117 @@
 package constants
 
 const (
-	FlagInterval       = "interval"
-	FlagTempPath       = "temp-path"
-	FlagCoolingPath    = "cooling-path"
-	FlagStoragePaths   = "storage-paths"
-	FlagDiscordWebhook = "discord-webhook"
-	FlagDiscordEvery   = "discord-every"
-	FlagAlsoConsole    = "also-console"
+	FlagInterval              = "interval"
+	FlagTempPath              = "temp-path"
+	FlagCoolingPath           = "cooling-path"
+	FlagStoragePaths          = "storage-paths"
+	FlagStorageDiscover       = "storage-discover"
+	FlagMMCStatePath          = "mmc-state-path"
+	FlagForecastWindow        = "storage-forecast-window"
+	FlagForecastMinR2         = "storage-forecast-min-r2"
+	FlagRates                 = "rates"
+	FlagExec                  = "exec"
+	FlagExecTimeout           = "exec-timeout"
+	FlagExecMaxOutput         = "exec-max-output"
+	FlagExecFormat            = "exec-format"
+	FlagTextfileDir           = "textfile-dir"
+	FlagLabels                = "labels"
+	FlagHostLabels            = "host-labels"
+	FlagRelabelConfig         = "relabel-config"
+	FlagAgentMetrics          = "agent-metrics"
+	FlagHeartbeatURL          = "heartbeat-url"
+	FlagHeartbeatMethod       = "heartbeat-method"
+	FlagHeartbeatEvery        = "heartbeat-every"
+	FlagHeartbeatFailOnErrors = "heartbeat-fail-on-errors"
+	FlagPushURL               = "push-url"
+	FlagPushToken             = "push-token"
+	FlagPushBatch             = "push-batch"
+	FlagPushMaxQueue          = "push-max-queue"
+	FlagIwPath                = "iw-path"
+	FlagPowerSupplyPath       = "power-supply-path"
+	FlagThrottledPath         = "throttled-path"
+	FlagShutdownBelow         = "shutdown-below"
+	FlagShutdownAfter         = "shutdown-after"
+	FlagShutdownGrace         = "shutdown-grace"
+	FlagShutdownCommand       = "shutdown-command"
+	FlagShutdownDryRun        = "shutdown-dry-run"
+	FlagConfig                = "config"
+	FlagAdminListen           = "admin-listen"
+	FlagWebhookConfig         = "webhook-config"
+	FlagNotifyAttempts        = "notify-attempts"
+	FlagNotifyBackoff         = "notify-backoff"
+	FlagTelegramToken         = "telegram-token"
+	FlagTelegramChatIDs       = "telegram-chat-ids"
+	FlagTelegramEvery         = "telegram-every"
+	FlagTelegramCommands      = "telegram-commands"
+	FlagTelegramAPIURL        = "telegram-api-url"
+	FlagSMTPAddr              = "smtp-addr"
+	FlagSMTPTLS               = "smtp-tls"
+	FlagSMTPUser              = "smtp-user"
+	FlagSMTPPassword          = "smtp-password"
+	FlagEmailFrom             = "email-from"
+	FlagEmailTo               = "email-to"
+	FlagEmailDigestAt         = "email-digest-at"
+	FlagDiscordWebhook        = "discord-webhook"
+	FlagDiscordDigest         = "discord-digest"
+	FlagDiscordEvery          = "discord-every"
+	FlagAlsoConsole           = "also-console"
 )
 
 const (
-	FlagUsageInterval       = "collection interval (e.g. 2s, 500ms, 1m)"
-	FlagUsageTempPath       = "sysfs path for CPU temperature"
-	FlagUsageCoolingPath    = "sysfs path for cooling device"
-	FlagUsageStoragePaths   = "Comma-separated list of filesystem paths to measure (e.g. /,/boot)"
-	FlagUsageDiscordWebhook = "Discord webhook URL (optional)"
-	FlagUsageDiscordEvery   = "How often to post to Discord (0 disables). e.g. 1m, 10m, 1h"
-	FlagUsageAlsoConsole    = "When Discord is enabled, also print JSON to stdout"
+	FlagUsageInterval              = "collection interval (e.g. 2s, 500ms, 1m)"
+	FlagUsageTempPath              = "sysfs path for CPU temperature"
+	FlagUsageCoolingPath           = "sysfs path for cooling device"
+	FlagUsageStoragePaths          = "Comma-separated list of filesystem paths to measure (e.g. /,/boot)"
+	FlagUsageStorageDiscover       = "Also measure every real mount found in /proc/self/mountinfo (skips tmpfs, proc, sysfs, overlay, ...)"
+	FlagUsageMMCStatePath          = "File that persists SD card write counters across restarts (empty keeps them in memory)"
+	FlagUsageForecastWindow        = "Window of storage history used to predict when each mount is full (0 disables)"
+	FlagUsageForecastMinR2         = "Minimum R² of the storage trend fit before a prediction is emitted (0-1; 0 accepts any growing trend)"
+	FlagUsageRates                 = "Derive a *_per_second rate from every counter metric (e.g. network_carrier_changes_per_second)"
+	FlagUsageExec                  = "Custom metrics command as name=command args..., quoted like in a shell (repeatable; no shell runs it, use a script for pipes)"
+	FlagUsageExecTimeout           = "Timeout for each -exec command run"
+	FlagUsageExecMaxOutput         = "Maximum stdout bytes accepted from an -exec command"
+	FlagUsageExecFormat            = "Output format of -exec commands: auto, json or prometheus"
+	FlagUsageTextfileDir           = "Directory of *.prom / *.json metric files written by other programs (optional)"
+	FlagUsageLabels                = "Static labels added to every sample, e.g. site=lab,role=sensor"
+	FlagUsageHostLabels            = "Detected identity labels added to every sample: any of host,model,serial (empty disables)"
+	FlagUsageRelabelConfig         = "JSON file with relabel rules, global and per exporter (optional)"
+	FlagUsageAgentMetrics          = "Report the agent's own collector/exporter timings, errors, memory and build info"
+	FlagUsageHeartbeatURL          = "Dead-man's-switch URL pinged after each collection (healthchecks.io or rpi-metrics heartbeat-receiver); optional. Accepts env:VAR or file:/path"
+	FlagUsageHeartbeatMethod       = "HTTP method for heartbeat pings: POST (with a status body) or GET"
+	FlagUsageHeartbeatEvery        = "Minimum time between heartbeat pings; a change between success and fail is sent immediately"
+	FlagUsageHeartbeatFailOnErrors = "Send a fail ping when any collector errors, not only when no samples were collected"
+	FlagUsagePushURL               = "rpi-metrics-hub push endpoint, e.g. http://hub:8081/api/push (optional)"
+	FlagUsagePushToken             = "This agent's push token, as listed in the hub's -tokens file. Accepts env:VAR or file:/path"
+	FlagUsagePushBatch             = "Collection cycles per push; larger batches mean fewer, bigger requests"
+	FlagUsagePushMaxQueue          = "Cycles kept while the hub is unreachable (oldest dropped)"
+	FlagUsageIwPath                = "iw command used for the Wi-Fi SSID and bitrate (\"-\" disables)"
+	FlagUsagePowerSupplyPath       = "Path to the power supply class (batteries, UPS HATs, mains/USB inputs)"
+	FlagUsageThrottledPath         = "Path to the firmware's get_throttled flags (under-voltage and throttling on a Raspberry Pi)"
+	FlagUsageShutdownBelow         = "Shut down cleanly when on battery below this capacity percent (0 disables)"
+	FlagUsageShutdownAfter         = "How long the battery must stay below -shutdown-below before the shutdown notice"
+	FlagUsageShutdownGrace         = "Time between the shutdown notice (Discord, log) and running -shutdown-command"
+	FlagUsageShutdownCommand       = "Command that shuts the Pi down, split into words like a shell would (quotes and backslashes, no expansion)"
+	FlagUsageShutdownDryRun        = "Only log and notify instead of running -shutdown-command"
+	FlagUsageConfig                = "File of agent flags, one name=value per line (# comments); flags on the command line win. Re-read on SIGHUP"
+	FlagUsageAdminListen           = "Address for the admin API (POST /-/reload), e.g. 127.0.0.1:9101 (optional; not changed by reloads)"
+	FlagUsageWebhookConfig         = "JSON file of templated webhooks (Slack, Teams, ntfy, ...); optional"
+	FlagUsageNotifyAttempts        = "Tries per Discord or webhook post; network errors, 429 and 5xx responses are retried"
+	FlagUsageNotifyBackoff         = "Wait before the first retry of a Discord or webhook post; doubles with each retry"
+	FlagUsageTelegramToken         = "Telegram bot token from @BotFather (optional). Accepts env:VAR or file:/path"
+	FlagUsageTelegramChatIDs       = "Comma separated Telegram chat IDs that get snapshots, alerts and notices and may use bot commands"
+	FlagUsageTelegramEvery         = "How often to send a snapshot to Telegram (0: alerts and notices only)"
+	FlagUsageTelegramCommands      = "Answer /status, /temp, /disk and /top from the allowed chats (long-polls the Bot API)"
+	FlagUsageTelegramAPIURL        = "Telegram Bot API base URL"
+	FlagUsageSMTPAddr              = "SMTP server host:port for alert and digest emails (optional), e.g. smtp.example.com:587"
+	FlagUsageSMTPTLS               = "SMTP connection security: starttls (port 587), tls (implicit TLS, port 465) or none (local relay only)"
+	FlagUsageSMTPUser              = "SMTP username (optional)"
+	FlagUsageSMTPPassword          = "SMTP password. Accepts env:VAR or file:/path"
+	FlagUsageEmailFrom             = "Sender address of alert and digest emails"
+	FlagUsageEmailTo               = "Comma separated recipient addresses of alert and digest emails"
+	FlagUsageEmailDigestAt         = "When to email a digest of the elapsed window: a local time of day (HH:MM), a schedule like \"every monday 08:00\" or a cron expression (empty: no digest)"
+	FlagUsageDiscordWebhook        = "Discord webhook URL (optional). Accepts env:VAR or file:/path"
+	FlagUsageDiscordDigest         = "Post a digest of the elapsed window on this schedule instead of snapshots, e.g. \"every day 08:00\", \"every 6h\" or a cron expression like \"0 8 * * 1-5\"; takes precedence over -discord-every"
+	FlagUsageDiscordEvery          = "How often to post to Discord (0 disables). e.g. 1m, 10m, 1h"
+	FlagUsageAlsoConsole           = "When Discord is enabled, also print JSON to stdout"
 )
@@ -0,0 +1,309 @@
+// Package check evaluates Nagios-style thresholds against a collected Result, for
+// `rpi-metrics check` run by Nagios or Icinga.
+package check
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"rpi-metrics/internal/metrics"
+)
+
+// State is a plugin state; its value is the plugin's exit code.
+type State int
+
+const (
+	OK       State = 0
+	Warning  State = 1
+	Critical State = 2
+	Unknown  State = 3
+)
+
+func (s State) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Warning:
+		return "WARNING"
+	case Critical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// stateRank orders states by severity: Unknown ranks between Warning and Critical, so
+// a real problem isn't hidden behind a missing metric.
+var stateRank = map[State]int{OK: 0, Warning: 1, Unknown: 2, Critical: 3}
+
+func worse(a, b State) bool { return stateRank[a] > stateRank[b] }
+
+// Range is a threshold in Nagios range syntax:
+//
+//	10      alert outside 0..10
+//	10:     alert below 10
+//	~:10    alert above 10
+//	10:20   alert outside 10..20
+//	@10:20  alert inside 10..20
+type Range struct {
+	Raw    string
+	Start  float64
+	End    float64
+	Inside bool
+}
+
+// ParseRange parses a threshold in Nagios range syntax.
+func ParseRange(s string) (Range, error) {
+	r := Range{Raw: s, Start: 0, End: math.Inf(1)}
+	rest := s
+	if strings.HasPrefix(rest, "@") {
+		r.Inside = true
+		rest = rest[1:]
+	}
+	start, end, hasColon := strings.Cut(rest, ":")
+	if !hasColon {
+		start, end = "", rest
+	}
+	var err error
+	switch start {
+	case "":
+	case "~":
+		r.Start = math.Inf(-1)
+	default:
+		if r.Start, err = strconv.ParseFloat(start, 64); err != nil {
+			return Range{}, fmt.Errorf("invalid threshold %q: %w", s, err)
+		}
+	}
+	if end != "" {
+		if r.End, err = strconv.ParseFloat(end, 64); err != nil {
+			return Range{}, fmt.Errorf("invalid threshold %q: %w", s, err)
+		}
+	} else if !hasColon {
+		return Range{}, fmt.Errorf("invalid threshold %q: empty", s)
+	}
+	if r.Start > r.End {
+		return Range{}, fmt.Errorf("invalid threshold %q: start is greater than end", s)
+	}
+	return r, nil
+}
+
+// Alert reports whether v triggers the threshold.
+func (r Range) Alert(v float64) bool {
+	inside := v >= r.Start && v <= r.End
+	return inside == r.Inside
+}
+
+// Selector picks samples by metric name and label matchers:
+//
+//	storage_used_percent{mount_point="/",fs_type!="tmpfs"}
+type Selector struct {
+	Raw      string
+	Name     string
+	matchers []labelMatcher
+}
+
+type labelMatcher struct {
+	name, value string
+	negate      bool
+}
+
+// ParseSelector parses a metric name with optional {label="value"} matchers. Values
+// may be unquoted; != negates a matcher.
+func ParseSelector(s string) (Selector, error) {
+	sel := Selector{Raw: s}
+	name, rest, hasLabels := strings.Cut(s, "{")
+	sel.Name = strings.TrimSpace(name)
+	if sel.Name == "" {
+		return Selector{}, fmt.Errorf("invalid selector %q: empty metric name", s)
+	}
+	if !hasLabels {
+		return sel, nil
+	}
+	body, ok := strings.CutSuffix(strings.TrimSpace(rest), "}")
+	if !ok {
+		return Selector{}, fmt.Errorf("invalid selector %q: missing }", s)
+	}
+	for _, part := range strings.Split(body, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		m := labelMatcher{}
+		k, v, ok := strings.Cut(part, "!=")
+		if ok {
+			m.negate = true
+		} else if k, v, ok = strings.Cut(part, "="); !ok {
+			return Selector{}, fmt.Errorf("invalid selector %q: matcher %q: want label=\"value\"", s, part)
+		}
+		m.name = strings.TrimSpace(k)
+		m.value = strings.Trim(strings.TrimSpace(v), `"`)
+		sel.matchers = append(sel.matchers, m)
+	}
+	return sel, nil
+}
+
+// Match reports whether a sample is selected.
+func (sel Selector) Match(s metrics.Sample) bool {
+	if s.Name != sel.Name {
+		return false
+	}
+	for _, m := range sel.matchers {
+		if (s.Labels[m.name] == m.value) == m.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// Rule is a warning and/or critical threshold for the samples of a selector.
+type Rule struct {
+	Selector Selector
+	Warn     *Range
+	Crit     *Range
+}
+
+// ParseRuleSpec splits a -w/-c argument, "selector=threshold", at the = after the
+// selector's closing brace.
+func ParseRuleSpec(spec string) (Selector, Range, error) {
+	i := strings.LastIndex(spec, "}") + 1
+	eq := strings.Index(spec[i:], "=")
+	if eq < 0 {
+		return Selector{}, Range{}, fmt.Errorf("invalid threshold %q: want metric{labels}=range", spec)
+	}
+	sel, err := ParseSelector(spec[:i+eq])
+	if err != nil {
+		return Selector{}, Range{}, err
+	}
+	r, err := ParseRange(strings.TrimSpace(spec[i+eq+1:]))
+	if err != nil {
+		return Selector{}, Range{}, err
+	}
+	return sel, r, nil
+}
+
+// Result is one evaluated sample.
+type Result struct {
+	Label  string // perfdata label
+	Sample metrics.Sample
+	Rule   *Rule
+	State  State
+}
+
+// Outcome is the overall result of a check.
+type Outcome struct {
+	State   State
+	Results []Result
+	Missing []*Rule // rules that matched no sample (UNKNOWN)
+}
+
+// Evaluate applies rules to the samples of res. A rule without matching samples
+// makes the check UNKNOWN: a threshold that silently checks nothing is worse than
+// no check.
+func Evaluate(res metrics.Result, rules []*Rule) Outcome {
+	var out Outcome
+	for _, rule := range rules {
+		var matched []metrics.Sample
+		for _, s := range res.Samples {
+			if rule.Selector.Match(s) {
+				matched = append(matched, s)
+			}
+		}
+		if len(matched) == 0 {
+			out.Missing = append(out.Missing, rule)
+			if worse(Unknown, out.State) {
+				out.State = Unknown
+			}
+			continue
+		}
+
+		distinct := distinctLabels(matched)
+		for _, s := range matched {
+			r := Result{Label: perfLabel(s, distinct), Sample: s, Rule: rule, State: OK}
+			switch {
+			case rule.Crit != nil && rule.Crit.Alert(s.Value):
+				r.State = Critical
+			case rule.Warn != nil && rule.Warn.Alert(s.Value):
+				r.State = Warning
+			}
+			if worse(r.State, out.State) {
+				out.State = r.State
+			}
+			out.Results = append(out.Results, r)
+		}
+	}
+	return out
+}
+
+// distinctLabels returns the label names whose values differ between samples, which
+// are the ones that tell series apart in perfdata labels.
+func distinctLabels(samples []metrics.Sample) []string {
+	seen := map[string]map[string]bool{}
+	for _, s := range samples {
+		for k, v := range s.Labels {
+			if seen[k] == nil {
+				seen[k] = map[string]bool{}
+			}
+			seen[k][v] = true
+		}
+	}
+	var out []string
+	for k, values := range seen {
+		if len(values) > 1 || !allHave(samples, k) {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func allHave(samples []metrics.Sample, label string) bool {
+	for _, s := range samples {
+		if _, ok := s.Labels[label]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// perfLabel is the metric name, followed by the distinguishing label values, e.g.
+// storage_used_percent[/boot]. Quotes and = aren't allowed in perfdata labels.
+func perfLabel(s metrics.Sample, distinct []string) string {
+	if len(distinct) == 0 {
+		return s.Name
+	}
+	values := make([]string, 0, len(distinct))
+	for _, k := range distinct {
+		values = append(values, s.Labels[k])
+	}
+	label := s.Name + "[" + strings.Join(values, ",") + "]"
+	return strings.NewReplacer("'", "", "=", "_").Replace(label)
+}
+
+// Perfdata renders a result as 'label'=value[UOM];warn;crit.
+func (r Result) Perfdata() string {
+	warn, crit := "", ""
+	if r.Rule.Warn != nil {
+		warn = r.Rule.Warn.Raw
+	}
+	if r.Rule.Crit != nil {
+		crit = r.Rule.Crit.Raw
+	}
+	return fmt.Sprintf("'%s'=%s%s;%s;%s", r.Label, strconv.FormatFloat(math.Round(r.Sample.Value*1000)/1000, 'f', -1, 64), perfUnit(r.Sample.Unit), warn, crit)
+}
+
+// perfUnit maps canonical units to the units of the plugin guidelines.
+func perfUnit(unit string) string {
+	switch unit {
+	case metrics.UnitPercent:
+		return "%"
+	case metrics.UnitBytes:
+		return "B"
+	case metrics.UnitSeconds:
+		return "s"
+	default:
+		return ""
+	}
+}
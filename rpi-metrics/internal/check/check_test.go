@@ -0,0 +1,183 @@
+package check
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"rpi-metrics/internal/metrics"
+)
+
+func TestParseRange(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		spec       string
+		start, end float64
+		inside     bool
+		alert      []float64 // values that trigger the threshold
+		quiet      []float64 // values that don't
+	}{
+		{"10", 0, 10, false, []float64{-1, 10.5}, []float64{0, 5, 10}},
+		{"10:", 10, inf, false, []float64{9.9, -3}, []float64{10, 1e9}},
+		{"~:10", math.Inf(-1), 10, false, []float64{10.1}, []float64{-1e9, 10}},
+		{"~:", math.Inf(-1), inf, false, nil, []float64{-1e9, 0, 1e9}},
+		{"10:20", 10, 20, false, []float64{9, 21}, []float64{10, 15, 20}},
+		{"-5:5", -5, 5, false, []float64{-6, 6}, []float64{-5, 0, 5}},
+		{"@10:20", 10, 20, true, []float64{10, 15, 20}, []float64{9, 21}},
+		{"@10", 0, 10, true, []float64{0, 10}, []float64{-1, 11}},
+		{"@~:0", math.Inf(-1), 0, true, []float64{-1, 0}, []float64{0.1}},
+		{"@5:", 5, inf, true, []float64{5, 1e9}, []float64{4}},
+		{"1e3:2.5e3", 1000, 2500, false, []float64{999, 2501}, []float64{1000, 2500}},
+	}
+	for _, tt := range tests {
+		r, err := ParseRange(tt.spec)
+		if err != nil {
+			t.Errorf("ParseRange(%q): %v", tt.spec, err)
+			continue
+		}
+		if r.Start != tt.start || r.End != tt.end || r.Inside != tt.inside || r.Raw != tt.spec {
+			t.Errorf("ParseRange(%q) = %+v, want %v..%v inside=%v", tt.spec, r, tt.start, tt.end, tt.inside)
+		}
+		for _, v := range tt.alert {
+			if !r.Alert(v) {
+				t.Errorf("%q: %v does not alert", tt.spec, v)
+			}
+		}
+		for _, v := range tt.quiet {
+			if r.Alert(v) {
+				t.Errorf("%q: %v alerts", tt.spec, v)
+			}
+		}
+	}
+}
+
+func TestParseRangeErrors(t *testing.T) {
+	for _, spec := range []string{"", "@", "~", "abc", "10:abc", "x:10", "20:10", "-10", "@:~"} {
+		if r, err := ParseRange(spec); err == nil {
+			t.Errorf("ParseRange(%q) = %+v, want an error", spec, r)
+		}
+	}
+}
+
+func TestParseRuleSpec(t *testing.T) {
+	tests := []struct {
+		spec, name, rng string
+		match           metrics.Sample
+	}{
+		{"cpu_temp_celsius=70", "cpu_temp_celsius", "70", metrics.Sample{Name: "cpu_temp_celsius"}},
+		{`storage_used_percent{mount_point="/",fs_type!="tmpfs"}=~:90`, "storage_used_percent", "~:90",
+			metrics.Sample{Name: "storage_used_percent", Labels: map[string]string{"mount_point": "/", "fs_type": "ext4"}}},
+		{`storage_used_percent{mount_point=/boot} = @0:5`, "storage_used_percent", "@0:5",
+			metrics.Sample{Name: "storage_used_percent", Labels: map[string]string{"mount_point": "/boot"}}},
+		{`net_up{label="a=b"}=1:`, "net_up", "1:",
+			metrics.Sample{Name: "net_up", Labels: map[string]string{"label": "a=b"}}},
+	}
+	for _, tt := range tests {
+		sel, r, err := ParseRuleSpec(tt.spec)
+		if err != nil {
+			t.Errorf("ParseRuleSpec(%q): %v", tt.spec, err)
+			continue
+		}
+		if sel.Name != tt.name || r.Raw != tt.rng || !sel.Match(tt.match) {
+			t.Errorf("ParseRuleSpec(%q) = %q, %q; matches %v: %v", tt.spec, sel.Name, r.Raw, tt.match, sel.Match(tt.match))
+		}
+	}
+	for _, spec := range []string{"cpu_temp_celsius", "{a=b}=1", `m{a="b"=1`, "m=", "m=@"} {
+		if _, _, err := ParseRuleSpec(spec); err == nil {
+			t.Errorf("ParseRuleSpec(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	res := metrics.Result{Samples: []metrics.Sample{
+		{Name: "cpu_temp_celsius", Value: 65},
+		{Name: "storage_used_percent", Value: 50, Unit: metrics.UnitPercent, Labels: map[string]string{"mount_point": "/", "host": "pi"}},
+		{Name: "storage_used_percent", Value: 95, Unit: metrics.UnitPercent, Labels: map[string]string{"mount_point": "/boot", "host": "pi"}},
+	}}
+	rule := func(spec, warn, crit string) *Rule {
+		t.Helper()
+		sel, err := ParseSelector(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &Rule{Selector: sel}
+		for _, th := range []struct {
+			raw string
+			dst **Range
+		}{{warn, &r.Warn}, {crit, &r.Crit}} {
+			if th.raw == "" {
+				continue
+			}
+			rng, err := ParseRange(th.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			*th.dst = &rng
+		}
+		return r
+	}
+	temp := func(warn, crit string) *Rule { return rule("cpu_temp_celsius", warn, crit) }
+	missing := rule("mmc_life_time_used_percent", "80", "90")
+
+	tests := []struct {
+		name    string
+		rules   []*Rule
+		want    State
+		states  []State // per result
+		missing int
+	}{
+		{"ok", []*Rule{temp("70", "80")}, OK, []State{OK}, 0},
+		{"warning", []*Rule{temp("60", "80")}, Warning, []State{Warning}, 0},
+		{"critical beats warning", []*Rule{temp("60", "~:64")}, Critical, []State{Critical}, 0},
+		{"warn only", []*Rule{temp("~:50", "")}, Warning, []State{Warning}, 0},
+		{"crit only", []*Rule{temp("", "@60:70")}, Critical, []State{Critical}, 0},
+		{"no sample is unknown", []*Rule{missing}, Unknown, nil, 1},
+		{"unknown beats warning", []*Rule{temp("60", "80"), missing}, Unknown, []State{Warning}, 1},
+		{"unknown beats warning in any order", []*Rule{missing, temp("60", "80")}, Unknown, []State{Warning}, 1},
+		{"critical beats unknown", []*Rule{missing, temp("60", "64")}, Critical, []State{Critical}, 1},
+		{"worst sample wins", []*Rule{rule("storage_used_percent", "80", "90")}, Critical, []State{OK, Critical}, 0},
+		{"label matchers", []*Rule{rule(`storage_used_percent{mount_point="/"}`, "80", "90")}, OK, []State{OK}, 0},
+		{"negated matcher", []*Rule{rule(`storage_used_percent{mount_point!="/"}`, "80", "90")}, Critical, []State{Critical}, 0},
+	}
+	for _, tt := range tests {
+		out := Evaluate(res, tt.rules)
+		if out.State != tt.want || len(out.Missing) != tt.missing || len(out.Results) != len(tt.states) {
+			t.Errorf("%s: state %s, %d results, %d missing; want %s, %d, %d",
+				tt.name, out.State, len(out.Results), len(out.Missing), tt.want, len(tt.states), tt.missing)
+			continue
+		}
+		for i, r := range out.Results {
+			if r.State != tt.states[i] {
+				t.Errorf("%s: result %d (%s) is %s, want %s", tt.name, i, r.Label, r.State, tt.states[i])
+			}
+		}
+	}
+}
+
+func TestPerfdata(t *testing.T) {
+	res := metrics.Result{Samples: []metrics.Sample{
+		{Name: "storage_used_percent", Value: 50.12345, Unit: metrics.UnitPercent, Labels: map[string]string{"mount_point": "/", "host": "pi"}},
+		{Name: "storage_used_percent", Value: 95, Unit: metrics.UnitPercent, Labels: map[string]string{"mount_point": "/boot", "host": "pi"}},
+		{Name: "cpu_temp_celsius", Value: 48, Unit: metrics.UnitCelsius, Labels: map[string]string{"host": "pi"}},
+	}}
+	warn, _ := ParseRange("80")
+	crit, _ := ParseRange("~:90")
+	out := Evaluate(res, []*Rule{
+		{Selector: Selector{Name: "storage_used_percent"}, Warn: &warn, Crit: &crit},
+		{Selector: Selector{Name: "cpu_temp_celsius"}},
+	})
+
+	var got []string
+	for _, r := range out.Results {
+		got = append(got, r.Perfdata())
+	}
+	want := []string{
+		"'storage_used_percent[/]'=50.123%;80;~:90",
+		"'storage_used_percent[/boot]'=95%;80;~:90",
+		"'cpu_temp_celsius'=48;;",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("perfdata = %q, want %q", got, want)
+	}
+}
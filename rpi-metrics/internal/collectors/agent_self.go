@@ -0,0 +1,165 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+)
+
+// AgentSelf reports on rpi-metrics itself: per-collector and per-exporter activity
+// recorded in Stats, the process's memory, goroutines and GC pauses, and build info.
+// Collector stats lag one cycle behind, since this collector runs inside the cycle.
+type AgentSelf struct {
+	Stats *metrics.AgentStats
+
+	Version string // e.g. set via -ldflags "-X main.version=v1.2.3"
+	Commit  string // defaults to the VCS revision embedded by the Go toolchain
+}
+
+func init() {
+	collectorLabels := []string{"collector"}
+	exporterLabels := []string{"exporter"}
+	metrics.MustRegisterDescriptors(
+		metrics.Descriptor{Name: "agent_collector_duration_seconds", Type: metrics.TypeGauge, Help: "Duration of the collector's last run.", Unit: metrics.UnitSeconds, Labels: collectorLabels},
+		metrics.Descriptor{Name: "agent_collector_runs_total", Type: metrics.TypeCounter, Help: "Collector runs since the agent started.", Unit: metrics.UnitCount, Labels: collectorLabels},
+		metrics.Descriptor{Name: "agent_collector_errors_total", Type: metrics.TypeCounter, Help: "Collector runs that returned an error.", Unit: metrics.UnitCount, Labels: collectorLabels},
+		metrics.Descriptor{Name: "agent_exporter_duration_seconds", Type: metrics.TypeGauge, Help: "Duration of the exporter's last export.", Unit: metrics.UnitSeconds, Labels: exporterLabels},
+		metrics.Descriptor{Name: "agent_exporter_exports_total", Type: metrics.TypeCounter, Help: "Exports since the agent started, by result (success or failure).", Unit: metrics.UnitCount, Labels: []string{"exporter", "result"}},
+		metrics.Descriptor{Name: "agent_exporter_queue_depth", Type: metrics.TypeGauge, Help: "Results buffered by the exporter and not yet sent.", Unit: metrics.UnitCount, Labels: exporterLabels},
+		metrics.Descriptor{Name: "agent_config_loads_total", Type: metrics.TypeCounter, Help: "Config loads (startup and reloads), by result (success or failure).", Unit: metrics.UnitCount, Labels: []string{"result"}},
+		metrics.Descriptor{Name: "agent_config_last_load_successful", Type: metrics.TypeGauge, Help: "1 when the last config load succeeded; 0 when the last reload was rejected and the previous config is still running.", Unit: metrics.UnitBool, Range: &metrics.Range{Min: 0, Max: 1}},
+		metrics.Descriptor{Name: "agent_config_last_load_success_seconds", Type: metrics.TypeGauge, Help: "Time of the last successful config load.", Unit: metrics.UnitTimestamp},
+		metrics.Descriptor{Name: "agent_memory_rss_bytes", Type: metrics.TypeGauge, Help: "Resident set size of the agent process.", Unit: metrics.UnitBytes},
+		metrics.Descriptor{Name: "agent_goroutines", Type: metrics.TypeGauge, Help: "Goroutines in the agent process.", Unit: metrics.UnitCount},
+		metrics.Descriptor{Name: "agent_gc_last_pause_seconds", Type: metrics.TypeGauge, Help: "Stop-the-world pause of the most recent garbage collection.", Unit: metrics.UnitSeconds},
+		metrics.Descriptor{Name: "agent_gc_pause_seconds_total", Type: metrics.TypeCounter, Help: "Cumulative garbage collection pause time.", Unit: metrics.UnitSeconds},
+		metrics.Descriptor{Name: "agent_build_info", Type: metrics.TypeGauge, Help: "Always 1; build details are in the labels.", Labels: []string{"version", "commit", "go_version"}},
+	)
+}
+
+func (c AgentSelf) ID() string { return "agent" }
+
+func (c AgentSelf) Collect(ctx context.Context) ([]metrics.Sample, error) {
+	_ = ctx
+
+	now := time.Now().UTC()
+	var out []metrics.Sample
+
+	if c.Stats != nil {
+		cs, es := c.Stats.Snapshot()
+		for _, s := range cs {
+			l := map[string]string{"collector": s.ID}
+			out = append(out,
+				metrics.Sample{Name: "agent_collector_duration_seconds", Value: s.LastDuration.Seconds(), Unit: metrics.UnitSeconds, Timestamp: now, Labels: l},
+				metrics.Sample{Name: "agent_collector_runs_total", Value: float64(s.Runs), Unit: metrics.UnitCount, Timestamp: now, Labels: l},
+				metrics.Sample{Name: "agent_collector_errors_total", Value: float64(s.Errors), Unit: metrics.UnitCount, Timestamp: now, Labels: l},
+			)
+		}
+		for _, s := range es {
+			l := map[string]string{"exporter": s.Name}
+			out = append(out,
+				metrics.Sample{Name: "agent_exporter_duration_seconds", Value: s.LastDuration.Seconds(), Unit: metrics.UnitSeconds, Timestamp: now, Labels: l},
+				metrics.Sample{Name: "agent_exporter_exports_total", Value: float64(s.Successes), Unit: metrics.UnitCount, Timestamp: now, Labels: map[string]string{"exporter": s.Name, "result": "success"}},
+				metrics.Sample{Name: "agent_exporter_exports_total", Value: float64(s.Failures), Unit: metrics.UnitCount, Timestamp: now, Labels: map[string]string{"exporter": s.Name, "result": "failure"}},
+			)
+			if s.QueueDepth >= 0 {
+				out = append(out, metrics.Sample{Name: "agent_exporter_queue_depth", Value: float64(s.QueueDepth), Unit: metrics.UnitCount, Timestamp: now, Labels: l})
+			}
+		}
+		if loads := c.Stats.ConfigLoads(); loads.Successes+loads.Failures > 0 {
+			out = append(out,
+				metrics.Sample{Name: "agent_config_loads_total", Value: float64(loads.Successes), Unit: metrics.UnitCount, Timestamp: now, Labels: map[string]string{"result": "success"}},
+				metrics.Sample{Name: "agent_config_loads_total", Value: float64(loads.Failures), Unit: metrics.UnitCount, Timestamp: now, Labels: map[string]string{"result": "failure"}},
+				metrics.Sample{Name: "agent_config_last_load_successful", Value: boolToFloat(!loads.LastFailed), Unit: metrics.UnitBool, Timestamp: now},
+			)
+			if !loads.LastSuccess.IsZero() {
+				out = append(out, metrics.Sample{Name: "agent_config_last_load_success_seconds", Value: float64(loads.LastSuccess.Unix()), Unit: metrics.UnitTimestamp, Timestamp: now})
+			}
+		}
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	lastPause := 0.0
+	if ms.NumGC > 0 {
+		lastPause = float64(ms.PauseNs[(ms.NumGC+255)%256]) / 1e9
+	}
+	out = append(out,
+		metrics.Sample{Name: "agent_goroutines", Value: float64(runtime.NumGoroutine()), Unit: metrics.UnitCount, Timestamp: now},
+		metrics.Sample{Name: "agent_gc_last_pause_seconds", Value: lastPause, Unit: metrics.UnitSeconds, Timestamp: now},
+		metrics.Sample{Name: "agent_gc_pause_seconds_total", Value: float64(ms.PauseTotalNs) / 1e9, Unit: metrics.UnitSeconds, Timestamp: now},
+		metrics.Sample{Name: "agent_build_info", Value: 1, Timestamp: now, Labels: c.BuildLabels()},
+	)
+
+	rss, err := readSelfRSS("/proc/self/status")
+	if err != nil {
+		return out, err
+	}
+	out = append(out, metrics.Sample{Name: "agent_memory_rss_bytes", Value: float64(rss), Unit: metrics.UnitBytes, Timestamp: now})
+	return out, nil
+}
+
+// BuildLabels returns the version, commit and Go version of this build, falling back
+// to the module build info when Version and Commit weren't set at link time.
+func (c AgentSelf) BuildLabels() map[string]string {
+	version, commit := c.Version, c.Commit
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			if commit == "" && s.Key == "vcs.revision" {
+				commit = s.Value
+			}
+		}
+	}
+	if version == "" {
+		version = "dev"
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	return map[string]string{
+		"version":    version,
+		"commit":     commit,
+		"go_version": runtime.Version(),
+	}
+}
+
+// readSelfRSS returns VmRSS from /proc/self/status in bytes.
+func readSelfRSS(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "VmRSS:") {
+			continue
+		}
+		fields := strings.Fields(line) // "VmRSS:", "12345", "kB"
+		if len(fields) < 2 {
+			break
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse %s VmRSS %q: %w", path, fields[1], err)
+		}
+		return kb * 1024, nil
+	}
+	if err := s.Err(); err != nil {
+		return 0, fmt.Errorf("read %s: %w", path, err)
+	}
+	return 0, fmt.Errorf("read %s: no VmRSS line", path)
+}
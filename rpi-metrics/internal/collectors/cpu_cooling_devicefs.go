@@ -16,6 +16,17 @@ type CPUCoolingDevicefs struct {
 	Path string // default: /sys/class/thermal/cooling_device0/cur_state
 }
 
+func init() {
+	metrics.MustRegisterDescriptors(metrics.Descriptor{
+		Name:   "cooling_state",
+		Type:   metrics.TypeStateSet,
+		Help:   "Current state of the cooling device (fan level on the Pi 5 / fan HATs, 0 = off).",
+		Unit:   metrics.UnitState,
+		Labels: []string{"source", "path"},
+		Range:  &metrics.Range{Min: 0, Max: 4},
+	})
+}
+
 func (c CPUCoolingDevicefs) ID() string { return "cpu_cooling_device" }
 
 func (c CPUCoolingDevicefs) Collect(ctx context.Context) ([]metrics.Sample, error) {
@@ -35,6 +46,9 @@ func (c CPUCoolingDevicefs) Collect(ctx context.Context) ([]metrics.Sample, erro
 	s := strings.TrimSpace(string(b))
 
 	raw, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse cooling device cur_state %q: %w", s, err)
+	}
 
 	cooling_cur_state := float64(raw)
 
@@ -42,7 +56,7 @@ func (c CPUCoolingDevicefs) Collect(ctx context.Context) ([]metrics.Sample, erro
 		{
 			Name:      "cooling_state",
 			Value:     cooling_cur_state,
-			Unit:      "from 0 - 4",
+			Unit:      metrics.UnitState,
 			Timestamp: time.Now().UTC(),
 			Labels: map[string]string{
 				"source": "sysfs",
@@ -16,6 +16,17 @@ type CPUTempSysfs struct {
 	Path string // default: /sys/class/thermal/thermal_zone0/temp
 }
 
+func init() {
+	metrics.MustRegisterDescriptors(metrics.Descriptor{
+		Name:   "cpu_temperature",
+		Type:   metrics.TypeGauge,
+		Help:   "SoC temperature reported by the thermal zone.",
+		Unit:   metrics.UnitCelsius,
+		Labels: []string{"source", "path"},
+		Range:  &metrics.Range{Min: -40, Max: 125},
+	})
+}
+
 func (c CPUTempSysfs) ID() string { return "cpu_temp" }
 
 func (c CPUTempSysfs) Collect(ctx context.Context) ([]metrics.Sample, error) {
@@ -44,7 +55,7 @@ func (c CPUTempSysfs) Collect(ctx context.Context) ([]metrics.Sample, error) {
 		{
 			Name:      "cpu_temperature",
 			Value:     celsius,
-			Unit:      "celsius",
+			Unit:      metrics.UnitCelsius,
 			Timestamp: time.Now().UTC(),
 			Labels: map[string]string{
 				"source": "sysfs",
@@ -7,18 +7,30 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"rpi-metrics/constants"
 	"rpi-metrics/internal/metrics"
 )
 
+// CPUUtilizationProcfs reports the CPU time counters of /proc/stat, overall
+// (cpu=total) and per core, split into busy and idle time. The pipeline's
+// metrics.RateStage and metrics.CPUUtilization turn them into cpu_utilization.
 type CPUUtilizationProcfs struct {
 	Path string // default: /proc/stat
+}
 
-	mu       sync.Mutex
-	last     map[string]cpuCounters
-	haveLast bool
+// /proc/stat counts in USER_HZ ticks, fixed at 100 per second for userspace.
+const procStatUserHZ = 100
+
+func init() {
+	metrics.MustRegisterDescriptors(metrics.Descriptor{
+		Name:   metrics.CPUTimeCounter,
+		Type:   metrics.TypeCounter,
+		Help:   "CPU time since boot, overall (cpu=total) and per core; mode=idle includes iowait.",
+		Unit:   metrics.UnitSeconds,
+		Labels: []string{"source", "path", "cpu", "mode"},
+	})
 }
 
 func (c *CPUUtilizationProcfs) ID() string { return "cpu_utilization" }
@@ -28,7 +40,7 @@ func (c *CPUUtilizationProcfs) Collect(ctx context.Context) ([]metrics.Sample, e
 
 	path := c.Path
 	if path == "" {
-		path = "/proc/stat"
+		path = constants.DefaultProcStatPath
 	}
 
 	counters, err := readProcStatCPUAll(path)
@@ -36,57 +48,30 @@ func (c *CPUUtilizationProcfs) Collect(ctx context.Context) ([]metrics.Sample, e
 		return nil, err
 	}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	now := time.Now().UTC()
-	samples := []metrics.Sample{}
-
-	if !c.haveLast {
-		c.last = counters
-		c.haveLast = true
-		return samples, nil
-	}
-
+	samples := make([]metrics.Sample, 0, len(counters)*2)
 	for cpuID, curr := range counters {
-		prev, ok := c.last[cpuID]
-		if !ok {
-			continue
-		}
-
-		deltaIdle := float64(curr.idle - prev.idle)
-		deltaTotal := float64(curr.total - prev.total)
-		if deltaTotal <= 0 {
-			continue
-		}
-
-		usage := (deltaTotal - deltaIdle) / deltaTotal * 100.0
-		if usage < 0 {
-			usage = 0
-		}
-		if usage > 100 {
-			usage = 100
-		}
-
-		labels := map[string]string{
-			"source": "procfs",
-			"path":   path,
-			"cpu":    cpuID,
-		}
 		if cpuID == "cpu" {
-			labels["cpu"] = "total"
+			cpuID = "total"
+		}
+		for _, m := range []struct {
+			mode    string
+			jiffies uint64
+		}{{"busy", curr.total - curr.idle}, {"idle", curr.idle}} {
+			samples = append(samples, metrics.Sample{
+				Name:      metrics.CPUTimeCounter,
+				Value:     float64(m.jiffies) / procStatUserHZ,
+				Unit:      metrics.UnitSeconds,
+				Timestamp: now,
+				Labels: map[string]string{
+					"source": "procfs",
+					"path":   path,
+					"cpu":    cpuID,
+					"mode":   m.mode,
+				},
+			})
 		}
-
-		samples = append(samples, metrics.Sample{
-			Name:      "cpu_utilization",
-			Value:     usage,
-			Unit:      "percent",
-			Timestamp: now,
-			Labels:    labels,
-		})
 	}
-
-	c.last = counters
 	return samples, nil
 }
 
@@ -0,0 +1,128 @@
+package collectors
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/metrics"
+)
+
+// ExecCollector runs a command on every collection and parses its stdout as metrics
+// (see metrics.ParseSamples for the accepted formats). Every sample gets a
+// "collector" label with Name so custom metrics can be told apart downstream.
+//
+// A run that fails, exits non-zero, times out or prints more than MaxOutputBytes is
+// reported as a collector error including the command's stderr. If the previous run
+// is still going (e.g. a slow script and a short interval) the cycle is skipped with
+// an error instead of starting a second copy.
+type ExecCollector struct {
+	Name    string   // used for ID ("exec:<name>") and the "collector" label
+	Command []string // argv; no shell is involved
+
+	Timeout        time.Duration // default: 10s
+	MaxOutputBytes int           // default: 1 MiB
+	Format         string        // metrics.FormatAuto (default), FormatJSON or FormatPrometheus
+
+	running sync.Mutex
+}
+
+func (c *ExecCollector) ID() string { return "exec:" + c.Name }
+
+func (c *ExecCollector) Collect(ctx context.Context) ([]metrics.Sample, error) {
+	if len(c.Command) == 0 {
+		return nil, fmt.Errorf("exec %s: no command configured", c.Name)
+	}
+	if !c.running.TryLock() {
+		return nil, fmt.Errorf("exec %s: previous run still in progress", c.Name)
+	}
+	defer c.running.Unlock()
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = constants.DefaultExecTimeout
+	}
+	maxOut := c.MaxOutputBytes
+	if maxOut <= 0 {
+		maxOut = constants.DefaultExecMaxOutputBytes
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+	// Run in its own process group so a timeout also kills children of shell scripts.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	stdout := &limitedBuffer{limit: maxOut}
+	stderr := &limitedBuffer{limit: 4 << 10}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("exec %s: timed out after %s%s", c.Name, timeout, stderrSuffix(stderr))
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("exec %s: exit status %d%s", c.Name, exitErr.ExitCode(), stderrSuffix(stderr))
+		}
+		return nil, fmt.Errorf("exec %s: %w%s", c.Name, err, stderrSuffix(stderr))
+	}
+	if stdout.truncated {
+		return nil, fmt.Errorf("exec %s: output exceeds %d bytes", c.Name, maxOut)
+	}
+
+	samples, err := metrics.ParseSamples(stdout.Bytes(), c.Format)
+	if err != nil {
+		return nil, fmt.Errorf("exec %s: %w", c.Name, err)
+	}
+	for i := range samples {
+		l := make(map[string]string, len(samples[i].Labels)+1)
+		for k, v := range samples[i].Labels {
+			l[k] = v
+		}
+		l["collector"] = c.Name
+		samples[i].Labels = l
+	}
+	return samples, nil
+}
+
+// limitedBuffer keeps at most limit bytes and records whether more were written.
+// It never returns an error so the child is not killed by SIGPIPE mid-write.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+func stderrSuffix(stderr *limitedBuffer) string {
+	msg := strings.TrimSpace(stderr.String())
+	if msg == "" {
+		return ""
+	}
+	return ": " + msg
+}
@@ -0,0 +1,85 @@
+package collectors
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"rpi-metrics/constants"
+)
+
+// genericHostnames are image defaults shared by many boards; they say nothing about
+// which Pi sent a sample.
+var genericHostnames = map[string]bool{
+	"":            true,
+	"localhost":   true,
+	"raspberrypi": true,
+}
+
+// DetectHostLabels returns identity labels for this board:
+//   - host:   the hostname, or "<hostname>-<serial suffix>" when the hostname is a
+//     generic image default such as "raspberrypi"
+//   - model:  /proc/device-tree/model (e.g. "Raspberry Pi 4 Model B Rev 1.4")
+//   - serial: /proc/device-tree/serial-number, falling back to "Serial" in /proc/cpuinfo
+//
+// Labels that cannot be detected are omitted.
+func DetectHostLabels() map[string]string {
+	out := make(map[string]string)
+
+	hostname, _ := os.Hostname()
+	model := readDeviceTreeString(constants.DefaultDeviceTreeModelPath)
+	serial := readDeviceTreeString(constants.DefaultDeviceTreeSerialPath)
+	if serial == "" {
+		serial = readCPUInfoSerial(constants.DefaultCPUInfoPath)
+	}
+
+	host := hostname
+	if genericHostnames[hostname] && serial != "" {
+		suffix := strings.TrimLeft(serial, "0")
+		if len(suffix) > 8 {
+			suffix = suffix[len(suffix)-8:]
+		}
+		if hostname == "" {
+			host = suffix
+		} else {
+			host = hostname + "-" + suffix
+		}
+	}
+
+	if host != "" {
+		out["host"] = host
+	}
+	if model != "" {
+		out["model"] = model
+	}
+	if serial != "" {
+		out["serial"] = serial
+	}
+	return out
+}
+
+// Device tree strings are NUL-terminated.
+func readDeviceTreeString(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(b), "\x00"))
+}
+
+func readCPUInfoSerial(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		key, val, ok := strings.Cut(s.Text(), ":")
+		if ok && strings.TrimSpace(key) == "Serial" {
+			return strings.TrimSpace(val)
+		}
+	}
+	return ""
+}
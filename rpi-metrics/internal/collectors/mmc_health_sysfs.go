@@ -0,0 +1,361 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/metrics"
+)
+
+// MMCHealthSysfs reports SD card / eMMC identity and wear indicators from
+// /sys/block/mmcblk*/device and tracks cumulative bytes written from /proc/diskstats.
+//
+// life_time and pre_eol_info are only exposed by eMMC (and a few industrial SD cards);
+// for plain SD cards only identity and write tracking are reported.
+type MMCHealthSysfs struct {
+	SysBlockPath  string // default: /sys/block
+	DiskstatsPath string // default: /proc/diskstats
+	UptimePath    string // default: /proc/uptime
+	StatPath      string // default: /proc/stat, for the boot time
+
+	// StatePath persists write counters across restarts (e.g. /var/lib/rpi-metrics/mmc.json).
+	// Empty keeps them in memory only.
+	StatePath string
+
+	mu          sync.Mutex
+	state       map[string]mmcWriteState
+	loaded      bool
+	bootTime    int64 // btime of /proc/stat; 0 when unknown
+	lastPersist time.Time
+}
+
+// mmcWriteState is the persisted per-device write accounting.
+type mmcWriteState struct {
+	BytesWritten float64   `json:"bytes_written"`
+	LastSectors  uint64    `json:"last_sectors"`
+	Since        time.Time `json:"since"`
+	BootTime     int64     `json:"boot_time,omitempty"` // unix seconds; LastSectors counts from then
+}
+
+const (
+	diskstatsSectorSize = 512
+
+	// Writes per day is noisy right after tracking starts; wait for some history first.
+	mmcMinRateWindow = time.Hour
+	// Persisting on every cycle would itself wear the card.
+	mmcPersistEvery = 10 * time.Minute
+	// btime is derived from the wall clock and can move by a second between reads.
+	mmcBootTimeSlack = 60
+)
+
+func init() {
+	devLabels := []string{"source", "device"}
+	metrics.MustRegisterDescriptors(
+		metrics.Descriptor{Name: "mmc_info", Type: metrics.TypeGauge, Help: "Always 1; card identity is in the labels.", Labels: []string{"source", "device", "name", "manfid", "oemid", "date", "type"}},
+		metrics.Descriptor{Name: "mmc_life_time_used_percent", Type: metrics.TypeGauge, Help: "Device life time estimate (eMMC life_time), upper bound of the 10% step; 110 means exceeded.", Unit: metrics.UnitPercent, Labels: []string{"source", "device", "type"}, Range: &metrics.Range{Min: 10, Max: 110}},
+		metrics.Descriptor{Name: "mmc_pre_eol_info", Type: metrics.TypeStateSet, Help: "Reserved block consumption: 1 normal, 2 warning (80%), 3 urgent (90%).", Unit: metrics.UnitState, Labels: devLabels, Range: &metrics.Range{Min: 1, Max: 3}},
+		metrics.Descriptor{Name: "mmc_bytes_written_total", Type: metrics.TypeCounter, Help: "Bytes written to the card since tracking started, across reboots when state is persisted.", Unit: metrics.UnitBytes, Labels: devLabels},
+		metrics.Descriptor{Name: "mmc_estimated_writes_per_day_bytes", Type: metrics.TypeGauge, Help: "Average bytes written per day since tracking started.", Unit: metrics.UnitBytes, Labels: devLabels},
+	)
+}
+
+func (c *MMCHealthSysfs) ID() string { return "mmc_health" }
+
+func (c *MMCHealthSysfs) Collect(ctx context.Context) ([]metrics.Sample, error) {
+	_ = ctx
+
+	sysBlock := c.SysBlockPath
+	if sysBlock == "" {
+		sysBlock = constants.DefaultSysBlockPath
+	}
+	diskstatsPath := c.DiskstatsPath
+	if diskstatsPath == "" {
+		diskstatsPath = constants.DefaultDiskstatsPath
+	}
+
+	devices, err := filepath.Glob(filepath.Join(sysBlock, "mmcblk*"))
+	if err != nil {
+		return nil, fmt.Errorf("glob mmc devices: %w", err)
+	}
+
+	// Partitions (mmcblk0p1) and boot areas (mmcblk0boot0) are not separate cards.
+	names := make([]string, 0, len(devices))
+	for _, d := range devices {
+		name := filepath.Base(d)
+		if strings.TrimLeft(strings.TrimPrefix(name, "mmcblk"), "0123456789") != "" {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, nil // no SD card / eMMC on this board (e.g. USB/NVMe boot)
+	}
+
+	written, err := readDiskstatsSectorsWritten(diskstatsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	out := make([]metrics.Sample, 0, len(names)*6)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadStateLocked()
+
+	for _, name := range names {
+		devDir := filepath.Join(sysBlock, name, "device")
+		labels := map[string]string{
+			"source": "sysfs",
+			"device": name,
+		}
+		for _, attr := range []string{"name", "manfid", "oemid", "date", "type"} {
+			if v, ok := readSysfsString(filepath.Join(devDir, attr)); ok {
+				labels[attr] = v
+			}
+		}
+
+		out = append(out, metrics.Sample{Name: "mmc_info", Value: 1, Timestamp: now, Labels: labels})
+
+		devLabels := map[string]string{"source": "sysfs", "device": name}
+
+		// life_time: "0x01 0x02" -> estimates for type A and type B memory in 10% steps
+		// (0x01 = 0-10% used ... 0x0A = 90-100%, 0x0B = exceeded).
+		if v, ok := readSysfsString(filepath.Join(devDir, "life_time")); ok {
+			for i, field := range strings.Fields(v) {
+				if i > 1 {
+					break
+				}
+				est, err := strconv.ParseUint(field, 0, 8)
+				if err != nil || est == 0 {
+					continue // 0x00 means "not defined"
+				}
+				l := copyLabels(devLabels)
+				l["type"] = string(rune('A' + i))
+				out = append(out, metrics.Sample{Name: "mmc_life_time_used_percent", Value: float64(est * 10), Unit: metrics.UnitPercent, Timestamp: now, Labels: l})
+			}
+		}
+
+		// pre_eol_info: 0x01 normal, 0x02 warning (80% of reserved blocks used), 0x03 urgent.
+		if v, ok := readSysfsString(filepath.Join(devDir, "pre_eol_info")); ok {
+			if eol, err := strconv.ParseUint(v, 0, 8); err == nil && eol > 0 {
+				out = append(out, metrics.Sample{Name: "mmc_pre_eol_info", Value: float64(eol), Unit: metrics.UnitState, Timestamp: now, Labels: copyLabels(devLabels)})
+			}
+		}
+
+		sectors, ok := written[name]
+		if !ok {
+			continue
+		}
+		st := c.updateWriteStateLocked(name, sectors, now)
+		out = append(out, metrics.Sample{Name: "mmc_bytes_written_total", Value: st.BytesWritten, Unit: metrics.UnitBytes, Timestamp: now, Labels: copyLabels(devLabels)})
+
+		if elapsed := now.Sub(st.Since); elapsed >= mmcMinRateWindow {
+			perDay := st.BytesWritten / elapsed.Hours() * 24
+			out = append(out, metrics.Sample{Name: "mmc_estimated_writes_per_day_bytes", Value: perDay, Unit: metrics.UnitBytes, Timestamp: now, Labels: copyLabels(devLabels)})
+		}
+	}
+
+	if c.StatePath != "" && now.Sub(c.lastPersist) >= mmcPersistEvery {
+		if err := c.persistStateLocked(); err != nil {
+			return out, fmt.Errorf("persist mmc write state: %w", err)
+		}
+		c.lastPersist = now
+	}
+
+	return out, nil
+}
+
+// updateWriteStateLocked folds the current diskstats counter into the cumulative total.
+// The kernel counter restarts at 0 on reboot; loadStateLocked zeroes LastSectors of a
+// state saved during an earlier boot, and a smaller value than last time means the
+// same when the boot time is unknown. Either way everything since boot is new.
+func (c *MMCHealthSysfs) updateWriteStateLocked(device string, sectors uint64, now time.Time) mmcWriteState {
+	st, ok := c.state[device]
+	switch {
+	case !ok:
+		// First sight of this card: count what was written since boot so the
+		// rate is meaningful immediately instead of after hours of tracking.
+		st = mmcWriteState{BytesWritten: float64(sectors) * diskstatsSectorSize, Since: now}
+		if up, err := readUptime(c.uptimePath()); err == nil {
+			st.Since = now.Add(-up)
+		}
+	case sectors >= st.LastSectors:
+		st.BytesWritten += float64(sectors-st.LastSectors) * diskstatsSectorSize
+	default:
+		st.BytesWritten += float64(sectors) * diskstatsSectorSize
+	}
+	st.LastSectors = sectors
+	if c.bootTime != 0 {
+		st.BootTime = c.bootTime
+	}
+	c.state[device] = st
+	return st
+}
+
+func (c *MMCHealthSysfs) uptimePath() string {
+	if c.UptimePath != "" {
+		return c.UptimePath
+	}
+	return constants.DefaultUptimePath
+}
+
+func (c *MMCHealthSysfs) statPath() string {
+	if c.StatPath != "" {
+		return c.StatPath
+	}
+	return constants.DefaultProcStatPath
+}
+
+func (c *MMCHealthSysfs) loadStateLocked() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	c.state = make(map[string]mmcWriteState)
+	if bt, err := readBootTime(c.statPath()); err == nil {
+		c.bootTime = bt
+	}
+	if c.StatePath == "" {
+		return
+	}
+	b, err := os.ReadFile(c.StatePath)
+	if err != nil {
+		return // first run, or unreadable: start fresh
+	}
+	if err := json.Unmarshal(b, &c.state); err != nil {
+		// Start fresh, but keep the old totals for whoever wants to recover them:
+		// the next persist overwrites the file.
+		keep := c.StatePath + ".corrupt"
+		if werr := os.WriteFile(keep, b, 0o644); werr != nil {
+			log.Printf("mmc_health: discarding corrupt write state %s: %v (saving a copy failed: %v)", c.StatePath, err, werr)
+		} else {
+			log.Printf("mmc_health: discarding corrupt write state %s: %v (copy kept as %s)", c.StatePath, err, keep)
+		}
+		c.state = nil
+	}
+	if c.state == nil {
+		c.state = make(map[string]mmcWriteState)
+	}
+
+	// The counters of a state saved during an earlier boot restarted at 0 since.
+	for dev, st := range c.state {
+		if c.bootTime != 0 && st.BootTime != 0 && absInt64(c.bootTime-st.BootTime) > mmcBootTimeSlack {
+			st.LastSectors = 0
+			c.state[dev] = st
+		}
+	}
+}
+
+func (c *MMCHealthSysfs) persistStateLocked() error {
+	b, err := json.Marshal(c.state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.StatePath), 0o755); err != nil {
+		return err
+	}
+	tmp := c.StatePath + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.StatePath)
+}
+
+// readDiskstatsSectorsWritten returns the "sectors written" counter per device name.
+func readDiskstatsSectorsWritten(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	out := make(map[string]uint64)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// major minor name reads merged sectors_read ms writes merged sectors_written ...
+		fields := strings.Fields(s.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s sectors written %q: %w", path, fields[9], err)
+		}
+		out[fields[2]] = v
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return out, nil
+}
+
+// readBootTime returns the btime line of /proc/stat: the boot time in unix seconds.
+func readBootTime(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if v, ok := strings.CutPrefix(s.Text(), "btime "); ok {
+			return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no btime in %s", path)
+}
+
+func absInt64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func readUptime(path string) (time.Duration, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty %s", path)
+	}
+	secs, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+func readSysfsString(path string) (string, bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	v := strings.TrimSpace(string(b))
+	return v, v != ""
+}
+
+func copyLabels(in map[string]string) map[string]string {
+	out := make(map[string]string, len(in)+1)
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMMCWriteStateAcrossBoots restarts the collector from its state file the way
+// an agent restart or a reboot would, and checks the bytes written total.
+func TestMMCWriteStateAcrossBoots(t *testing.T) {
+	dir := t.TempDir()
+	sysBlock := filepath.Join(dir, "block")
+	writeFile(t, filepath.Join(sysBlock, "mmcblk0", "device", "name"), "SD32G")
+	diskstats := filepath.Join(dir, "diskstats")
+	stat := filepath.Join(dir, "stat")
+	uptime := filepath.Join(dir, "uptime")
+	writeFile(t, uptime, "100.00 50.00\n")
+	state := filepath.Join(dir, "mmc.json")
+
+	collect := func(btime int64, sectors uint64) float64 {
+		t.Helper()
+		writeFile(t, stat, fmt.Sprintf("cpu  1 2 3 4\nbtime %d\nprocesses 10\n", btime))
+		writeFile(t, diskstats, fmt.Sprintf("179 0 mmcblk0 1 0 2 3 4 0 %d 5 0 6 7\n", sectors))
+		c := &MMCHealthSysfs{SysBlockPath: sysBlock, DiskstatsPath: diskstats, UptimePath: uptime, StatPath: stat, StatePath: state}
+		samples, err := c.Collect(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range samples {
+			if s.Name == "mmc_bytes_written_total" {
+				return s.Value
+			}
+		}
+		t.Fatal("no mmc_bytes_written_total")
+		return 0
+	}
+
+	steps := []struct {
+		name    string
+		btime   int64
+		sectors uint64
+		want    uint64 // sectors written in total
+	}{
+		{"first run counts since boot", 1_000_000, 100, 100},
+		{"agent restart in the same boot", 1_000_000, 150, 150},
+		{"btime jitter is the same boot", 1_000_001, 180, 180},
+		// Fewer sectors would tell, but a busy boot can write more than the last one did.
+		{"reboot with a larger counter", 1_090_000, 500, 680},
+		{"reboot with a smaller counter", 1_200_000, 20, 700},
+	}
+	for _, s := range steps {
+		if got := collect(s.btime, s.sectors); got != float64(s.want*diskstatsSectorSize) {
+			t.Errorf("%s: bytes written %v, want %v", s.name, got, s.want*diskstatsSectorSize)
+		}
+	}
+}
+
+func TestMMCCorruptState(t *testing.T) {
+	dir := t.TempDir()
+	sysBlock := filepath.Join(dir, "block")
+	writeFile(t, filepath.Join(sysBlock, "mmcblk0", "device", "name"), "SD32G")
+	writeFile(t, filepath.Join(dir, "diskstats"), "179 0 mmcblk0 1 0 2 3 4 0 10 5 0 6 7\n")
+	state := filepath.Join(dir, "mmc.json")
+	writeFile(t, state, `{"mmcblk0":{"bytes_written":`)
+
+	c := &MMCHealthSysfs{
+		SysBlockPath:  sysBlock,
+		DiskstatsPath: filepath.Join(dir, "diskstats"),
+		UptimePath:    filepath.Join(dir, "missing"),
+		StatPath:      filepath.Join(dir, "missing"),
+		StatePath:     state,
+	}
+	if _, err := c.Collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(state + ".corrupt"); err != nil || string(b) != `{"mmcblk0":{"bytes_written":` {
+		t.Errorf("corrupt copy = %q, %v", b, err)
+	}
+	if st := c.state["mmcblk0"]; st.BytesWritten != 10*diskstatsSectorSize {
+		t.Errorf("state after a corrupt file = %+v, want a fresh start", st)
+	}
+}
@@ -0,0 +1,338 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/metrics"
+)
+
+// NetworkSysfs reports link state for every network interface from /sys/class/net and
+// Wi-Fi signal and link quality from /proc/net/wireless. When `iw` is installed, the
+// SSID, access point and bitrates of connected Wi-Fi interfaces are added.
+type NetworkSysfs struct {
+	SysClassNetPath string // default: /sys/class/net
+	WirelessPath    string // default: /proc/net/wireless
+	IwPath          string // default: iw (looked up in PATH); "-" disables
+}
+
+var networkLabels = []string{"interface", "source"}
+
+func init() {
+	metrics.MustRegisterDescriptors(
+		metrics.Descriptor{
+			Name:   "network_up",
+			Type:   metrics.TypeGauge,
+			Help:   "1 when the interface's operstate is up.",
+			Unit:   metrics.UnitBool,
+			Labels: networkLabels,
+			Range:  &metrics.Range{Min: 0, Max: 1},
+		},
+		metrics.Descriptor{
+			Name:   "network_carrier_changes_total",
+			Type:   metrics.TypeCounter,
+			Help:   "Number of times the link went up or down since the interface appeared; a climbing count means dropouts.",
+			Unit:   metrics.UnitCount,
+			Labels: networkLabels,
+		},
+		metrics.Descriptor{
+			Name:   "network_speed_bits_per_second",
+			Type:   metrics.TypeGauge,
+			Help:   "Negotiated link speed of wired interfaces.",
+			Unit:   metrics.UnitBitsPerSecond,
+			Labels: networkLabels,
+		},
+		metrics.Descriptor{
+			Name:   "wifi_link_quality",
+			Type:   metrics.TypeGauge,
+			Help:   "Link quality as reported by the driver (usually out of 70).",
+			Unit:   metrics.UnitNone,
+			Labels: networkLabels,
+		},
+		metrics.Descriptor{
+			Name:   "wifi_signal_dbm",
+			Type:   metrics.TypeGauge,
+			Help:   "Received signal level; above -67 dBm is good, below -80 dBm drops out.",
+			Unit:   metrics.UnitDBm,
+			Labels: networkLabels,
+		},
+		metrics.Descriptor{
+			Name:   "wifi_noise_dbm",
+			Type:   metrics.TypeGauge,
+			Help:   "Background noise level, when the driver reports it.",
+			Unit:   metrics.UnitDBm,
+			Labels: networkLabels,
+		},
+		metrics.Descriptor{
+			Name:   "wifi_discarded_packets_total",
+			Type:   metrics.TypeCounter,
+			Help:   "Received packets discarded, by reason (nwid, crypt, frag, retry, misc).",
+			Unit:   metrics.UnitCount,
+			Labels: []string{"interface", "reason", "source"},
+		},
+		metrics.Descriptor{
+			Name:   "wifi_missed_beacons_total",
+			Type:   metrics.TypeCounter,
+			Help:   "Beacons missed from the access point.",
+			Unit:   metrics.UnitCount,
+			Labels: networkLabels,
+		},
+		metrics.Descriptor{
+			Name:   "wifi_info",
+			Type:   metrics.TypeGauge,
+			Help:   "Always 1; labels identify the network and access point the interface is connected to.",
+			Unit:   metrics.UnitNone,
+			Labels: []string{"interface", "ssid", "bssid", "frequency_mhz", "source"},
+			Range:  &metrics.Range{Min: 1, Max: 1},
+		},
+		metrics.Descriptor{
+			Name:   "wifi_bitrate_bits_per_second",
+			Type:   metrics.TypeGauge,
+			Help:   "Current Wi-Fi bitrate, per direction (rx, tx).",
+			Unit:   metrics.UnitBitsPerSecond,
+			Labels: []string{"interface", "direction", "source"},
+		},
+	)
+}
+
+// iw is quick; don't let a wedged driver hold up the collection cycle.
+const iwTimeout = 2 * time.Second
+
+func (c NetworkSysfs) ID() string { return "network" }
+
+func (c NetworkSysfs) Collect(ctx context.Context) ([]metrics.Sample, error) {
+	netPath := c.SysClassNetPath
+	if netPath == "" {
+		netPath = constants.DefaultSysClassNetPath
+	}
+	wirelessPath := c.WirelessPath
+	if wirelessPath == "" {
+		wirelessPath = constants.DefaultWirelessPath
+	}
+
+	now := time.Now().UTC()
+	var out []metrics.Sample
+	add := func(name string, v float64, unit string, labels map[string]string) {
+		out = append(out, metrics.Sample{Name: name, Value: v, Unit: unit, Timestamp: now, Labels: labels})
+	}
+
+	entries, err := os.ReadDir(netPath)
+	if err != nil {
+		return nil, fmt.Errorf("read network interfaces: %w", err)
+	}
+	for _, e := range entries {
+		iface := e.Name()
+		if iface == "lo" {
+			continue
+		}
+		dir := filepath.Join(netPath, iface)
+		labels := map[string]string{"interface": iface, "source": "sysfs"}
+
+		if state, ok := readSysfsString(filepath.Join(dir, "operstate")); ok {
+			add("network_up", boolToFloat(state == "up"), metrics.UnitBool, labels)
+		}
+		if v, ok := readSysfsFloat(filepath.Join(dir, "carrier_changes")); ok {
+			add("network_carrier_changes_total", v, metrics.UnitCount, labels)
+		}
+		// Reading speed fails with EINVAL while the link is down; Wi-Fi reports -1.
+		if v, ok := readSysfsFloat(filepath.Join(dir, "speed")); ok && v > 0 {
+			add("network_speed_bits_per_second", v*1e6, metrics.UnitBitsPerSecond, labels) // Mbit/s
+		}
+	}
+
+	wireless, err := readProcNetWireless(wirelessPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return out, err
+	}
+	// iw is optional: a failing `iw dev X link` is reported alongside the samples of
+	// every interface, not instead of them.
+	var iwErrs []error
+	for _, w := range wireless {
+		labels := map[string]string{"interface": w.iface, "source": "procfs"}
+		add("wifi_link_quality", w.link, metrics.UnitNone, labels)
+		add("wifi_signal_dbm", w.level, metrics.UnitDBm, labels)
+		if w.noise > -256 { // -256 means "not reported"
+			add("wifi_noise_dbm", w.noise, metrics.UnitDBm, labels)
+		}
+		for i, reason := range wirelessDiscardReasons {
+			add("wifi_discarded_packets_total", w.discarded[i], metrics.UnitCount,
+				map[string]string{"interface": w.iface, "reason": reason, "source": "procfs"})
+		}
+		add("wifi_missed_beacons_total", w.missedBeacons, metrics.UnitCount, labels)
+
+		link, err := c.iwLink(ctx, w.iface)
+		if err != nil {
+			iwErrs = append(iwErrs, err)
+			continue
+		}
+		if link == nil {
+			continue
+		}
+		add("wifi_info", 1, metrics.UnitNone, map[string]string{
+			"interface":     w.iface,
+			"ssid":          link.ssid,
+			"bssid":         link.bssid,
+			"frequency_mhz": link.freq,
+			"source":        "iw",
+		})
+		if link.rxBitrate > 0 {
+			add("wifi_bitrate_bits_per_second", link.rxBitrate, metrics.UnitBitsPerSecond,
+				map[string]string{"interface": w.iface, "direction": "rx", "source": "iw"})
+		}
+		if link.txBitrate > 0 {
+			add("wifi_bitrate_bits_per_second", link.txBitrate, metrics.UnitBitsPerSecond,
+				map[string]string{"interface": w.iface, "direction": "tx", "source": "iw"})
+		}
+	}
+	return out, errors.Join(iwErrs...)
+}
+
+var wirelessDiscardReasons = []string{"nwid", "crypt", "frag", "retry", "misc"}
+
+type wirelessStats struct {
+	iface         string
+	link          float64
+	level         float64
+	noise         float64
+	discarded     [5]float64 // in wirelessDiscardReasons order
+	missedBeacons float64
+}
+
+// readProcNetWireless parses /proc/net/wireless:
+//
+//	Inter-| sta-|   Quality        |   Discarded packets               | Missed | WE
+//	 face | tus | link level noise |  nwid  crypt   frag  retry   misc | beacon | 22
+//	 wlan0: 0000   70.  -40.  -256        0      0      0      0     12        0
+func readProcNetWireless(path string) ([]wirelessStats, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var out []wirelessStats
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		iface, rest, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 10 {
+			continue // header lines
+		}
+		nums := make([]float64, 9)
+		for i := range nums {
+			// Values are followed by "." when updated since the last read.
+			v, err := strconv.ParseFloat(strings.TrimSuffix(fields[i+1], "."), 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: interface %s: %w", path, strings.TrimSpace(iface), err)
+			}
+			nums[i] = v
+		}
+		w := wirelessStats{
+			iface:         strings.TrimSpace(iface),
+			link:          nums[0],
+			level:         nums[1],
+			noise:         nums[2],
+			missedBeacons: nums[8],
+		}
+		copy(w.discarded[:], nums[3:8])
+		out = append(out, w)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].iface < out[j].iface })
+	return out, sc.Err()
+}
+
+type iwLinkInfo struct {
+	ssid, bssid, freq    string
+	rxBitrate, txBitrate float64 // bits per second
+}
+
+// iwLink runs `iw dev <iface> link`. It returns nil without error when iw isn't
+// installed or the interface isn't connected.
+func (c NetworkSysfs) iwLink(ctx context.Context, iface string) (*iwLinkInfo, error) {
+	iw := c.IwPath
+	if iw == "" {
+		iw = constants.DefaultIwPath
+	}
+	if iw == "-" {
+		return nil, nil
+	}
+	path, err := exec.LookPath(iw)
+	if err != nil {
+		return nil, nil // iw not installed: SSID and bitrate are optional
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, iwTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "dev", iface, "link").Output()
+	if err != nil {
+		return nil, fmt.Errorf("iw dev %s link: %w", iface, err)
+	}
+	return parseIwLink(out), nil
+}
+
+// parseIwLink parses `iw dev <iface> link` output:
+//
+//	Connected to aa:bb:cc:dd:ee:ff (on wlan0)
+//		SSID: garage
+//		freq: 5180
+//		signal: -52 dBm
+//		rx bitrate: 65.0 MBit/s
+//		tx bitrate: 72.2 MBit/s VHT-MCS 7 80MHz short GI VHT-NSS 1
+func parseIwLink(out []byte) *iwLinkInfo {
+	var info iwLinkInfo
+	connected := false
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if rest, ok := strings.CutPrefix(line, "Connected to "); ok {
+			connected = true
+			info.bssid, _, _ = strings.Cut(rest, " ")
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "SSID":
+			info.ssid = value
+		case "freq":
+			info.freq = strings.Fields(value + " ")[0]
+		case "rx bitrate":
+			info.rxBitrate = parseIwBitrate(value)
+		case "tx bitrate":
+			info.txBitrate = parseIwBitrate(value)
+		}
+	}
+	if !connected {
+		return nil // "Not connected."
+	}
+	return &info
+}
+
+func parseIwBitrate(s string) float64 {
+	fields := strings.Fields(s)
+	if len(fields) < 2 || fields[1] != "MBit/s" {
+		return 0
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return v * 1e6
+}
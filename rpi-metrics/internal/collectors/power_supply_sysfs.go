@@ -0,0 +1,158 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/metrics"
+)
+
+// PowerSupplySysfs reports every power supply the kernel exposes under
+// /sys/class/power_supply: mains/USB inputs, batteries and UPS HATs with a driver.
+// Attributes a supply doesn't provide are skipped.
+type PowerSupplySysfs struct {
+	Path string // default: /sys/class/power_supply
+}
+
+var powerSupplyStatuses = map[string]float64{
+	"Unknown":      metrics.PowerStatusUnknown,
+	"Charging":     metrics.PowerStatusCharging,
+	"Discharging":  metrics.PowerStatusDischarging,
+	"Not charging": metrics.PowerStatusNotCharging,
+	"Full":         metrics.PowerStatusFull,
+}
+
+var powerSupplyLabels = []string{"supply", "type", "source"}
+
+func init() {
+	metrics.MustRegisterDescriptors(
+		metrics.Descriptor{
+			Name:   "power_supply_online",
+			Type:   metrics.TypeGauge,
+			Help:   "1 when the supply is connected (mains/USB input present, or the UPS is powered).",
+			Unit:   metrics.UnitBool,
+			Labels: powerSupplyLabels,
+			Range:  &metrics.Range{Min: 0, Max: 1},
+		},
+		metrics.Descriptor{
+			Name:   "power_supply_capacity_percent",
+			Type:   metrics.TypeGauge,
+			Help:   "Remaining battery charge.",
+			Unit:   metrics.UnitPercent,
+			Labels: powerSupplyLabels,
+			Range:  &metrics.Range{Min: 0, Max: 100},
+		},
+		metrics.Descriptor{
+			Name:   "power_supply_status",
+			Type:   metrics.TypeStateSet,
+			Help:   "Charge status: 0 unknown, 1 charging, 2 discharging, 3 not charging, 4 full.",
+			Unit:   metrics.UnitState,
+			Labels: powerSupplyLabels,
+			Range:  &metrics.Range{Min: metrics.PowerStatusUnknown, Max: metrics.PowerStatusFull},
+		},
+		metrics.Descriptor{
+			Name:   "power_supply_voltage_volts",
+			Type:   metrics.TypeGauge,
+			Help:   "Present voltage of the supply.",
+			Unit:   metrics.UnitVolts,
+			Labels: powerSupplyLabels,
+		},
+		metrics.Descriptor{
+			Name:   "power_supply_current_amperes",
+			Type:   metrics.TypeGauge,
+			Help:   "Present current; the sign convention (charging vs discharging) depends on the driver.",
+			Unit:   metrics.UnitAmperes,
+			Labels: powerSupplyLabels,
+		},
+		metrics.Descriptor{
+			Name:   "power_supply_temperature",
+			Type:   metrics.TypeGauge,
+			Help:   "Battery temperature.",
+			Unit:   metrics.UnitCelsius,
+			Labels: powerSupplyLabels,
+		},
+	)
+}
+
+func (c PowerSupplySysfs) ID() string { return "power_supply" }
+
+func (c PowerSupplySysfs) Collect(ctx context.Context) ([]metrics.Sample, error) {
+	_ = ctx
+
+	root := c.Path
+	if root == "" {
+		root = constants.DefaultPowerSupplyPath
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // no power supply class: nothing to report
+		}
+		return nil, fmt.Errorf("read power supplies: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name()) // entries are symlinks into /sys/devices
+	}
+	sort.Strings(names)
+
+	now := time.Now().UTC()
+	var out []metrics.Sample
+	for _, name := range names {
+		dir := filepath.Join(root, name)
+		typ, _ := readSysfsString(filepath.Join(dir, "type"))
+		labels := map[string]string{
+			"supply": name,
+			"type":   typ,
+			"source": "sysfs",
+		}
+		add := func(metric string, v float64, unit string) {
+			out = append(out, metrics.Sample{
+				Name:      metric,
+				Value:     v,
+				Unit:      unit,
+				Timestamp: now,
+				Labels:    labels,
+			})
+		}
+
+		if v, ok := readSysfsFloat(filepath.Join(dir, "online")); ok {
+			add("power_supply_online", v, metrics.UnitBool)
+		}
+		if v, ok := readSysfsFloat(filepath.Join(dir, "capacity")); ok {
+			add("power_supply_capacity_percent", v, metrics.UnitPercent)
+		}
+		if s, ok := readSysfsString(filepath.Join(dir, "status")); ok {
+			add("power_supply_status", powerSupplyStatuses[s], metrics.UnitState)
+		}
+		if v, ok := readSysfsFloat(filepath.Join(dir, "voltage_now")); ok {
+			add("power_supply_voltage_volts", v/1e6, metrics.UnitVolts) // µV
+		}
+		if v, ok := readSysfsFloat(filepath.Join(dir, "current_now")); ok {
+			add("power_supply_current_amperes", v/1e6, metrics.UnitAmperes) // µA
+		}
+		if v, ok := readSysfsFloat(filepath.Join(dir, "temp")); ok {
+			add("power_supply_temperature", v/10, metrics.UnitCelsius) // tenths of °C
+		}
+	}
+	return out, nil
+}
+
+func readSysfsFloat(path string) (float64, bool) {
+	s, ok := readSysfsString(path)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
@@ -17,28 +17,91 @@ type StorageStatfs struct {
 	// Paths are filesystem paths to measure (e.g. "/", "/boot").
 	// Each path is resolved to its containing mount point for labels (best-effort).
 	Paths []string
+
+	// Discover adds every real mount point found in mountinfo to Paths.
+	// Pseudo filesystems (tmpfs, proc, sysfs, overlay, ...) are skipped by type.
+	Discover bool
+}
+
+// statfs f_flags bit for read-only mounts (ST_RDONLY in <sys/statvfs.h>).
+const stRdonly = 0x1
+
+// pseudoFilesystemTypes are skipped in discovery mode: they are either in-memory
+// or kernel interfaces and never reflect SD card / disk usage.
+var pseudoFilesystemTypes = map[string]bool{
+	"autofs":      true,
+	"binfmt_misc": true,
+	"bpf":         true,
+	"cgroup":      true,
+	"cgroup2":     true,
+	"configfs":    true,
+	"debugfs":     true,
+	"devpts":      true,
+	"devtmpfs":    true,
+	"efivarfs":    true,
+	"fusectl":     true,
+	"hugetlbfs":   true,
+	"mqueue":      true,
+	"nsfs":        true,
+	"overlay":     true,
+	"proc":        true,
+	"pstore":      true,
+	"ramfs":       true,
+	"rpc_pipefs":  true,
+	"securityfs":  true,
+	"squashfs":    true, // snap/loop images: always read-only and always 100% used
+	"sysfs":       true,
+	"tmpfs":       true,
+	"tracefs":     true,
+}
+
+// storageLabels are the labels of every storage sample; mount labels are best-effort.
+var storageLabels = []string{"path", "mount_point", "fs_type", "source"}
+
+func init() {
+	percent := &metrics.Range{Min: 0, Max: 100}
+	metrics.MustRegisterDescriptors(
+		metrics.Descriptor{Name: "storage_total_bytes", Type: metrics.TypeGauge, Help: "Filesystem size.", Unit: metrics.UnitBytes, Labels: storageLabels},
+		metrics.Descriptor{Name: "storage_free_bytes", Type: metrics.TypeGauge, Help: "Free space, including blocks reserved for root.", Unit: metrics.UnitBytes, Labels: storageLabels},
+		metrics.Descriptor{Name: "storage_available_bytes", Type: metrics.TypeGauge, Help: "Space available to unprivileged users.", Unit: metrics.UnitBytes, Labels: storageLabels},
+		metrics.Descriptor{Name: "storage_used_bytes", Type: metrics.TypeGauge, Help: "Used space (total - free).", Unit: metrics.UnitBytes, Labels: storageLabels},
+		metrics.Descriptor{Name: "storage_used_percent", Type: metrics.TypeGauge, Help: "Used share of the space available to unprivileged users, like df.", Unit: metrics.UnitPercent, Labels: storageLabels, Range: percent},
+		metrics.Descriptor{Name: "storage_readonly", Type: metrics.TypeGauge, Help: "1 when the filesystem is mounted read-only (e.g. remounted after I/O errors).", Unit: metrics.UnitBool, Labels: storageLabels, Range: &metrics.Range{Min: 0, Max: 1}},
+		metrics.Descriptor{Name: "storage_inodes_total", Type: metrics.TypeGauge, Help: "Inodes on the filesystem.", Unit: metrics.UnitCount, Labels: storageLabels},
+		metrics.Descriptor{Name: "storage_inodes_free", Type: metrics.TypeGauge, Help: "Free inodes.", Unit: metrics.UnitCount, Labels: storageLabels},
+		metrics.Descriptor{Name: "storage_inodes_used_percent", Type: metrics.TypeGauge, Help: "Used share of inodes.", Unit: metrics.UnitPercent, Labels: storageLabels, Range: percent},
+	)
 }
 
 func (c StorageStatfs) ID() string { return "storage_usage" }
 
 type mountInfoEntry struct {
-	mountPoint string
-	fsType     string
-	source     string
+	mountPoint   string
+	fsType       string
+	source       string
+	mountOptions string // per-mount options, e.g. "rw,relatime"
+	superOptions string // per-superblock options, e.g. "ro,errors=remount-ro"
 }
 
 func (c StorageStatfs) Collect(ctx context.Context) ([]metrics.Sample, error) {
 	_ = ctx
 
+	mounts, mountsErr := readMountInfo("/proc/self/mountinfo")
+
 	paths := c.Paths
+	if c.Discover {
+		if mountsErr != nil {
+			return nil, fmt.Errorf("discover mounts: %w", mountsErr)
+		}
+		paths = append(append([]string(nil), paths...), discoverMountPoints(mounts)...)
+	}
 	if len(paths) == 0 {
 		paths = []string{"/"}
 	}
 
-	mounts, _ := readMountInfo("/proc/self/mountinfo")
-
 	now := time.Now().UTC()
-	out := make([]metrics.Sample, 0, len(paths)*5)
+	out := make([]metrics.Sample, 0, len(paths)*9)
+	seen := make(map[string]bool, len(paths))
 	var lastErr error
 
 	for _, p := range paths {
@@ -47,6 +110,10 @@ func (c StorageStatfs) Collect(ctx context.Context) ([]metrics.Sample, error) {
 			continue
 		}
 		p = filepath.Clean(p)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
 
 		var st syscall.Statfs_t
 		if err := syscall.Statfs(p, &st); err != nil {
@@ -69,8 +136,13 @@ func (c StorageStatfs) Collect(ctx context.Context) ([]metrics.Sample, error) {
 			usedPercent = (float64(total-avail) / float64(total)) * 100.0
 		}
 
+		readOnly := st.Flags&stRdonly != 0
+
 		labels := map[string]string{"path": p}
 		if mi, ok := bestMountForPath(mounts, p); ok {
+			if hasMountOption(mi.mountOptions, "ro") || hasMountOption(mi.superOptions, "ro") {
+				readOnly = true
+			}
 			if mi.mountPoint != "" {
 				labels["mount_point"] = mi.mountPoint
 			}
@@ -83,12 +155,28 @@ func (c StorageStatfs) Collect(ctx context.Context) ([]metrics.Sample, error) {
 		}
 
 		out = append(out,
-			metrics.Sample{Name: "storage_total_bytes", Value: float64(total), Unit: "bytes", Timestamp: now, Labels: labels},
-			metrics.Sample{Name: "storage_free_bytes", Value: float64(free), Unit: "bytes", Timestamp: now, Labels: labels},
-			metrics.Sample{Name: "storage_available_bytes", Value: float64(avail), Unit: "bytes", Timestamp: now, Labels: labels},
-			metrics.Sample{Name: "storage_used_bytes", Value: float64(used), Unit: "bytes", Timestamp: now, Labels: labels},
-			metrics.Sample{Name: "storage_used_percent", Value: usedPercent, Unit: "percent", Timestamp: now, Labels: labels},
+			metrics.Sample{Name: "storage_total_bytes", Value: float64(total), Unit: metrics.UnitBytes, Timestamp: now, Labels: labels},
+			metrics.Sample{Name: "storage_free_bytes", Value: float64(free), Unit: metrics.UnitBytes, Timestamp: now, Labels: labels},
+			metrics.Sample{Name: "storage_available_bytes", Value: float64(avail), Unit: metrics.UnitBytes, Timestamp: now, Labels: labels},
+			metrics.Sample{Name: "storage_used_bytes", Value: float64(used), Unit: metrics.UnitBytes, Timestamp: now, Labels: labels},
+			metrics.Sample{Name: "storage_used_percent", Value: usedPercent, Unit: metrics.UnitPercent, Timestamp: now, Labels: labels},
+			metrics.Sample{Name: "storage_readonly", Value: boolToFloat(readOnly), Unit: metrics.UnitBool, Timestamp: now, Labels: labels},
 		)
+
+		// Some filesystems (vfat /boot, btrfs) report no inode counts; skip rather than report 0%.
+		if st.Files > 0 {
+			inodesTotal := uint64(st.Files)
+			inodesFree := uint64(st.Ffree)
+			inodesUsedPercent := 0.0
+			if inodesTotal >= inodesFree {
+				inodesUsedPercent = float64(inodesTotal-inodesFree) / float64(inodesTotal) * 100.0
+			}
+			out = append(out,
+				metrics.Sample{Name: "storage_inodes_total", Value: float64(inodesTotal), Unit: metrics.UnitCount, Timestamp: now, Labels: labels},
+				metrics.Sample{Name: "storage_inodes_free", Value: float64(inodesFree), Unit: metrics.UnitCount, Timestamp: now, Labels: labels},
+				metrics.Sample{Name: "storage_inodes_used_percent", Value: inodesUsedPercent, Unit: metrics.UnitPercent, Timestamp: now, Labels: labels},
+			)
+		}
 	}
 
 	if len(out) == 0 {
@@ -127,7 +215,14 @@ func readMountInfo(path string) ([]mountInfoEntry, error) {
 		fsType := right[0]
 		source := unescapeMountInfoField(right[1])
 
-		out = append(out, mountInfoEntry{mountPoint: mountPoint, fsType: fsType, source: source})
+		entry := mountInfoEntry{mountPoint: mountPoint, fsType: fsType, source: source}
+		if len(left) >= 6 {
+			entry.mountOptions = left[5]
+		}
+		if len(right) >= 3 {
+			entry.superOptions = right[2]
+		}
+		out = append(out, entry)
 	}
 	if err := s.Err(); err != nil {
 		return nil, err
@@ -158,6 +253,40 @@ func bestMountForPath(mounts []mountInfoEntry, path string) (mountInfoEntry, boo
 	return mountInfoEntry{}, false
 }
 
+// discoverMountPoints returns the mount points of real filesystems, in mountinfo order.
+// A mount point listed more than once (over-mounted) is returned once.
+func discoverMountPoints(mounts []mountInfoEntry) []string {
+	out := make([]string, 0, len(mounts))
+	seen := make(map[string]bool, len(mounts))
+	for _, m := range mounts {
+		if m.mountPoint == "" || pseudoFilesystemTypes[m.fsType] {
+			continue
+		}
+		if seen[m.mountPoint] {
+			continue
+		}
+		seen[m.mountPoint] = true
+		out = append(out, m.mountPoint)
+	}
+	return out
+}
+
+func hasMountOption(options, want string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == want {
+			return true
+		}
+	}
+	return false
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 // mountinfo escapes spaces and other bytes as octal: "\040".
 func unescapeMountInfoField(s string) string {
 	if !strings.Contains(s, "\\") {
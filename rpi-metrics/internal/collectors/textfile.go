@@ -0,0 +1,139 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/metrics"
+)
+
+// TextfileCollector reads metrics dropped into a directory by other programs
+// (cron jobs, backup scripts, sensor readers), node_exporter style.
+//
+// Every *.prom file is parsed as Prometheus text format and every *.json file as a
+// JSON array of samples, on each collection. textfile_mtime_seconds is exported per
+// file so stale files can be alerted on. Writers should write to a temporary name
+// and rename into place so a half-written file is never read.
+type TextfileCollector struct {
+	Dir string
+
+	MaxFileBytes int64 // default: 1 MiB
+}
+
+func init() {
+	metrics.MustRegisterDescriptors(
+		metrics.Descriptor{Name: "textfile_mtime_seconds", Type: metrics.TypeGauge, Help: "Modification time of a textfile; alert when it stops advancing.", Unit: metrics.UnitTimestamp, Labels: []string{"file"}},
+		metrics.Descriptor{Name: "textfile_scrape_error", Type: metrics.TypeGauge, Help: "1 when a textfile could not be read or parsed.", Unit: metrics.UnitBool, Labels: []string{"file"}, Range: &metrics.Range{Min: 0, Max: 1}},
+	)
+}
+
+func (c TextfileCollector) ID() string { return "textfile" }
+
+func (c TextfileCollector) Collect(ctx context.Context) ([]metrics.Sample, error) {
+	_ = ctx
+
+	if c.Dir == "" {
+		return nil, fmt.Errorf("no textfile directory configured")
+	}
+	maxBytes := c.MaxFileBytes
+	if maxBytes <= 0 {
+		maxBytes = constants.DefaultTextfileMaxBytes
+	}
+
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("read textfile dir: %w", err)
+	}
+
+	now := time.Now().UTC()
+	var out []metrics.Sample
+	var errs []error
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var format string
+		switch filepath.Ext(name) {
+		case ".prom":
+			format = metrics.FormatPrometheus
+		case ".json":
+			format = metrics.FormatJSON
+		default:
+			continue
+		}
+
+		path := filepath.Join(c.Dir, name)
+		fileLabels := map[string]string{"file": name}
+
+		samples, mtime, err := readTextfile(path, format, maxBytes)
+		scrapeErr := 0.0
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			scrapeErr = 1
+		} else {
+			out = append(out, samples...)
+		}
+
+		if !mtime.IsZero() {
+			out = append(out, metrics.Sample{
+				Name:      "textfile_mtime_seconds",
+				Value:     float64(mtime.UnixNano()) / 1e9,
+				Unit:      metrics.UnitTimestamp,
+				Timestamp: now,
+				Labels:    fileLabels,
+			})
+		}
+		out = append(out, metrics.Sample{
+			Name:      "textfile_scrape_error",
+			Value:     scrapeErr,
+			Unit:      metrics.UnitBool,
+			Timestamp: now,
+			Labels:    fileLabels,
+		})
+	}
+
+	return out, errors.Join(errs...)
+}
+
+func readTextfile(path, format string, maxBytes int64) ([]metrics.Sample, time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	mtime := st.ModTime().UTC()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxBytes+1))
+	if err != nil {
+		return nil, mtime, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, mtime, fmt.Errorf("file exceeds %d bytes", maxBytes)
+	}
+
+	samples, err := metrics.ParseSamples(data, format)
+	if err != nil {
+		return nil, mtime, err
+	}
+	return samples, mtime, nil
+}
@@ -0,0 +1,93 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/metrics"
+)
+
+// ThrottledSysfs reports the Raspberry Pi firmware's under-voltage and throttling
+// flags (what `vcgencmd get_throttled` prints). Machines without the firmware
+// interface report nothing.
+type ThrottledSysfs struct {
+	Path string // default: /sys/devices/platform/soc/soc:firmware/get_throttled
+}
+
+// ThrottleCondition is a bit of the firmware's get_throttled value. The same bit
+// shifted by ThrottleOccurredShift records whether the condition occurred since boot.
+type ThrottleCondition struct {
+	Bit   uint
+	Label string // the condition label of the firmware_throttle_* samples
+	Text  string
+}
+
+var ThrottleConditions = []ThrottleCondition{
+	{0, "under_voltage", "under-voltage"},
+	{1, "frequency_capped", "ARM frequency capped"},
+	{2, "throttled", "throttled"},
+	{3, "soft_temp_limit", "soft temperature limit"},
+}
+
+const ThrottleOccurredShift = 16
+
+func init() {
+	metrics.MustRegisterDescriptors(
+		metrics.Descriptor{
+			Name:   "firmware_throttle_active",
+			Type:   metrics.TypeGauge,
+			Help:   "1 while the firmware reports the condition (under_voltage, frequency_capped, throttled, soft_temp_limit).",
+			Unit:   metrics.UnitBool,
+			Labels: []string{"condition", "source"},
+			Range:  &metrics.Range{Min: 0, Max: 1},
+		},
+		metrics.Descriptor{
+			Name:   "firmware_throttle_occurred",
+			Type:   metrics.TypeGauge,
+			Help:   "1 when the condition occurred since boot.",
+			Unit:   metrics.UnitBool,
+			Labels: []string{"condition", "source"},
+			Range:  &metrics.Range{Min: 0, Max: 1},
+		},
+	)
+}
+
+func (c ThrottledSysfs) ID() string { return "throttled" }
+
+func (c ThrottledSysfs) Collect(ctx context.Context) ([]metrics.Sample, error) {
+	_ = ctx
+
+	path := c.Path
+	if path == "" {
+		path = constants.DefaultThrottledPath
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // not a Raspberry Pi, or no firmware interface: nothing to report
+		}
+		return nil, fmt.Errorf("read get_throttled: %w", err)
+	}
+	raw := strings.TrimSpace(string(b))
+	v, err := strconv.ParseUint(strings.TrimPrefix(raw, "0x"), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("parse get_throttled %q: %w", raw, err)
+	}
+
+	now := time.Now().UTC()
+	out := make([]metrics.Sample, 0, 2*len(ThrottleConditions))
+	for _, cond := range ThrottleConditions {
+		labels := map[string]string{"condition": cond.Label, "source": "sysfs"}
+		out = append(out,
+			metrics.Sample{Name: "firmware_throttle_active", Value: float64(v >> cond.Bit & 1), Unit: metrics.UnitBool, Timestamp: now, Labels: labels},
+			metrics.Sample{Name: "firmware_throttle_occurred", Value: float64(v >> (cond.Bit + ThrottleOccurredShift) & 1), Unit: metrics.UnitBool, Timestamp: now, Labels: labels},
+		)
+	}
+	return out, nil
+}
@@ -0,0 +1,90 @@
+// Package doctor checks an agent's setup: that every configured collector source
+// exists and yields plausible values, which optional sources this board has and
+// whether the exporter endpoints accept requests. See `rpi-metrics doctor`.
+package doctor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn" // works, but something is missing or looks off
+	Fail Status = "fail" // the agent will report errors or nothing at all
+)
+
+// Sections of a report, in the order they are printed.
+const (
+	SectionCollectors = "collectors"
+	SectionOptional   = "optional sources"
+	SectionExporters  = "exporters"
+)
+
+// Check is the outcome of one diagnostic.
+type Check struct {
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Detail  string `json:"detail"`
+	Hint    string `json:"hint,omitempty"` // what to do about a warn or fail
+}
+
+// Report collects checks in the order they ran.
+type Report struct {
+	Checks []Check `json:"checks"`
+	Passed int     `json:"passed"`
+	Warned int     `json:"warned"`
+	Failed int     `json:"failed"`
+}
+
+func (r *Report) Add(cs ...Check) {
+	for _, c := range cs {
+		r.Checks = append(r.Checks, c)
+		switch c.Status {
+		case Pass:
+			r.Passed++
+		case Warn:
+			r.Warned++
+		default:
+			r.Failed++
+		}
+	}
+}
+
+// WriteText prints the report as a table per section followed by a summary line.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	section := ""
+	for _, c := range r.Checks {
+		if c.Section != section {
+			if section != "" {
+				fmt.Fprintln(tw)
+			}
+			section = c.Section
+			fmt.Fprintf(tw, "%s:\n", strings.ToUpper(section))
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", strings.ToUpper(string(c.Status)), c.Name, c.Detail)
+		if c.Hint != "" {
+			fmt.Fprintf(tw, "  \t\t→ %s\n", c.Hint)
+		}
+	}
+	fmt.Fprintf(tw, "\n%d passed, %d warnings, %d failed\n", r.Passed, r.Warned, r.Failed)
+	return tw.Flush()
+}
+
+func pass(section, name, format string, args ...any) Check {
+	return Check{Section: section, Name: name, Status: Pass, Detail: fmt.Sprintf(format, args...)}
+}
+
+func warn(section, name, hint, format string, args ...any) Check {
+	return Check{Section: section, Name: name, Status: Warn, Detail: fmt.Sprintf(format, args...), Hint: hint}
+}
+
+func fail(section, name, hint, format string, args ...any) Check {
+	return Check{Section: section, Name: name, Status: Fail, Detail: fmt.Sprintf(format, args...), Hint: hint}
+}
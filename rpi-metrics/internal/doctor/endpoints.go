@@ -0,0 +1,189 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"rpi-metrics/internal/email"
+	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/secret"
+	"rpi-metrics/internal/telegram"
+)
+
+// The checks below are dry runs: nothing is posted to Discord, nothing is recorded by
+// the hub and no heartbeat is counted.
+
+// CheckDiscordWebhook fetches the webhook's metadata (a GET, which Discord answers
+// without posting a message) to confirm the URL and its token are valid.
+func CheckDiscordWebhook(ctx context.Context, client *http.Client, webhookURL string) Check {
+	const name = "discord"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, webhookURL, nil)
+	if err != nil {
+		return fail(SectionExporters, name, "check -discord-webhook", "invalid webhook url: %v", secret.StripURL(err))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fail(SectionExporters, name, "check DNS, the network and the system clock (TLS)", "%v", secret.StripURL(err))
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var hook struct {
+			Name      string `json:"name"`
+			ChannelID string `json:"channel_id"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&hook)
+		return pass(SectionExporters, name, "webhook %q (channel %s) is valid; nothing posted", hook.Name, hook.ChannelID)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusNotFound:
+		return fail(SectionExporters, name, "the webhook was deleted or the URL is incomplete; copy it again from the channel settings",
+			"webhook rejected: %s", resp.Status)
+	default:
+		return fail(SectionExporters, name, "", "unexpected response: %s", resp.Status)
+	}
+}
+
+// CheckPushURL sends an empty batch to an rpi-metrics-hub, which checks the token and
+// records nothing.
+func CheckPushURL(ctx context.Context, client *http.Client, pushURL, token string) Check {
+	const name = "push"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pushURL, bytes.NewReader([]byte(`{"results":[]}`)))
+	if err != nil {
+		return fail(SectionExporters, name, "check -push-url", "invalid push url: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fail(SectionExporters, name, "is rpi-metrics-hub running and reachable from this Pi?", "%v", secret.StripURL(err))
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return pass(SectionExporters, name, "hub accepted the token (empty batch)")
+	case resp.StatusCode == http.StatusUnauthorized:
+		return fail(SectionExporters, name, "-push-token must match this host's entry in the hub's -tokens file",
+			"token rejected: %s", resp.Status)
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed:
+		return fail(SectionExporters, name, "the URL should end in /api/push", "not a hub push endpoint: %s", resp.Status)
+	default:
+		return fail(SectionExporters, name, "", "unexpected response: %s", resp.Status)
+	}
+}
+
+// CheckHeartbeatURL only connects to the heartbeat host (including the TLS handshake
+// for https): any request to a ping URL would count as a check-in.
+func CheckHeartbeatURL(ctx context.Context, heartbeatURL string) Check {
+	const name = "heartbeat"
+
+	u, err := url.Parse(heartbeatURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fail(SectionExporters, name, "check -heartbeat-url", "invalid heartbeat url: want http:// or https://host/path")
+	}
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	addr := net.JoinHostPort(u.Hostname(), port)
+
+	var conn net.Conn
+	if u.Scheme == "https" {
+		d := tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		hint := "check DNS and the network"
+		if strings.Contains(err.Error(), "certificate") {
+			hint = "check the system clock; a Pi without an RTC starts in the past until NTP syncs"
+		}
+		return fail(SectionExporters, name, hint, "%v", err)
+	}
+	conn.Close()
+	return pass(SectionExporters, name, "%s reachable; not pinged", addr)
+}
+
+// CheckWebhookConfig loads the -webhook-config file: its secrets resolve and its
+// templates parse. Generic webhooks have no dry-run request, so nothing is sent.
+func CheckWebhookConfig(path string) []Check {
+	webhooks, err := metrics.LoadWebhookConfig(path)
+	if err != nil {
+		return []Check{fail(SectionExporters, "webhooks", "check -webhook-config", "%v", err)}
+	}
+	if len(webhooks) == 0 {
+		return []Check{warn(SectionExporters, "webhooks", "add webhooks to -webhook-config or remove the flag", "%s lists no webhooks", path)}
+	}
+	out := make([]Check, 0, len(webhooks))
+	for _, c := range webhooks {
+		out = append(out, pass(SectionExporters, "webhook:"+c.Name, "config valid; not posted (no dry run for generic webhooks)"))
+	}
+	return out
+}
+
+// CheckTelegram confirms the bot token (getMe) and that the bot can see each chat
+// (getChat); neither sends a message.
+func CheckTelegram(ctx context.Context, client telegram.Client, chatIDs []int64) []Check {
+	const name = "telegram"
+
+	me, err := client.GetMe(ctx)
+	if err != nil {
+		var se *metrics.StatusError
+		if errors.As(err, &se) && (se.Code == http.StatusUnauthorized || se.Code == http.StatusNotFound) {
+			return []Check{fail(SectionExporters, name, "check -telegram-token; @BotFather shows the bot's token", "token rejected: %v", err)}
+		}
+		return []Check{fail(SectionExporters, name, "check DNS, the network, the system clock (TLS) and -telegram-api-url", "%v", err)}
+	}
+	out := []Check{pass(SectionExporters, name, "bot @%s is valid; nothing sent", me.Username)}
+	for _, id := range chatIDs {
+		chat := fmt.Sprintf("telegram chat %d", id)
+		if err := client.GetChat(ctx, id); err != nil {
+			out = append(out, fail(SectionExporters, chat, "add the bot to the chat (or send it /start in a private chat) and check -telegram-chat-ids", "%v", err))
+			continue
+		}
+		out = append(out, pass(SectionExporters, chat, "bot can see the chat; nothing sent"))
+	}
+	return out
+}
+
+// CheckEmail goes through a delivery up to the message (TLS, auth and every
+// recipient) and then resets it, so nothing is mailed.
+func CheckEmail(ctx context.Context, sender email.Sender) Check {
+	const name = "email"
+
+	if err := sender.Check(ctx); err != nil {
+		hint := "check -smtp-addr, DNS and the network"
+		switch msg := err.Error(); {
+		case strings.Contains(msg, ": auth:"):
+			hint = "check -smtp-user and -smtp-password; some providers need an app password"
+		case strings.Contains(msg, "unencrypted connection"):
+			hint = "the password is only sent over TLS; use -smtp-tls=starttls or tls"
+		case strings.Contains(msg, ": rcpt to "), strings.Contains(msg, ": mail from:"):
+			hint = "the server refused -email-from or an -email-to address"
+		case strings.Contains(msg, "certificate"), strings.Contains(msg, "STARTTLS"):
+			hint = "check -smtp-tls (starttls for port 587, tls for 465) and the system clock"
+		}
+		return fail(SectionExporters, name, hint, "%v", err)
+	}
+	return pass(SectionExporters, name, "%s accepts %s for %d recipient(s); nothing sent", sender.Addr, sender.From, len(sender.To))
+}
+
+// NotConfigured reports an exporter that is switched off.
+func NotConfigured(name, flag string) Check {
+	return pass(SectionExporters, name, "not configured (-%s)", flag)
+}
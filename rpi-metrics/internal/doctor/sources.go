@@ -0,0 +1,237 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"rpi-metrics/internal/collectors"
+	"rpi-metrics/internal/metrics"
+)
+
+// Stateful collectors (CPU utilization) report nothing until their second reading.
+const secondReadingDelay = 250 * time.Millisecond
+
+// maxViolations limits how many out-of-range samples a check lists.
+const maxViolations = 3
+
+// CheckCollector runs one collection and checks every sample against its descriptor
+// (unit, plausible value range, labels). flag names the agent flag that points the
+// collector at its source, for the hint; it may be empty.
+func CheckCollector(ctx context.Context, c metrics.Collector, flag string) Check {
+	name := c.ID()
+	samples, err := c.Collect(ctx)
+	if err == nil && len(samples) == 0 {
+		time.Sleep(secondReadingDelay)
+		samples, err = c.Collect(ctx)
+	}
+	if err != nil {
+		return fail(SectionCollectors, name, sourceHint(err, flag), "%v", err)
+	}
+	if len(samples) == 0 {
+		return pass(SectionCollectors, name, "no samples (nothing to report on this board)")
+	}
+
+	var violations []string
+	for _, s := range samples {
+		if msg := metrics.CheckSample(s); msg != "" {
+			violations = append(violations, msg)
+		}
+	}
+	if len(violations) > 0 {
+		more := ""
+		if len(violations) > maxViolations {
+			more = fmt.Sprintf(" (and %d more)", len(violations)-maxViolations)
+			violations = violations[:maxViolations]
+		}
+		hint := "the source parses but its values look implausible; is it the right file?"
+		if flag != "" {
+			hint = fmt.Sprintf("the source parses but its values look implausible; check -%s", flag)
+		}
+		return warn(SectionCollectors, name, hint, "%s%s", strings.Join(violations, "; "), more)
+	}
+	return pass(SectionCollectors, name, "%d samples", len(samples))
+}
+
+func sourceHint(err error, flag string) string {
+	via := "the collector's source"
+	if flag != "" {
+		via = "-" + flag
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Sprintf("the path does not exist on this board; check %s against the optional sources below", via)
+	case errors.Is(err, fs.ErrPermission):
+		return "permission denied; run the agent as a user that can read it"
+	case errors.Is(err, strconv.ErrSyntax), errors.Is(err, strconv.ErrRange):
+		return fmt.Sprintf("the file exists but doesn't hold a number; check %s", via)
+	default:
+		return ""
+	}
+}
+
+// ProbeThrottled reads the firmware's under-voltage and throttling flags from sysfs,
+// falling back to `vcgencmd get_throttled`.
+func ProbeThrottled(ctx context.Context, sysfsPath, vcgencmd string) Check {
+	const name = "throttle flags"
+
+	raw, source := "", ""
+	if b, err := os.ReadFile(sysfsPath); err == nil {
+		raw, source = strings.TrimSpace(string(b)), sysfsPath
+	} else if path, err := exec.LookPath(vcgencmd); err == nil {
+		out, err := exec.CommandContext(ctx, path, "get_throttled").Output()
+		if err != nil {
+			return warn(SectionOptional, name, "", "vcgencmd get_throttled: %v", err)
+		}
+		_, raw, _ = strings.Cut(strings.TrimSpace(string(out)), "=") // throttled=0x50000
+		source = "vcgencmd"
+	} else {
+		return warn(SectionOptional, name,
+			"under-voltage and throttling can't be detected (not a Raspberry Pi, or firmware interface missing)",
+			"not available (%s missing, %s not installed)", sysfsPath, vcgencmd)
+	}
+
+	v, err := strconv.ParseUint(strings.TrimPrefix(raw, "0x"), 16, 32)
+	if err != nil {
+		return warn(SectionOptional, name, "", "%s: unexpected value %q", source, raw)
+	}
+	var now, past []string
+	for _, c := range collectors.ThrottleConditions {
+		if v&(1<<c.Bit) != 0 {
+			now = append(now, c.Text)
+		}
+		if v&(1<<(c.Bit+collectors.ThrottleOccurredShift)) != 0 {
+			past = append(past, c.Text)
+		}
+	}
+	if len(now) == 0 && len(past) == 0 {
+		return pass(SectionOptional, name, "no under-voltage or throttling since boot (%s)", source)
+	}
+	detail := fmt.Sprintf("0x%x from %s", v, source)
+	if len(now) > 0 {
+		detail += "; now: " + strings.Join(now, ", ")
+	}
+	if len(past) > 0 {
+		detail += "; since boot: " + strings.Join(past, ", ")
+	}
+	return warn(SectionOptional, name, "use the official power supply and check cooling", "%s", detail)
+}
+
+// ProbeThermalZones lists the thermal zones (thermal_zoneN) and cooling devices
+// (cooling_deviceN) under root, the candidates for -temp-path and -cooling-path.
+func ProbeThermalZones(root string) []Check {
+	zones := listClassDevices(root, "thermal_zone*", func(dir string) string {
+		typ, _ := readTrimmed(filepath.Join(dir, "type"))
+		if s, ok := readTrimmed(filepath.Join(dir, "temp")); ok {
+			if milli, err := strconv.ParseFloat(s, 64); err == nil {
+				return fmt.Sprintf("%s %.1f°C", typ, milli/1000)
+			}
+		}
+		return typ
+	})
+	devices := listClassDevices(root, "cooling_device*", func(dir string) string {
+		typ, _ := readTrimmed(filepath.Join(dir, "type"))
+		if maxState, ok := readTrimmed(filepath.Join(dir, "max_state")); ok {
+			return fmt.Sprintf("%s, max state %s", typ, maxState)
+		}
+		return typ
+	})
+
+	var out []Check
+	if len(zones) == 0 {
+		out = append(out, warn(SectionOptional, "thermal zones", "temperature can't be collected on this board", "none under %s", root))
+	} else {
+		out = append(out, pass(SectionOptional, "thermal zones", "%s", strings.Join(zones, "; ")))
+	}
+	if len(devices) == 0 {
+		out = append(out, warn(SectionOptional, "cooling devices", "no fan or cooling driver; the cooling_device collector will fail", "none under %s", root))
+	} else {
+		out = append(out, pass(SectionOptional, "cooling devices", "%s", strings.Join(devices, "; ")))
+	}
+	return out
+}
+
+// ProbeHwmon lists hardware monitoring chips (e.g. rpi_volt for under-voltage, fan
+// controllers, HAT sensors).
+func ProbeHwmon(root string) Check {
+	chips := listClassDevices(root, "hwmon*", func(dir string) string {
+		name, _ := readTrimmed(filepath.Join(dir, "name"))
+		return name
+	})
+	if len(chips) == 0 {
+		return warn(SectionOptional, "hwmon", "", "no hardware monitoring chips under %s", root)
+	}
+	return pass(SectionOptional, "hwmon", "%s", strings.Join(chips, "; "))
+}
+
+// ProbePowerSupplies lists the power supplies collectors.PowerSupplySysfs reports.
+func ProbePowerSupplies(root string) Check {
+	supplies := listClassDevices(root, "*", func(dir string) string {
+		typ, _ := readTrimmed(filepath.Join(dir, "type"))
+		return typ
+	})
+	if len(supplies) == 0 {
+		return warn(SectionOptional, "power supplies",
+			"battery and UPS metrics and -shutdown-below need a UPS HAT or battery with a kernel driver",
+			"none under %s", root)
+	}
+	return pass(SectionOptional, "power supplies", "%s", strings.Join(supplies, "; "))
+}
+
+// ProbeWireless reports whether Wi-Fi statistics and `iw` are available.
+func ProbeWireless(wirelessPath, iw string) Check {
+	const name = "wi-fi"
+
+	b, err := os.ReadFile(wirelessPath)
+	if err != nil {
+		return warn(SectionOptional, name, "", "%s not readable: %v", wirelessPath, err)
+	}
+	var ifaces []string
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		if iface, _, ok := bytes.Cut(line, []byte(":")); ok && !bytes.Contains(iface, []byte("|")) {
+			ifaces = append(ifaces, strings.TrimSpace(string(iface)))
+		}
+	}
+	if len(ifaces) == 0 {
+		return warn(SectionOptional, name, "", "no wireless interfaces in %s", wirelessPath)
+	}
+	if iw == "-" {
+		return pass(SectionOptional, name, "%s (iw disabled)", strings.Join(ifaces, ", "))
+	}
+	if _, err := exec.LookPath(iw); err != nil {
+		return warn(SectionOptional, name, "install iw (apt install iw) for the SSID and bitrates",
+			"%s; %s not found", strings.Join(ifaces, ", "), iw)
+	}
+	return pass(SectionOptional, name, "%s; %s found", strings.Join(ifaces, ", "), iw)
+}
+
+// listClassDevices describes every entry of a sysfs class directory matching pattern
+// as "entry (description)", sorted by name.
+func listClassDevices(root, pattern string, describe func(dir string) string) []string {
+	matches, _ := filepath.Glob(filepath.Join(root, pattern))
+	out := make([]string, 0, len(matches))
+	for _, dir := range matches {
+		entry := filepath.Base(dir)
+		if d := describe(dir); d != "" {
+			entry += " (" + d + ")"
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+func readTrimmed(path string) (string, bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(b)), true
+}
@@ -0,0 +1,121 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/schedule"
+)
+
+// Exporter mails an alert as soon as it is raised or resolved, notices (Notify) and a
+// digest each time Digest fires: a metrics.Summary of the window since the previous
+// digest (the first one covers the time since the agent started). The digest is
+// scheduled by a metrics.DigestExporter, as Discord's is.
+type Exporter struct {
+	Sender Sender
+	Digest schedule.Schedule // the zero Schedule: no digests
+
+	alerts map[string]metrics.Alert // active alerts by key, from the last Export
+	digest metrics.DigestExporter
+	host   string // of the last Export, for the digest
+}
+
+func (e *Exporter) Export(ctx context.Context, res metrics.Result) error {
+	host := hostOf(res)
+
+	// Remember the alerts only once they are sent, so a failed send is tried again.
+	raised, resolved, active := metrics.AlertChanges(e.alerts, metrics.DetectAlerts(res))
+	var alertErr error
+	if len(raised) > 0 || len(resolved) > 0 {
+		alertErr = e.sendAlerts(ctx, host, raised, resolved)
+	}
+	if alertErr == nil {
+		e.alerts = active
+	}
+
+	if e.Digest.IsZero() {
+		return alertErr
+	}
+	if e.digest.SendSummary == nil {
+		e.digest.Schedule, e.digest.SendSummary = e.Digest, e.sendDigest
+	}
+	e.host = host
+	if err := e.digest.Export(ctx, res); err != nil && alertErr == nil {
+		alertErr = fmt.Errorf("digest: %w", err)
+	}
+	return alertErr
+}
+
+// Notify mails a one-off notice (a shutdown is about to happen).
+func (e *Exporter) Notify(ctx context.Context, msg string) error {
+	host := hostOf(metrics.Result{})
+	return e.send(ctx, fmt.Sprintf("[rpi-metrics %s] %s", host, firstLine(msg)), content{
+		Heading:  "Notice from " + host,
+		Sections: []metrics.SummarySection{{Lines: strings.Split(msg, "\n")}},
+	})
+}
+
+func (e *Exporter) sendAlerts(ctx context.Context, host string, raised, resolved []metrics.Alert) error {
+	c := content{Heading: fmt.Sprintf("%s at %s", host, time.Now().Format("2006-01-02 15:04 MST"))}
+	var subject string
+	if len(raised) > 0 {
+		sec := metrics.SummarySection{Title: "Alerts"}
+		for _, a := range raised {
+			sec.Lines = append(sec.Lines, fmt.Sprintf("[%s] %s", strings.ToUpper(a.Severity), a.Message))
+		}
+		c.Sections = append(c.Sections, sec)
+		subject = fmt.Sprintf("%s: %s", strings.ToUpper(raised[0].Severity), raised[0].Message)
+	}
+	if len(resolved) > 0 {
+		sec := metrics.SummarySection{Title: "Resolved"}
+		for _, a := range resolved {
+			sec.Lines = append(sec.Lines, a.Message)
+		}
+		c.Sections = append(c.Sections, sec)
+		if subject == "" {
+			subject = "Resolved: " + resolved[0].Message
+		}
+	}
+	if n := len(raised) + len(resolved); n > 1 {
+		subject += fmt.Sprintf(" (+%d more)", n-1)
+	}
+	return e.send(ctx, fmt.Sprintf("[rpi-metrics %s] %s", host, subject), c)
+}
+
+func (e *Exporter) sendDigest(ctx context.Context, s *metrics.Summary) error {
+	c := content{
+		Heading:  s.Heading(),
+		Sections: s.Sections(),
+		Footer:   fmt.Sprintf("Digest from rpi-metrics on %s, sent %s.", e.host, e.Digest),
+	}
+	return e.send(ctx, fmt.Sprintf("[rpi-metrics %s] Digest %s", e.host, time.Now().Format("2006-01-02 15:04")), c)
+}
+
+func (e *Exporter) send(ctx context.Context, subject string, c content) error {
+	html, err := renderHTML(c)
+	if err != nil {
+		return err
+	}
+	return e.Sender.Send(ctx, subject, renderText(c), html)
+}
+
+// hostOf names the Pi in subjects: the host label the agent puts on samples, or the
+// hostname.
+func hostOf(res metrics.Result) string {
+	for _, s := range res.Samples {
+		if h := s.Labels["host"]; h != "" {
+			return h
+		}
+	}
+	h, _ := os.Hostname()
+	return h
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,102 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+)
+
+// buildMessage renders a multipart/alternative message with a text and an HTML part.
+func buildMessage(from string, to []string, subject, text, html string, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := "localhost"
+	if _, d, ok := strings.Cut(from, "@"); ok {
+		domain = strings.Trim(d, "<> ")
+	}
+	header := []string{
+		"From: " + from,
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + now.Format(time.RFC1123Z),
+		fmt.Sprintf("Message-ID: <%s@%s>", hex.EncodeToString(id), domain),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + mw.Boundary(),
+	}
+	buf.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(strings.ReplaceAll(part.body, "\n", "\r\n"))); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// content is a mail: a heading and sections, rendered as text and as HTML.
+type content struct {
+	Heading  string
+	Sections []metrics.SummarySection
+	Footer   string
+}
+
+var htmlTemplate = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif; font-size: 14px">
+<h2 style="font-size: 16px">{{.Heading}}</h2>
+{{range .Sections}}{{if .Title}}<h3 style="font-size: 14px; margin-bottom: 4px">{{.Title}}</h3>
+{{end}}<ul style="margin-top: 0">
+{{range .Lines}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}{{if .Footer}}<p style="color: #666">{{.Footer}}</p>
+{{end}}</body></html>
+`))
+
+// renderHTML renders sections as HTML.
+func renderHTML(d content) (string, error) {
+	var b strings.Builder
+	if err := htmlTemplate.Execute(&b, d); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// renderText renders the same content as text, as in Discord messages.
+func renderText(d content) string {
+	text := metrics.FormatSections(d.Heading, d.Sections)
+	if d.Footer != "" {
+		text += "\n\n" + d.Footer
+	}
+	return text
+}
@@ -0,0 +1,195 @@
+// Package email sends rpi-metrics alerts, notices and daily digests by SMTP, each as
+// a plain text and an HTML part.
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+)
+
+// How the connection to the SMTP server is secured (-smtp-tls).
+const (
+	TLSStartTLS = "starttls" // plain connection upgraded with STARTTLS, usually port 587
+	TLSImplicit = "tls"      // TLS from the start, usually port 465
+	TLSNone     = "none"     // no TLS; only for a relay on localhost
+)
+
+// Sender delivers messages through one SMTP server.
+type Sender struct {
+	Addr     string // host:port
+	TLS      string // TLSStartTLS (default), TLSImplicit or TLSNone
+	Username string // optional; PLAIN auth, which net/smtp only allows over TLS or to localhost
+	Password string
+	From     string
+	To       []string
+	Timeout  time.Duration // per message; default 30s
+
+	// TLSConfig is the base for TLSImplicit and TLSStartTLS, e.g. RootCAs for a relay
+	// with a private CA. Optional; ServerName defaults to the host of Addr.
+	TLSConfig *tls.Config
+
+	// Retry retries failed sends: network errors and 4xx (transient) SMTP replies.
+	Retry metrics.Retry
+}
+
+// Send sends a message with a plain text and an HTML body to every recipient.
+func (s *Sender) Send(ctx context.Context, subject, text, html string) error {
+	if s.Addr == "" || s.From == "" || len(s.To) == 0 {
+		return fmt.Errorf("smtp server, sender and recipients are required")
+	}
+	msg, err := buildMessage(s.From, s.To, subject, text, html, time.Now())
+	if err != nil {
+		return err
+	}
+	return s.Retry.Do(ctx, func() error { return s.send(ctx, msg) })
+}
+
+func (s *Sender) send(ctx context.Context, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout())
+	defer cancel()
+	c, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := s.envelope(c); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return smtpError(s.Addr, "data", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return smtpError(s.Addr, "data", err)
+	}
+	if err := w.Close(); err != nil {
+		return smtpError(s.Addr, "data", err)
+	}
+	return c.Quit()
+}
+
+// Check goes through a delivery up to the message (connection, TLS, auth, sender and
+// recipients), then resets it: nothing is sent.
+func (s *Sender) Check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout())
+	defer cancel()
+	c, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := s.envelope(c); err != nil {
+		return err
+	}
+	if err := c.Reset(); err != nil {
+		return smtpError(s.Addr, "rset", err)
+	}
+	return c.Quit()
+}
+
+func (s *Sender) timeout() time.Duration {
+	if s.Timeout <= 0 {
+		return 30 * time.Second
+	}
+	return s.Timeout
+}
+
+// dial connects, secures the connection as configured and authenticates.
+func (s *Sender) dial(ctx context.Context) (*smtp.Client, error) {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("smtp server %q: %w", s.Addr, err)
+	}
+
+	tlsConfig := &tls.Config{}
+	if s.TLSConfig != nil {
+		tlsConfig = s.TLSConfig.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host
+	}
+
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	if s.TLS == TLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", s.Addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", s.Addr)
+	}
+	if err != nil {
+		return nil, &metrics.TransportError{Err: fmt.Errorf("smtp %s: %w", s.Addr, err)}
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, smtpError(s.Addr, "greeting", err)
+	}
+	if s.TLS == "" || s.TLS == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			c.Close()
+			return nil, fmt.Errorf("smtp %s does not offer STARTTLS; use -smtp-tls=tls for port 465", s.Addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, smtpError(s.Addr, "starttls", err)
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			c.Close()
+			return nil, smtpError(s.Addr, "auth", err)
+		}
+	}
+	return c, nil
+}
+
+// envelope sends the sender and every recipient, without display names.
+func (s *Sender) envelope(c *smtp.Client) error {
+	if err := c.Mail(envelopeAddr(s.From)); err != nil {
+		return smtpError(s.Addr, "mail from", err)
+	}
+	for _, to := range s.To {
+		if err := c.Rcpt(envelopeAddr(to)); err != nil {
+			return smtpError(s.Addr, "rcpt to "+to, err)
+		}
+	}
+	return nil
+}
+
+// envelopeAddr returns the bare address of "Name <user@host>"; anything it can't
+// parse is passed on for the server to judge.
+func envelopeAddr(addr string) string {
+	if a, err := mail.ParseAddress(addr); err == nil {
+		return a.Address
+	}
+	return addr
+}
+
+// smtpError wraps err from an SMTP step; Retry retries network errors and 4xx replies
+// (e.g. greylisting), not 5xx ones (a rejected recipient, failed auth).
+func smtpError(addr, step string, err error) error {
+	err = fmt.Errorf("smtp %s: %s: %w", addr, step, err)
+	var tpe *textproto.Error
+	if errors.As(err, &tpe) && tpe.Code >= 500 {
+		return err
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) || (tpe != nil && tpe.Code >= 400) {
+		return &metrics.TransportError{Err: err}
+	}
+	return err
+}
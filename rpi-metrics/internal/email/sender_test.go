@@ -0,0 +1,360 @@
+package email
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+)
+
+// fakeSMTP is a minimal SMTP server with STARTTLS and AUTH PLAIN that records what
+// each session did.
+type fakeSMTP struct {
+	ln       net.Listener
+	tls      *tls.Config // nil: no STARTTLS
+	user     string
+	password string
+	// reply overrides the reply to a command verb ("RCPT") for the n-th session
+	// (counting from 1); it returns "" to answer normally.
+	reply func(session int, verb string) string
+
+	mu       sync.Mutex
+	sessions []*smtpSession
+}
+
+type smtpSession struct {
+	tls   bool
+	auth  string // user of a successful AUTH
+	from  string
+	rcpts []string
+	data  string
+	rset  bool
+	quit  bool
+}
+
+func newFakeSMTP(t *testing.T, withTLS bool) (*fakeSMTP, *x509.CertPool) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeSMTP{ln: ln, user: "pi", password: "s3cret"}
+	var pool *x509.CertPool
+	if withTLS {
+		var cert tls.Certificate
+		cert, pool = selfSignedCert(t)
+		f.tls = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f, pool
+}
+
+func (f *fakeSMTP) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		s := &smtpSession{}
+		f.sessions = append(f.sessions, s)
+		n := len(f.sessions)
+		f.mu.Unlock()
+		go f.handle(conn, n, s)
+	}
+}
+
+func (f *fakeSMTP) handle(conn net.Conn, n int, s *smtpSession) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	tc := textproto.NewConn(conn)
+	tc.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		verb = strings.ToUpper(verb)
+		if f.reply != nil {
+			if r := f.reply(n, verb); r != "" {
+				tc.PrintfLine("%s", r)
+				continue
+			}
+		}
+
+		f.mu.Lock()
+		switch verb {
+		case "EHLO", "HELO":
+			ext := []string{"fake"}
+			if f.tls != nil && !s.tls {
+				ext = append(ext, "STARTTLS")
+			}
+			ext = append(ext, "AUTH PLAIN")
+			for i, e := range ext {
+				sep := "-"
+				if i == len(ext)-1 {
+					sep = " "
+				}
+				tc.PrintfLine("250%s%s", sep, e)
+			}
+		case "STARTTLS":
+			tc.PrintfLine("220 go ahead")
+			tlsConn := tls.Server(conn, f.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				f.mu.Unlock()
+				return
+			}
+			conn, s.tls = tlsConn, true
+			tc = textproto.NewConn(conn)
+		case "AUTH":
+			mech, resp, _ := strings.Cut(arg, " ")
+			b, _ := base64.StdEncoding.DecodeString(resp)
+			if parts := strings.Split(string(b), "\x00"); mech == "PLAIN" && len(parts) == 3 &&
+				parts[1] == f.user && parts[2] == f.password {
+				s.auth = parts[1]
+				tc.PrintfLine("235 2.7.0 Authentication successful")
+			} else {
+				tc.PrintfLine("535 5.7.8 Authentication credentials invalid")
+			}
+		case "MAIL":
+			s.from = arg
+			tc.PrintfLine("250 OK")
+		case "RCPT":
+			s.rcpts = append(s.rcpts, arg)
+			tc.PrintfLine("250 OK")
+		case "DATA":
+			tc.PrintfLine("354 end with <CRLF>.<CRLF>")
+			f.mu.Unlock()
+			b, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			s.data = string(b)
+			tc.PrintfLine("250 queued")
+		case "RSET":
+			s.rset = true
+			tc.PrintfLine("250 OK")
+		case "NOOP":
+			tc.PrintfLine("250 OK")
+		case "QUIT":
+			s.quit = true
+			tc.PrintfLine("221 bye")
+			f.mu.Unlock()
+			return
+		default:
+			tc.PrintfLine("502 command not implemented")
+		}
+		f.mu.Unlock()
+	}
+}
+
+// session returns a copy of the n-th session, counting from 1.
+func (f *fakeSMTP) session(t *testing.T, n int) smtpSession {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sessions) < n {
+		t.Fatalf("got %d SMTP sessions, want at least %d", len(f.sessions), n)
+	}
+	return *f.sessions[n-1]
+}
+
+func (f *fakeSMTP) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sessions)
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 and a pool that trusts it.
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake smtp"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+func TestSendStartTLS(t *testing.T) {
+	srv, pool := newFakeSMTP(t, true)
+	s := &Sender{
+		Addr:      srv.ln.Addr().String(),
+		Username:  "pi",
+		Password:  "s3cret",
+		From:      "rpi-metrics <pi@example.com>",
+		To:        []string{"ops@example.com", "Me <me@example.com>"},
+		TLSConfig: &tls.Config{RootCAs: pool},
+	}
+
+	if err := s.Send(context.Background(), "Disk 91% full", "root is 91% full", "<p>root is 91% full</p>"); err != nil {
+		t.Fatal(err)
+	}
+	got := srv.session(t, 1)
+	if !got.tls || got.auth != "pi" || !got.quit {
+		t.Errorf("session tls=%v auth=%q quit=%v, want STARTTLS, auth as pi and QUIT", got.tls, got.auth, got.quit)
+	}
+	if got.from != "FROM:<pi@example.com>" {
+		t.Errorf("MAIL %s", got.from)
+	}
+	if strings.Join(got.rcpts, " ") != "TO:<ops@example.com> TO:<me@example.com>" {
+		t.Errorf("RCPT %q", got.rcpts)
+	}
+	for _, want := range []string{"Subject: Disk 91% full", "To: ops@example.com, Me <me@example.com>", "multipart/alternative", "root is 91% full"} {
+		if !strings.Contains(got.data, want) {
+			t.Errorf("message does not contain %q:\n%s", want, got.data)
+		}
+	}
+}
+
+func TestSendStartTLSUntrusted(t *testing.T) {
+	srv, _ := newFakeSMTP(t, true)
+	s := &Sender{Addr: srv.ln.Addr().String(), From: "pi@example.com", To: []string{"ops@example.com"}}
+
+	err := s.Send(context.Background(), "s", "t", "h")
+	if err == nil || !strings.Contains(err.Error(), "starttls") {
+		t.Fatalf("error = %v, want a starttls certificate error", err)
+	}
+	if srv.session(t, 1).rcpts != nil {
+		t.Error("envelope sent over an unverified connection")
+	}
+}
+
+func TestSendWithoutStartTLS(t *testing.T) {
+	srv, _ := newFakeSMTP(t, false)
+	s := &Sender{Addr: srv.ln.Addr().String(), From: "pi@example.com", To: []string{"ops@example.com"}}
+
+	err := s.Send(context.Background(), "s", "t", "h")
+	if err == nil || !strings.Contains(err.Error(), "does not offer STARTTLS") {
+		t.Fatalf("error = %v, want STARTTLS to be required", err)
+	}
+
+	s.TLS = TLSNone
+	if err := s.Send(context.Background(), "s", "t", "h"); err != nil {
+		t.Fatalf("-smtp-tls=none: %v", err)
+	}
+	if got := srv.session(t, 2); got.tls || got.data == "" {
+		t.Errorf("session tls=%v, data %d bytes; want a plain delivery", got.tls, len(got.data))
+	}
+}
+
+func TestSendAuthFailure(t *testing.T) {
+	srv, pool := newFakeSMTP(t, true)
+	s := &Sender{
+		Addr:      srv.ln.Addr().String(),
+		Username:  "pi",
+		Password:  "wrong",
+		From:      "pi@example.com",
+		To:        []string{"ops@example.com"},
+		TLSConfig: &tls.Config{RootCAs: pool},
+		Retry:     metrics.Retry{Attempts: 3, Backoff: time.Millisecond},
+	}
+
+	err := s.Send(context.Background(), "s", "t", "h")
+	var tpe *textproto.Error
+	if !errors.As(err, &tpe) || tpe.Code != 535 || !strings.Contains(err.Error(), "auth") {
+		t.Fatalf("error = %v, want a 535 auth error", err)
+	}
+	var te *metrics.TransportError
+	if errors.As(err, &te) {
+		t.Error("auth failure is retryable")
+	}
+	if n := srv.count(); n != 1 {
+		t.Errorf("got %d sessions, want 1: a 5xx reply is not retried", n)
+	}
+	if got := srv.session(t, 1); got.from != "" || got.data != "" {
+		t.Error("sent the envelope after a failed AUTH")
+	}
+}
+
+func TestSendRetriesTransientReply(t *testing.T) {
+	srv, pool := newFakeSMTP(t, true)
+	srv.reply = func(session int, verb string) string {
+		if session == 1 && verb == "RCPT" {
+			return "451 4.7.1 greylisted, try again later"
+		}
+		return ""
+	}
+	s := &Sender{
+		Addr:      srv.ln.Addr().String(),
+		From:      "pi@example.com",
+		To:        []string{"ops@example.com"},
+		TLSConfig: &tls.Config{RootCAs: pool},
+		Retry:     metrics.Retry{Attempts: 3, Backoff: time.Millisecond},
+	}
+
+	if err := s.Send(context.Background(), "s", "t", "h"); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.count(); n != 2 {
+		t.Errorf("got %d sessions, want 2", n)
+	}
+	if srv.session(t, 2).data == "" {
+		t.Error("retry did not deliver the message")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	srv, pool := newFakeSMTP(t, true)
+	s := &Sender{
+		Addr:      srv.ln.Addr().String(),
+		Username:  "pi",
+		Password:  "s3cret",
+		From:      "pi@example.com",
+		To:        []string{"ops@example.com"},
+		TLSConfig: &tls.Config{RootCAs: pool},
+	}
+
+	if err := s.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := srv.session(t, 1)
+	if !got.tls || got.auth != "pi" || len(got.rcpts) != 1 || !got.rset || !got.quit {
+		t.Errorf("session %+v, want STARTTLS, AUTH, RCPT, RSET and QUIT", got)
+	}
+	if got.data != "" {
+		t.Error("Check sent a message")
+	}
+
+	s.Password = "wrong"
+	if err := s.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "535") {
+		t.Errorf("Check with a wrong password: %v, want a 535 auth error", err)
+	}
+}
@@ -0,0 +1,356 @@
+// Package hub aggregates Results pushed by many agents (see metrics.PushExporter):
+// it authenticates agents by token, tracks when each host was last seen, keeps a short
+// history per series and serves a fleet overview API.
+package hub
+
+import (
+	"compress/gzip"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusWarning  = "warning"  // warning alerts or collector errors
+	StatusCritical = "critical" // critical alerts
+	StatusStale    = "stale"    // no push within StaleAfter
+)
+
+const (
+	defaultHistory    = time.Hour
+	defaultStaleAfter = 2 * time.Minute
+	maxPushBytes      = 8 << 20 // decompressed
+)
+
+// Hub holds the fleet state. The zero value is not usable; Tokens must be set.
+type Hub struct {
+	// Tokens maps host name to that agent's push token.
+	Tokens map[string]string
+
+	History    time.Duration // how much history to keep per series; default 1h
+	StaleAfter time.Duration // default 2m
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	lastSeen time.Time
+	latest   metrics.PushResult
+	series   map[string]*series
+}
+
+type series struct {
+	name   string
+	unit   string
+	labels map[string]string
+	points []point
+}
+
+type point struct {
+	t time.Time
+	v float64
+}
+
+// LoadTokens reads a JSON object of host name to token, e.g. {"garage-pi": "s3cret"}.
+func LoadTokens(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tokens: %w", err)
+	}
+	var tokens map[string]string
+	if err := json.Unmarshal(b, &tokens); err != nil {
+		return nil, fmt.Errorf("parse tokens %s: %w", path, err)
+	}
+	for host, token := range tokens {
+		if host == "" || token == "" {
+			return nil, fmt.Errorf("tokens %s: empty host or token", path)
+		}
+	}
+	return tokens, nil
+}
+
+// HostSummary is one tile of the fleet overview.
+type HostSummary struct {
+	Host     string                   `json:"host"`
+	Status   string                   `json:"status"`
+	LastSeen time.Time                `json:"last_seen"`
+	Alerts   []metrics.Alert          `json:"alerts,omitempty"`
+	Errors   []metrics.CollectorError `json:"errors,omitempty"`
+
+	// Headline values for the tile; absent when the host doesn't report them.
+	CPUTemperature  *float64 `json:"cpu_temperature,omitempty"`
+	CPUUtilization  *float64 `json:"cpu_utilization,omitempty"`
+	RootUsedPercent *float64 `json:"root_used_percent,omitempty"`
+}
+
+// HostMetrics is the latest Result of one host, as served to the single-host view.
+type HostMetrics struct {
+	Host      string                   `json:"host"`
+	Timestamp time.Time                `json:"timestamp"`
+	Samples   []metrics.Sample         `json:"samples"`
+	Errors    []metrics.CollectorError `json:"errors,omitempty"`
+}
+
+// SeriesHistory is the recent history of one series, points as [unix seconds, value].
+type SeriesHistory struct {
+	Name   string            `json:"name"`
+	Unit   string            `json:"unit,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Points [][2]float64      `json:"points"`
+}
+
+// Handler serves the hub API:
+//
+//	POST /api/push                   agents push a metrics.PushBatch (bearer token auth)
+//	GET  /api/hosts                  fleet overview, worst status first
+//	GET  /api/hosts/{host}/metrics   latest Result of one host
+//	GET  /api/hosts/{host}/history   recent history, optionally ?name=cpu_temperature
+func (h *Hub) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/push", h.handlePush)
+	mux.HandleFunc("GET /api/hosts", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, h.Summaries(time.Now()))
+	})
+	mux.HandleFunc("GET /api/hosts/{host}/metrics", func(w http.ResponseWriter, r *http.Request) {
+		m, ok := h.Latest(r.PathValue("host"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, m)
+	})
+	mux.HandleFunc("GET /api/hosts/{host}/history", func(w http.ResponseWriter, r *http.Request) {
+		hist, ok := h.HistoryOf(r.PathValue("host"), r.URL.Query().Get("name"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, hist)
+	})
+	return mux
+}
+
+func (h *Hub) handlePush(w http.ResponseWriter, r *http.Request) {
+	host, ok := h.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="rpi-metrics-hub"`)
+		http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, "bad gzip body", http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		body = zr
+	}
+
+	var batch metrics.PushBatch
+	if err := json.NewDecoder(io.LimitReader(body, maxPushBytes)).Decode(&batch); err != nil {
+		http.Error(w, fmt.Sprintf("bad push body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	h.record(host, batch.Results, time.Now())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authenticate returns the host whose token is presented as a bearer token.
+func (h *Hub) authenticate(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+	for host, want := range h.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+			return host, true
+		}
+	}
+	return "", false
+}
+
+func (h *Hub) record(host string, results []metrics.PushResult, now time.Time) {
+	if len(results) == 0 {
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].CollectedAt.Before(results[j].CollectedAt) })
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.hosts == nil {
+		h.hosts = make(map[string]*hostState)
+	}
+	hs, ok := h.hosts[host]
+	if !ok {
+		hs = &hostState{series: make(map[string]*series)}
+		h.hosts[host] = hs
+	}
+	hs.lastSeen = now
+
+	history := h.History
+	if history <= 0 {
+		history = defaultHistory
+	}
+
+	for _, res := range results {
+		if !res.CollectedAt.Before(hs.latest.CollectedAt) {
+			hs.latest = res
+		}
+		for _, s := range res.Samples {
+			key := metrics.SeriesKey(s.Name, s.Labels)
+			ser, ok := hs.series[key]
+			if !ok {
+				ser = &series{name: s.Name, unit: s.Unit, labels: s.Labels}
+				hs.series[key] = ser
+			}
+			ser.points = append(ser.points, point{t: s.Timestamp, v: s.Value})
+		}
+	}
+
+	// Trim history and forget series that stopped reporting.
+	cutoff := now.Add(-history)
+	for key, ser := range hs.series {
+		drop := 0
+		for drop < len(ser.points) && ser.points[drop].t.Before(cutoff) {
+			drop++
+		}
+		ser.points = ser.points[drop:]
+		if len(ser.points) == 0 {
+			delete(hs.series, key)
+		}
+	}
+}
+
+// Summaries returns the fleet overview, worst status first, then by host name.
+// Hosts with a token that never pushed are listed as stale.
+func (h *Hub) Summaries(now time.Time) []HostSummary {
+	staleAfter := h.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = defaultStaleAfter
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := make([]HostSummary, 0, len(h.Tokens))
+	for host := range h.Tokens {
+		sum := HostSummary{Host: host, Status: StatusStale}
+		hs, ok := h.hosts[host]
+		if !ok {
+			out = append(out, sum)
+			continue
+		}
+		res := metrics.Result{Samples: hs.latest.Samples, Errors: hs.latest.Errors}
+		sum.LastSeen = hs.lastSeen
+		sum.Alerts = metrics.DetectAlerts(res)
+		sum.Errors = hs.latest.Errors
+		sum.Status = hostStatus(sum, now.Sub(hs.lastSeen) > staleAfter)
+
+		for _, s := range hs.latest.Samples {
+			v := s.Value
+			switch {
+			case s.Name == "cpu_temperature":
+				sum.CPUTemperature = &v
+			case s.Name == "cpu_utilization" && s.Labels["cpu"] == "total":
+				sum.CPUUtilization = &v
+			case s.Name == "storage_used_percent" && s.Labels["mount_point"] == "/":
+				sum.RootUsedPercent = &v
+			}
+		}
+		out = append(out, sum)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		ri, rj := statusRank[out[i].Status], statusRank[out[j].Status]
+		if ri != rj {
+			return ri > rj
+		}
+		return out[i].Host < out[j].Host
+	})
+	return out
+}
+
+var statusRank = map[string]int{StatusOK: 0, StatusWarning: 1, StatusCritical: 2, StatusStale: 3}
+
+func hostStatus(sum HostSummary, stale bool) string {
+	switch {
+	case stale:
+		return StatusStale
+	case len(sum.Alerts) > 0 && sum.Alerts[0].Severity == metrics.SeverityCritical:
+		return StatusCritical // DetectAlerts sorts critical first
+	case len(sum.Alerts) > 0 || len(sum.Errors) > 0:
+		return StatusWarning
+	default:
+		return StatusOK
+	}
+}
+
+// Latest returns the most recent Result pushed by host.
+func (h *Hub) Latest(host string) (HostMetrics, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hs, ok := h.hosts[host]
+	if !ok {
+		return HostMetrics{}, false
+	}
+	return HostMetrics{
+		Host:      host,
+		Timestamp: hs.latest.CollectedAt,
+		Samples:   hs.latest.Samples,
+		Errors:    hs.latest.Errors,
+	}, true
+}
+
+// HistoryOf returns the kept history of host's series, restricted to metric name if set.
+func (h *Hub) HistoryOf(host, name string) ([]SeriesHistory, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hs, ok := h.hosts[host]
+	if !ok {
+		return nil, false
+	}
+	keys := make([]string, 0, len(hs.series))
+	for key, ser := range hs.series {
+		if name == "" || ser.name == name {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	out := make([]SeriesHistory, 0, len(keys))
+	for _, key := range keys {
+		ser := hs.series[key]
+		sh := SeriesHistory{Name: ser.name, Unit: ser.unit, Labels: ser.labels, Points: make([][2]float64, len(ser.points))}
+		for i, p := range ser.points {
+			sh.Points[i] = [2]float64{float64(p.t.UnixMilli()) / 1000, p.v}
+		}
+		out = append(out, sh)
+	}
+	return out, true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("json encode error: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+)
+
+func newTestHub(t *testing.T) *httptest.Server {
+	t.Helper()
+	h := &Hub{Tokens: map[string]string{"pi-a": "tok-a", "pi-b": "tok-b"}}
+	srv := httptest.NewServer(h.Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func getJSON(t *testing.T, url string, v any) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestPushAuth(t *testing.T) {
+	srv := newTestHub(t)
+	tests := []struct {
+		name, auth string
+		want       int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"unknown token", "Bearer tok-c", http.StatusUnauthorized},
+		{"empty token", "Bearer ", http.StatusUnauthorized},
+		{"token as basic auth", "Basic cGktYTp0b2stYQ==", http.StatusUnauthorized},
+		{"valid token", "Bearer tok-a", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/push", strings.NewReader(`{"results":[]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+		if tt.want == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: 401 without WWW-Authenticate", tt.name)
+		}
+	}
+}
+
+func TestPushBadBody(t *testing.T) {
+	srv := newTestHub(t)
+	tests := []struct {
+		name, encoding, body string
+	}{
+		{"not gzip", "gzip", `{"results":[]}`},
+		{"not JSON", "", "results"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/push", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer tok-a")
+		if tt.encoding != "" {
+			req.Header.Set("Content-Encoding", tt.encoding)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+// TestPushAndRead pushes through a metrics.PushExporter, the way agents do, and
+// reads the result back through the API.
+func TestPushAndRead(t *testing.T) {
+	srv := newTestHub(t)
+	e := &metrics.PushExporter{URL: srv.URL + "/api/push", Token: "tok-a"}
+	now := time.Now().Truncate(time.Second)
+	for i, v := range []float64{48, 51} {
+		res := metrics.Result{Samples: []metrics.Sample{
+			{Name: "cpu_temperature", Value: v, Unit: metrics.UnitCelsius, Timestamp: now.Add(time.Duration(i-1) * time.Minute)},
+			{Name: "storage_used_percent", Value: 40, Unit: metrics.UnitPercent, Timestamp: now, Labels: map[string]string{"mount_point": "/"}},
+		}}
+		if err := e.Export(context.Background(), res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var hosts []HostSummary
+	if code := getJSON(t, srv.URL+"/api/hosts", &hosts); code != http.StatusOK {
+		t.Fatalf("GET /api/hosts: status %d", code)
+	}
+	if len(hosts) != 2 || hosts[0].Host != "pi-b" || hosts[0].Status != StatusStale || hosts[1].Host != "pi-a" || hosts[1].Status != StatusOK {
+		t.Fatalf("hosts = %+v, want pi-b stale then pi-a ok", hosts)
+	}
+	if a := hosts[1]; a.CPUTemperature == nil || *a.CPUTemperature != 51 || a.RootUsedPercent == nil || *a.RootUsedPercent != 40 {
+		t.Errorf("pi-a tile = %+v, want the latest temperature and root usage", a)
+	}
+
+	var latest HostMetrics
+	if code := getJSON(t, srv.URL+"/api/hosts/pi-a/metrics", &latest); code != http.StatusOK {
+		t.Fatalf("GET metrics: status %d", code)
+	}
+	if latest.Host != "pi-a" || len(latest.Samples) != 2 || !latest.Timestamp.Equal(now) {
+		t.Errorf("latest = %+v, want the second push", latest)
+	}
+
+	var hist []SeriesHistory
+	if code := getJSON(t, srv.URL+"/api/hosts/pi-a/history?name=cpu_temperature", &hist); code != http.StatusOK {
+		t.Fatalf("GET history: status %d", code)
+	}
+	if len(hist) != 1 || len(hist[0].Points) != 2 || hist[0].Points[1] != [2]float64{float64(now.Unix()), 51} {
+		t.Errorf("history = %+v, want two cpu_temperature points", hist)
+	}
+	if code := getJSON(t, srv.URL+"/api/hosts/pi-a/history", &hist); code != http.StatusOK || len(hist) != 2 {
+		t.Errorf("unfiltered history: status %d, %d series, want 2", code, len(hist))
+	}
+
+	for _, path := range []string{"/api/hosts/pi-b/metrics", "/api/hosts/pi-c/history"} {
+		if code := getJSON(t, srv.URL+path, nil); code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want 404", path, code)
+		}
+	}
+}
+
+func TestHistoryTrim(t *testing.T) {
+	h := &Hub{Tokens: map[string]string{"pi-a": "tok-a"}, History: 10 * time.Minute}
+	now := time.Now()
+	push := func(name string, at time.Time) {
+		h.record("pi-a", []metrics.PushResult{{CollectedAt: at, Samples: []metrics.Sample{{Name: name, Value: 1, Timestamp: at}}}}, at)
+	}
+	push("gone_metric", now.Add(-20*time.Minute))
+	push("kept_metric", now.Add(-5*time.Minute))
+	push("kept_metric", now)
+
+	hist, _ := h.HistoryOf("pi-a", "")
+	if len(hist) != 1 || hist[0].Name != "kept_metric" || len(hist[0].Points) != 2 {
+		t.Errorf("history = %+v, want only kept_metric with two points", hist)
+	}
+}
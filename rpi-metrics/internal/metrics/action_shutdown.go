@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ShutdownAction is an Exporter that shuts the Pi down cleanly before a UPS HAT or
+// battery runs flat, using the power_supply_* samples of collectors.PowerSupplySysfs.
+//
+// When the board is on battery (a battery reports discharging, or every mains/USB
+// input is offline) and the lowest battery capacity stays below BelowPercent for
+// After, Notify is told that a shutdown is coming. If power doesn't return within
+// Grace, Command is run once. With DryRun the command is only logged.
+//
+// Only a mains/USB input coming online or a battery charging again calls the
+// shutdown off. A cycle without power_supply samples changes nothing, so a failed
+// collection can't cancel a pending shutdown or run the command a second time.
+type ShutdownAction struct {
+	BelowPercent float64
+	After        time.Duration
+	Grace        time.Duration
+	Command      []string
+	DryRun       bool
+
+	// Notify is told before the shutdown and when it is called off. Optional.
+	Notify func(ctx context.Context, msg string) error
+
+	lowSince time.Time
+	noticeAt time.Time
+	done     bool
+
+	now func() time.Time // time.Now; set by tests
+}
+
+// Values of power_supply_status, from the kernel's POWER_SUPPLY_STATUS_* strings.
+const (
+	PowerStatusUnknown     = 0
+	PowerStatusCharging    = 1
+	PowerStatusDischarging = 2
+	PowerStatusNotCharging = 3
+	PowerStatusFull        = 4
+)
+
+const shutdownCommandTimeout = 30 * time.Second
+
+func (a *ShutdownAction) Export(ctx context.Context, res Result) error {
+	now := time.Now()
+	if a.now != nil {
+		now = a.now()
+	}
+	ps, ok := powerState(res.Samples)
+	if !ok {
+		return nil
+	}
+	if ps.powered {
+		if !a.noticeAt.IsZero() && !a.done {
+			a.notify(ctx, fmt.Sprintf("🔌 %sshutdown called off: power is back (battery at %.0f%%)", hostPrefix(res), ps.capacity))
+		}
+		a.lowSince, a.noticeAt, a.done = time.Time{}, time.Time{}, false
+		return nil
+	}
+	capacity := ps.capacity
+	if !ps.onBattery || capacity >= a.BelowPercent || a.done {
+		return nil
+	}
+
+	if a.lowSince.IsZero() {
+		a.lowSince = now
+	}
+	if now.Sub(a.lowSince) < a.After {
+		return nil
+	}
+
+	if a.noticeAt.IsZero() {
+		a.noticeAt = now
+		a.notify(ctx, fmt.Sprintf("🪫 %son battery at %.0f%% for %s: shutting down in %s (`%s`)%s",
+			hostPrefix(res), capacity, now.Sub(a.lowSince).Round(time.Second), a.Grace,
+			strings.Join(a.Command, " "), dryRunSuffix(a.DryRun)))
+	}
+	if now.Sub(a.noticeAt) < a.Grace {
+		return nil
+	}
+
+	a.done = true
+	if a.DryRun {
+		log.Printf("shutdown dry run: would run %q (battery at %.0f%%)", strings.Join(a.Command, " "), capacity)
+		return nil
+	}
+	return a.run(ctx)
+}
+
+func (a *ShutdownAction) run(ctx context.Context) error {
+	if len(a.Command) == 0 {
+		return fmt.Errorf("shutdown command is empty")
+	}
+	ctx, cancel := context.WithTimeout(ctx, shutdownCommandTimeout)
+	defer cancel()
+
+	log.Printf("battery low: running %q", strings.Join(a.Command, " "))
+	out, err := exec.CommandContext(ctx, a.Command[0], a.Command[1:]...).CombinedOutput()
+	if err != nil {
+		a.done = false // try again next cycle
+		return fmt.Errorf("run shutdown command: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+func (a *ShutdownAction) notify(ctx context.Context, msg string) {
+	log.Print(msg)
+	if a.Notify == nil {
+		return
+	}
+	if err := a.Notify(ctx, msg); err != nil {
+		log.Printf("shutdown notify error: %v", err)
+	}
+}
+
+type powerSupplies struct {
+	onBattery bool    // a battery discharges, or every mains/USB input is offline
+	powered   bool    // not onBattery, and an input is online or a battery charges
+	capacity  float64 // lowest battery capacity
+}
+
+// powerState sums up the power_supply samples of one cycle. ok is false when no
+// supply reports a capacity.
+func powerState(samples []Sample) (ps powerSupplies, ok bool) {
+	ps.capacity = math.Inf(1)
+	inputs, inputsOnline, charging := 0, 0, false
+	for _, s := range samples {
+		switch s.Name {
+		case "power_supply_capacity_percent":
+			ps.capacity = math.Min(ps.capacity, s.Value)
+			ok = true
+		case "power_supply_status":
+			switch s.Value {
+			case PowerStatusDischarging:
+				ps.onBattery = true
+			case PowerStatusCharging, PowerStatusFull:
+				charging = true
+			}
+		case "power_supply_online":
+			if t := s.Labels["type"]; t == "Mains" || strings.HasPrefix(t, "USB") {
+				inputs++
+				if s.Value != 0 {
+					inputsOnline++
+				}
+			}
+		}
+	}
+	if inputs > 0 && inputsOnline == 0 {
+		ps.onBattery = true
+	}
+	ps.powered = !ps.onBattery && (inputsOnline > 0 || charging)
+	if !ok {
+		ps.capacity = 0
+	}
+	return ps, ok
+}
+
+func hostPrefix(res Result) string {
+	for _, s := range res.Samples {
+		if h := s.Labels["host"]; h != "" {
+			return h + ": "
+		}
+	}
+	return ""
+}
+
+func dryRunSuffix(dryRun bool) string {
+	if dryRun {
+		return " [dry run]"
+	}
+	return ""
+}
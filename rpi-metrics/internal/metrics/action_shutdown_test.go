@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// power returns the samples of a UPS HAT with one USB input.
+func power(inputOnline bool, status, capacity float64) Result {
+	online := 0.0
+	if inputOnline {
+		online = 1
+	}
+	return Result{Samples: []Sample{
+		{Name: "power_supply_online", Value: online, Labels: map[string]string{"supply": "usb", "type": "USB", "host": "pi"}},
+		{Name: "power_supply_status", Value: status, Labels: map[string]string{"supply": "ups", "type": "Battery", "host": "pi"}},
+		{Name: "power_supply_capacity_percent", Value: capacity, Labels: map[string]string{"supply": "ups", "type": "Battery", "host": "pi"}},
+	}}
+}
+
+func TestShutdownAction(t *testing.T) {
+	var (
+		onBattery = func(capacity float64) Result { return power(false, PowerStatusDischarging, capacity) }
+		charging  = power(true, PowerStatusCharging, 8)
+		noSamples = Result{Samples: []Sample{{Name: "cpu_temp_celsius", Value: 50}}}
+		// Input offline but the battery doesn't say: still on battery.
+		noStatus = Result{Samples: []Sample{
+			{Name: "power_supply_online", Value: 0, Labels: map[string]string{"type": "Mains"}},
+			{Name: "power_supply_capacity_percent", Value: 5},
+		}}
+		// A battery that is neither charging nor discharging, and no input: no evidence either way.
+		notCharging = Result{Samples: []Sample{
+			{Name: "power_supply_status", Value: PowerStatusNotCharging},
+			{Name: "power_supply_capacity_percent", Value: 5},
+		}}
+	)
+	const (
+		notice    = "🪫 pi: on battery at "
+		calledOff = "🔌 pi: shutdown called off: power is back"
+	)
+	type step struct {
+		at      time.Duration // since the start
+		res     Result
+		notices []string // prefixes
+		runs    int      // command runs so far
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"shuts down once after After and Grace", []step{
+			{0, onBattery(8), nil, 0},
+			{time.Minute, onBattery(7), nil, 0},
+			{2 * time.Minute, onBattery(7), []string{notice + "7% for 2m0s: shutting down in 1m0s"}, 0},
+			{3 * time.Minute, onBattery(6), nil, 1},
+			{4 * time.Minute, onBattery(5), nil, 1},
+		}},
+		{"above the threshold does nothing", []step{
+			{0, onBattery(50), nil, 0},
+			{5 * time.Minute, onBattery(50), nil, 0},
+		}},
+		{"power back before the notice is silent", []step{
+			{0, onBattery(8), nil, 0},
+			{time.Minute, charging, nil, 0},
+			{2 * time.Minute, onBattery(8), nil, 0},
+			{3 * time.Minute, onBattery(8), nil, 0},
+		}},
+		{"power back during the grace period calls it off", []step{
+			{0, onBattery(8), nil, 0},
+			{2 * time.Minute, onBattery(8), []string{notice}, 0},
+			{150 * time.Second, charging, []string{calledOff + " (battery at 8%)"}, 0},
+			{4 * time.Minute, onBattery(8), nil, 0},
+		}},
+		{"mains online with a full battery calls it off", []step{
+			{0, onBattery(8), nil, 0},
+			{2 * time.Minute, onBattery(8), []string{notice}, 0},
+			{150 * time.Second, power(true, PowerStatusFull, 8), []string{calledOff}, 0},
+		}},
+		{"missing samples keep a pending shutdown", []step{
+			{0, onBattery(8), nil, 0},
+			{2 * time.Minute, onBattery(8), []string{notice}, 0},
+			{150 * time.Second, noSamples, nil, 0},
+			{3 * time.Minute, onBattery(8), nil, 1},
+		}},
+		{"missing samples after the shutdown don't run it again", []step{
+			{0, onBattery(8), nil, 0},
+			{2 * time.Minute, onBattery(8), []string{notice}, 0},
+			{3 * time.Minute, onBattery(8), nil, 1},
+			{4 * time.Minute, noSamples, nil, 1},
+			{5 * time.Minute, onBattery(8), nil, 1},
+		}},
+		{"missing samples keep counting towards After", []step{
+			{0, onBattery(8), nil, 0},
+			{time.Minute, noSamples, nil, 0},
+			{2 * time.Minute, onBattery(8), []string{notice + "8% for 2m0s"}, 0},
+		}},
+		{"every input offline is on battery", []step{
+			{0, noStatus, nil, 0},
+			{2 * time.Minute, noStatus, []string{"🪫 on battery at 5%"}, 0},
+		}},
+		{"no evidence either way keeps the state", []step{
+			{0, onBattery(8), nil, 0},
+			{2 * time.Minute, onBattery(8), []string{notice}, 0},
+			{150 * time.Second, notCharging, nil, 0},
+			{3 * time.Minute, onBattery(8), nil, 1},
+		}},
+		{"an input online while the battery discharges is still on battery", []step{
+			{0, power(true, PowerStatusDischarging, 8), nil, 0},
+			{2 * time.Minute, power(true, PowerStatusDischarging, 8), []string{notice}, 0},
+			{3 * time.Minute, power(true, PowerStatusDischarging, 8), nil, 1},
+		}},
+	}
+	for _, tt := range tests {
+		runs := filepath.Join(t.TempDir(), "runs")
+		start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+		var now time.Time
+		var got []string
+		a := &ShutdownAction{
+			BelowPercent: 10,
+			After:        2 * time.Minute,
+			Grace:        time.Minute,
+			Command:      []string{"sh", "-c", "echo run >> " + runs},
+			Notify: func(_ context.Context, msg string) error {
+				got = append(got, msg)
+				return nil
+			},
+			now: func() time.Time { return now },
+		}
+		for i, s := range tt.steps {
+			now = start.Add(s.at)
+			got = nil
+			if err := a.Export(context.Background(), s.res); err != nil {
+				t.Fatalf("%s: step %d: %v", tt.name, i, err)
+			}
+			if len(got) != len(s.notices) {
+				t.Errorf("%s: step %d: notices = %q, want %d", tt.name, i, got, len(s.notices))
+			} else {
+				for j, want := range s.notices {
+					if !strings.HasPrefix(got[j], want) {
+						t.Errorf("%s: step %d: notice %q, want prefix %q", tt.name, i, got[j], want)
+					}
+				}
+			}
+			out, _ := os.ReadFile(runs)
+			if n := strings.Count(string(out), "run\n"); n != s.runs {
+				t.Errorf("%s: step %d: command ran %d times, want %d", tt.name, i, n, s.runs)
+			}
+		}
+	}
+}
+
+func TestShutdownActionDryRun(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	var got []string
+	a := &ShutdownAction{
+		BelowPercent: 10,
+		Grace:        time.Minute,
+		Command:      []string{"false"},
+		DryRun:       true,
+		Notify: func(_ context.Context, msg string) error {
+			got = append(got, msg)
+			return nil
+		},
+		now: func() time.Time { return now },
+	}
+	low := power(false, PowerStatusDischarging, 3)
+	for i := 0; i < 3; i++ {
+		if err := a.Export(context.Background(), low); err != nil {
+			t.Fatalf("export %d: %v", i, err)
+		}
+		now = now.Add(time.Minute)
+	}
+	if len(got) != 1 || !strings.HasSuffix(got[0], "(`false`) [dry run]") || !a.done {
+		t.Errorf("notices = %q, done = %v; want one dry-run notice and done", got, a.done)
+	}
+}
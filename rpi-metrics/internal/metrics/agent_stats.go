@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AgentStats records the agent's own collection and export activity so it can be
+// reported through the normal pipeline (see collectors.AgentSelf).
+type AgentStats struct {
+	mu         sync.Mutex
+	collectors map[string]*CollectorStats
+	exporters  map[string]*ExporterStats
+	configs    ConfigLoadStats
+}
+
+type CollectorStats struct {
+	ID           string
+	LastDuration time.Duration
+	Runs         uint64
+	Errors       uint64
+}
+
+type ExporterStats struct {
+	Name         string
+	LastDuration time.Duration
+	Successes    uint64
+	Failures     uint64
+	QueueDepth   int // -1 when the exporter has no queue
+}
+
+// ConfigLoadStats counts loads of the agent's config: the one at startup and reloads.
+type ConfigLoadStats struct {
+	Successes   uint64
+	Failures    uint64
+	LastSuccess time.Time
+	LastFailed  bool
+}
+
+// QueueDepther is implemented by exporters that buffer Results before sending them.
+type QueueDepther interface {
+	QueueDepth() int
+}
+
+func (s *AgentStats) ObserveCollect(id string, d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.collectors == nil {
+		s.collectors = make(map[string]*CollectorStats)
+	}
+	cs, ok := s.collectors[id]
+	if !ok {
+		cs = &CollectorStats{ID: id}
+		s.collectors[id] = cs
+	}
+	cs.LastDuration = d
+	cs.Runs++
+	if err != nil {
+		cs.Errors++
+	}
+}
+
+func (s *AgentStats) ObserveExport(name string, d time.Duration, err error, queueDepth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.exporters == nil {
+		s.exporters = make(map[string]*ExporterStats)
+	}
+	es, ok := s.exporters[name]
+	if !ok {
+		es = &ExporterStats{Name: name}
+		s.exporters[name] = es
+	}
+	es.LastDuration = d
+	es.QueueDepth = queueDepth
+	if err != nil {
+		es.Failures++
+	} else {
+		es.Successes++
+	}
+}
+
+// ObserveConfigLoad records a config load; err is why it was rejected.
+func (s *AgentStats) ObserveConfigLoad(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.configs.LastFailed = err != nil
+	if err != nil {
+		s.configs.Failures++
+		return
+	}
+	s.configs.Successes++
+	s.configs.LastSuccess = time.Now()
+}
+
+// ConfigLoads returns the config load stats.
+func (s *AgentStats) ConfigLoads() ConfigLoadStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.configs
+}
+
+// Snapshot returns copies of the current stats, sorted by collector ID / exporter name.
+func (s *AgentStats) Snapshot() ([]CollectorStats, []ExporterStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cs := make([]CollectorStats, 0, len(s.collectors))
+	for _, c := range s.collectors {
+		cs = append(cs, *c)
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].ID < cs[j].ID })
+
+	es := make([]ExporterStats, 0, len(s.exporters))
+	for _, e := range s.exporters {
+		es = append(es, *e)
+	}
+	sort.Slice(es, func(i, j int) bool { return es[i].Name < es[j].Name })
+
+	return cs, es
+}
+
+// InstrumentedExporter records duration, outcome and queue depth of every export.
+type InstrumentedExporter struct {
+	Name  string
+	Next  Exporter
+	Stats *AgentStats
+}
+
+func (e InstrumentedExporter) Export(ctx context.Context, res Result) error {
+	start := time.Now()
+	err := e.Next.Export(ctx, res)
+
+	depth := -1
+	if q, ok := e.Next.(QueueDepther); ok {
+		depth = q.QueueDepth()
+	}
+	e.Stats.ObserveExport(e.Name, time.Since(start), err, depth)
+	return err
+}
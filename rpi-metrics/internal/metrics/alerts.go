@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"rpi-metrics/constants"
+)
+
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Alert is a condition derived from a Result that needs someone's attention.
+type Alert struct {
+	Name     string            `json:"name"`
+	Severity string            `json:"severity"`
+	Message  string            `json:"message"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// DetectAlerts evaluates the built-in alert conditions against a Result.
+// Alerts are returned critical first, then by name.
+func DetectAlerts(res Result) []Alert {
+	var out []Alert
+	for _, s := range res.Samples {
+		switch s.Name {
+		case "mmc_pre_eol_info":
+			// 0x02: 80% of reserved blocks consumed, 0x03: urgent (90%).
+			switch {
+			case s.Value >= 3:
+				out = append(out, Alert{
+					Name:     "mmc_pre_eol",
+					Severity: SeverityCritical,
+					Message:  fmt.Sprintf("%s reports pre-EOL urgent: reserved blocks nearly exhausted, replace the card", s.Labels["device"]),
+					Labels:   s.Labels,
+				})
+			case s.Value == 2:
+				out = append(out, Alert{
+					Name:     "mmc_pre_eol",
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("%s reports pre-EOL warning: 80%% of reserved blocks used", s.Labels["device"]),
+					Labels:   s.Labels,
+				})
+			}
+		case "storage_predicted_full_seconds":
+			left := time.Duration(s.Value * float64(time.Second))
+			if left < constants.DefaultStorageFullAlertHorizon {
+				mount := s.Labels["mount_point"]
+				if mount == "" {
+					mount = s.Labels["path"]
+				}
+				out = append(out, Alert{
+					Name:     "storage_filling_up",
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("%s will be full in %s at the current rate", mount, humanizeDuration(left)),
+					Labels:   s.Labels,
+				})
+			}
+		case "mmc_life_time_used_percent":
+			if s.Value >= 90 {
+				out = append(out, Alert{
+					Name:     "mmc_life_time",
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("%s type %s life time estimate at %.0f%%", s.Labels["device"], s.Labels["type"], s.Value),
+					Labels:   s.Labels,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Severity != out[j].Severity {
+			return out[i].Severity == SeverityCritical
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// AlertKey identifies an alert across cycles: its name and labels, not its message,
+// which carries the current value.
+func AlertKey(a Alert) string {
+	return SeriesKey(a.Name, a.Labels)
+}
+
+// AlertChanges compares alerts with the ones active before (by AlertKey). It returns
+// the newly raised alerts, the resolved ones (sorted by message) and the now active
+// ones, which the caller passes as prev next time.
+func AlertChanges(prev map[string]Alert, alerts []Alert) (raised, resolved []Alert, active map[string]Alert) {
+	active = make(map[string]Alert, len(alerts))
+	for _, a := range alerts {
+		key := AlertKey(a)
+		active[key] = a
+		if _, ok := prev[key]; !ok {
+			raised = append(raised, a)
+		}
+	}
+	for key, a := range prev {
+		if _, ok := active[key]; !ok {
+			resolved = append(resolved, a)
+		}
+	}
+	sort.Slice(resolved, func(i, j int) bool { return resolved[i].Message < resolved[j].Message })
+	return raised, resolved, active
+}
+
+// humanizeDuration renders a coarse, human-friendly duration such as "~3 days".
+func humanizeDuration(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("~%d minutes", int(d.Round(time.Minute)/time.Minute))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("~%d hours", int(d.Round(time.Hour)/time.Hour))
+	default:
+		return fmt.Sprintf("~%d days", int((d+12*time.Hour)/(24*time.Hour)))
+	}
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// CPUTimeCounter is the counter collectors.CPUUtilizationProcfs reports: seconds of
+// CPU time per cpu label, split into mode="busy" and mode="idle".
+const CPUTimeCounter = "cpu_time_seconds_total"
+
+// CPUUtilization is a Stage that derives cpu_utilization from the rates RateStage
+// computes for CPUTimeCounter: busy over busy plus idle time since the previous cycle.
+// It must run after a RateStage that covers CPUTimeCounter.
+type CPUUtilization struct{}
+
+func init() {
+	MustRegisterDescriptors(Descriptor{
+		Name:   "cpu_utilization",
+		Type:   TypeGauge,
+		Help:   "Share of CPU time spent non-idle since the previous collection, overall (cpu=total) and per core.",
+		Unit:   UnitPercent,
+		Labels: []string{"source", "path", "cpu"},
+		Range:  &Range{Min: 0, Max: 100},
+	})
+}
+
+func (CPUUtilization) Process(res Result) Result {
+	type cpuTime struct {
+		labels     map[string]string
+		busy, idle float64
+		seen       int
+		ts         time.Time
+	}
+	rate := rateName(CPUTimeCounter)
+	byCPU := make(map[string]*cpuTime)
+	for _, s := range res.Samples {
+		if s.Name != rate {
+			continue
+		}
+		labels := make(map[string]string, len(s.Labels))
+		for k, v := range s.Labels {
+			if k != "mode" {
+				labels[k] = v
+			}
+		}
+		key := SeriesKey("", labels)
+		ct := byCPU[key]
+		if ct == nil {
+			ct = &cpuTime{labels: labels, ts: s.Timestamp}
+			byCPU[key] = ct
+		}
+		switch s.Labels["mode"] {
+		case "busy":
+			ct.busy = s.Value
+			ct.seen++
+		case "idle":
+			ct.idle = s.Value
+			ct.seen++
+		}
+	}
+
+	keys := make([]string, 0, len(byCPU))
+	for k := range byCPU {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		ct := byCPU[k]
+		total := ct.busy + ct.idle
+		if ct.seen != 2 || total <= 0 {
+			continue
+		}
+		res.Samples = append(res.Samples, Sample{
+			Name:      "cpu_utilization",
+			Value:     min(max(ct.busy/total*100, 0), 100),
+			Unit:      UnitPercent,
+			Timestamp: ct.ts,
+			Labels:    ct.labels,
+		})
+	}
+	return res
+}
@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type MetricType string
+
+const (
+	TypeGauge    MetricType = "gauge"
+	TypeCounter  MetricType = "counter"
+	TypeStateSet MetricType = "stateset" // small set of numbered states, e.g. fan level 0-4
+)
+
+// Canonical units. Sample.Unit should be one of these so exporters can format values
+// without guessing from metric names.
+const (
+	UnitNone           = ""
+	UnitCelsius        = "celsius"
+	UnitPercent        = "percent"
+	UnitBytes          = "bytes"
+	UnitSeconds        = "seconds"   // a duration
+	UnitTimestamp      = "timestamp" // seconds since the Unix epoch
+	UnitCount          = "count"
+	UnitBool           = "bool" // 0 or 1
+	UnitState          = "state"
+	UnitVolts          = "volts"
+	UnitAmperes        = "amperes"
+	UnitDBm            = "dBm"
+	UnitBitsPerSecond  = "bits_per_second"
+	UnitBytesPerSecond = "bytes_per_second"
+	UnitPerSecond      = "per_second" // events per second, e.g. a rate of a count
+)
+
+// Descriptor documents a metric: what it means, how to interpret and format its value
+// and which labels it carries.
+type Descriptor struct {
+	Name   string     `json:"name"`
+	Type   MetricType `json:"type"`
+	Help   string     `json:"help"`
+	Unit   string     `json:"unit,omitempty"`
+	Labels []string   `json:"labels,omitempty"` // expected label names; nil skips the label check
+	Range  *Range     `json:"range,omitempty"`  // expected value range; nil skips the range check
+}
+
+// Range is an inclusive value range.
+type Range struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+var (
+	descMu      sync.RWMutex
+	descriptors = make(map[string]Descriptor)
+
+	// derivedRates are the rate descriptors registered for counters, which an
+	// explicit registration of the same name replaces.
+	derivedRates = make(map[string]bool)
+)
+
+// RegisterDescriptors adds descriptors to the global registry. Registering the same
+// name twice with a different definition is an error.
+func RegisterDescriptors(ds ...Descriptor) error {
+	descMu.Lock()
+	defer descMu.Unlock()
+
+	for _, d := range ds {
+		if d.Name == "" {
+			return fmt.Errorf("descriptor name cannot be empty")
+		}
+		if existing, ok := descriptors[d.Name]; ok && !derivedRates[d.Name] && !sameDescriptor(existing, d) {
+			return fmt.Errorf("descriptor already registered with a different definition: %s", d.Name)
+		}
+		descriptors[d.Name] = d
+		delete(derivedRates, d.Name)
+
+		// Document the rate RateStage derives from a counter up front, so list-metrics
+		// and /api/descriptors show it. A collector may register the name itself; its
+		// definition wins, whichever comes first.
+		if d.Type == TypeCounter {
+			r := rateDescriptor(d)
+			if _, ok := descriptors[r.Name]; !ok {
+				descriptors[r.Name] = r
+				derivedRates[r.Name] = true
+			}
+		}
+	}
+	return nil
+}
+
+// MustRegisterDescriptors is RegisterDescriptors for package init functions.
+func MustRegisterDescriptors(ds ...Descriptor) {
+	if err := RegisterDescriptors(ds...); err != nil {
+		panic(err)
+	}
+}
+
+// LookupDescriptor returns the descriptor registered for a metric name.
+func LookupDescriptor(name string) (Descriptor, bool) {
+	descMu.RLock()
+	defer descMu.RUnlock()
+
+	d, ok := descriptors[name]
+	return d, ok
+}
+
+// Descriptors returns every registered descriptor, sorted by name.
+func Descriptors() []Descriptor {
+	descMu.RLock()
+	defer descMu.RUnlock()
+
+	out := make([]Descriptor, 0, len(descriptors))
+	for _, d := range descriptors {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// CheckSample reports how a sample violates its descriptor, or "" when it conforms
+// or has no descriptor (e.g. exec/textfile metrics).
+func CheckSample(s Sample) string {
+	d, ok := LookupDescriptor(s.Name)
+	if !ok {
+		return ""
+	}
+	if s.Unit != d.Unit {
+		return fmt.Sprintf("%s: unit %q, descriptor declares %q", s.Name, s.Unit, d.Unit)
+	}
+	if d.Range != nil && (s.Value < d.Range.Min || s.Value > d.Range.Max) {
+		return fmt.Sprintf("%s: value %g outside expected range [%g, %g]", s.Name, s.Value, d.Range.Min, d.Range.Max)
+	}
+	if d.Labels != nil {
+		for k := range s.Labels {
+			if !containsString(d.Labels, k) {
+				return fmt.Sprintf("%s: unexpected label %q", s.Name, k)
+			}
+		}
+	}
+	return ""
+}
+
+func sameDescriptor(a, b Descriptor) bool {
+	if a.Name != b.Name || a.Type != b.Type || a.Help != b.Help || a.Unit != b.Unit {
+		return false
+	}
+	if (a.Range == nil) != (b.Range == nil) || (a.Range != nil && *a.Range != *b.Range) {
+		return false
+	}
+	if len(a.Labels) != len(b.Labels) {
+		return false
+	}
+	for i := range a.Labels {
+		if a.Labels[i] != b.Labels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func init() {
+	MustRegisterDescriptors(Descriptor{
+		Name:   "test_level",
+		Type:   TypeStateSet,
+		Help:   "Test state, 0-4.\nSecond line with a \\ backslash.",
+		Unit:   UnitState,
+		Labels: []string{"dev"},
+		Range:  &Range{Min: 0, Max: 4},
+	}, Descriptor{
+		Name: "test_ratio",
+		Type: TypeGauge,
+		Help: "Test gauge with any labels.",
+		Unit: UnitPercent,
+	})
+}
+
+func TestCheckSample(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample Sample
+		want   string // substring of the violation; "" conforms
+	}{
+		{"conforms", Sample{Name: "test_level", Value: 2, Unit: UnitState, Labels: map[string]string{"dev": "a"}}, ""},
+		{"range is inclusive", Sample{Name: "test_level", Value: 4, Unit: UnitState}, ""},
+		{"wrong unit", Sample{Name: "test_level", Value: 2, Unit: UnitCount}, `unit "count", descriptor declares "state"`},
+		{"missing unit", Sample{Name: "test_level", Value: 2}, `unit "", descriptor declares "state"`},
+		{"below range", Sample{Name: "test_level", Value: -1, Unit: UnitState}, "value -1 outside expected range [0, 4]"},
+		{"above range", Sample{Name: "test_level", Value: 4.5, Unit: UnitState}, "outside expected range"},
+		{"unexpected label", Sample{Name: "test_level", Value: 1, Unit: UnitState, Labels: map[string]string{"dev": "a", "zone": "b"}}, `unexpected label "zone"`},
+		// Neither Labels nor Range: any labels and values.
+		{"any labels", Sample{Name: "test_ratio", Value: 150, Unit: UnitPercent, Labels: map[string]string{"whatever": "x"}}, ""},
+		{"no descriptor", Sample{Name: "backup_ok", Value: 1e9, Unit: "anything"}, ""},
+	}
+	for _, tt := range tests {
+		got := CheckSample(tt.sample)
+		if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("%s: CheckSample = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterDescriptors(t *testing.T) {
+	d := Descriptor{Name: "test_registered_total", Type: TypeCounter, Help: "Test counter.", Unit: UnitBytes}
+	if err := RegisterDescriptors(d); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterDescriptors(d); err != nil {
+		t.Errorf("registering the same definition twice: %v", err)
+	}
+	changed := d
+	changed.Help = "Something else."
+	if err := RegisterDescriptors(changed); err == nil {
+		t.Error("registering a different definition succeeded")
+	}
+	if err := RegisterDescriptors(Descriptor{Type: TypeGauge}); err == nil {
+		t.Error("registering a descriptor without a name succeeded")
+	}
+
+	rate, ok := LookupDescriptor("test_registered_per_second")
+	if !ok || rate.Type != TypeGauge || rate.Unit != UnitBytesPerSecond {
+		t.Errorf("derived rate descriptor = %+v, %v; want a bytes_per_second gauge", rate, ok)
+	}
+	// An explicit registration replaces the derived one.
+	explicit := Descriptor{Name: "test_registered_per_second", Type: TypeGauge, Help: "Explicit.", Unit: UnitBytesPerSecond}
+	if err := RegisterDescriptors(explicit); err != nil {
+		t.Fatalf("replacing the derived rate: %v", err)
+	}
+	if got, _ := LookupDescriptor("test_registered_per_second"); got.Help != "Explicit." {
+		t.Errorf("rate descriptor = %+v, want the explicit one", got)
+	}
+	if err := RegisterDescriptors(Descriptor{Name: "test_registered_per_second", Type: TypeGauge, Help: "Third."}); err == nil {
+		t.Error("replacing an explicit registration succeeded")
+	}
+}
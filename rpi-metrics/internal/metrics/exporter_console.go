@@ -11,7 +11,10 @@ type ConsoleExporter struct {
 	Out io.Writer
 }
 
-type consoleEnvelope struct {
+// ConsoleEnvelope is one line of the console exporter's JSON Lines output.
+type ConsoleEnvelope struct {
+	// CollectedAt is the newest sample timestamp (the time of export when there are
+	// no samples), so a recording replays with its own times.
 	CollectedAt time.Time        `json:"collected_at"`
 	Samples     []Sample         `json:"samples"`
 	Errors      []CollectorError `json:"errors,omitempty"`
@@ -19,8 +22,8 @@ type consoleEnvelope struct {
 
 func (e ConsoleExporter) Export(ctx context.Context, res Result) error {
 	_ = ctx
-	env := consoleEnvelope{
-		CollectedAt: time.Now().UTC(),
+	env := ConsoleEnvelope{
+		CollectedAt: collectedAt(res),
 		Samples:     res.Samples,
 		Errors:      res.Errors,
 	}
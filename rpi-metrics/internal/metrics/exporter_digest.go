@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"rpi-metrics/internal/schedule"
+)
+
+// digestRetryAfter is how long a digest that failed to post waits before it is tried
+// again (the notifier's retries are spent by then).
+const digestRetryAfter = 10 * time.Minute
+
+// DigestExporter summarizes every Result and posts the summary of the elapsed window
+// (see Summary) through Notify each time Schedule fires, instead of raw snapshots.
+// The first digest covers the time since the agent started. A digest that fails is
+// tried again digestRetryAfter later and still covers the whole window.
+type DigestExporter struct {
+	Schedule schedule.Schedule
+	Notify   func(ctx context.Context, msg string) error
+
+	// SendSummary, when set, is called instead of Notify with the Summary itself, for
+	// notifiers that lay it out on their own (email's HTML part).
+	SendSummary func(ctx context.Context, s *Summary) error
+
+	summary Summary
+	next    time.Time
+}
+
+func (e *DigestExporter) Export(ctx context.Context, res Result) error {
+	e.summary.Add(res)
+	now := time.Now()
+	if e.next.IsZero() {
+		e.next = e.Schedule.Next(now)
+	}
+	if e.next.IsZero() || now.Before(e.next) || (e.Notify == nil && e.SendSummary == nil) {
+		return nil
+	}
+	if err := e.send(ctx); err != nil {
+		e.next = now.Add(digestRetryAfter)
+		return err
+	}
+	e.summary.Reset()
+	e.next = e.Schedule.Next(now)
+	return nil
+}
+
+func (e *DigestExporter) send(ctx context.Context) error {
+	if e.SendSummary != nil {
+		return e.SendSummary(ctx, &e.summary)
+	}
+	return e.Notify(ctx, FormatSummary(&e.summary))
+}
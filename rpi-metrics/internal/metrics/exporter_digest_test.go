@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"rpi-metrics/internal/schedule"
+)
+
+func TestDigestExporter(t *testing.T) {
+	s, err := schedule.Parse("every day 08:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent []string
+	fail := true
+	e := &DigestExporter{Schedule: s, Notify: func(_ context.Context, msg string) error {
+		if fail {
+			return errors.New("discord down")
+		}
+		sent = append(sent, msg)
+		return nil
+	}}
+	res := Result{Samples: []Sample{{Name: "cpu_temp_celsius", Value: 50, Unit: UnitCelsius, Timestamp: time.Now()}}}
+	ctx := context.Background()
+
+	if err := e.Export(ctx, res); err != nil || len(sent) != 0 {
+		t.Fatalf("before the schedule fires: err %v, sent %q", err, sent)
+	}
+	if want := s.Next(time.Now()); !e.next.Equal(want) {
+		t.Errorf("next = %s, want %s", e.next, want)
+	}
+
+	// Due, but the post fails: retried later, and the window keeps growing.
+	e.next = time.Now().Add(-time.Second)
+	if err := e.Export(ctx, res); err == nil {
+		t.Fatal("a failed post returned no error")
+	}
+	if d := time.Until(e.next); d < digestRetryAfter-time.Minute || d > digestRetryAfter {
+		t.Errorf("retry in %s, want %s", d, digestRetryAfter)
+	}
+	if e.summary.Cycles != 2 {
+		t.Errorf("summary has %d cycles after a failed post, want 2", e.summary.Cycles)
+	}
+
+	fail = false
+	e.next = time.Now().Add(-time.Second)
+	if err := e.Export(ctx, res); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || !strings.Contains(sent[0], "cpu_temp_celsius") {
+		t.Errorf("sent %q, want one digest covering cpu_temp_celsius", sent)
+	}
+	if e.summary.Cycles != 0 || !e.next.After(time.Now()) {
+		t.Errorf("after the digest: %d cycles, next %s; want a new window", e.summary.Cycles, e.next)
+	}
+
+	// SendSummary takes over from Notify.
+	var cycles int
+	e.SendSummary = func(_ context.Context, s *Summary) error {
+		cycles = s.Cycles
+		return nil
+	}
+	e.next = time.Now().Add(-time.Second)
+	if err := e.Export(ctx, res); err != nil {
+		t.Fatal(err)
+	}
+	if cycles != 1 || len(sent) != 1 {
+		t.Errorf("SendSummary got %d cycles, Notify sent %d digests; want 1 and still 1", cycles, len(sent))
+	}
+}
@@ -5,13 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strings"
 	"time"
 
 	"rpi-metrics/constants"
+	"rpi-metrics/internal/secret"
 )
 
 type DiscordWebhookExporter struct {
@@ -21,6 +21,9 @@ type DiscordWebhookExporter struct {
 	// If > 0, limits how often we post (prevents spam/rate-limit issues)
 	MinInterval time.Duration
 	lastSent    time.Time
+
+	// Retry retries failed posts.
+	Retry Retry
 }
 
 type discordWebhookPayload struct {
@@ -32,47 +35,86 @@ func (e *DiscordWebhookExporter) Export(ctx context.Context, res Result) error {
 		return fmt.Errorf("discord webhook url is empty")
 	}
 
-	if e.Client == nil {
-		e.Client = &http.Client{Timeout: 10 * time.Second}
-	}
-
 	now := time.Now()
-	if e.MinInterval > 0 && !e.lastSent.IsZero() && now.Sub(e.lastSent) < e.MinInterval {
+	if throttled(e.lastSent, e.MinInterval, now) {
 		return nil // too soon; skip
 	}
 
-	msg := formatDiscordMessage(res)
-
-	bodyBytes, err := json.Marshal(discordWebhookPayload{Content: msg})
-	if err != nil {
-		return fmt.Errorf("marshal discord payload: %w", err)
+	if err := e.post(ctx, FormatMessage(res)); err != nil {
+		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.WebhookURL, bytes.NewReader(bodyBytes))
-	if err != nil {
-		return fmt.Errorf("new request: %w", err)
+	e.lastSent = now
+	return nil
+}
+
+// Notify posts a plain message immediately, ignoring MinInterval. It is used for
+// one-off notices (a Pi went quiet, a shutdown is about to happen).
+func (e *DiscordWebhookExporter) Notify(ctx context.Context, msg string) error {
+	if e.WebhookURL == "" {
+		return fmt.Errorf("discord webhook url is empty")
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return e.post(ctx, msg)
+}
+
+// post sends content, split into several messages at line boundaries if it exceeds
+// Discord's per-message limit.
+func (e *DiscordWebhookExporter) post(ctx context.Context, content string) error {
+	for _, msg := range SplitMessage(content, constants.DiscordMessageMaxLen) {
+		bodyBytes, err := json.Marshal(discordWebhookPayload{Content: msg})
+		if err != nil {
+			return fmt.Errorf("marshal discord payload: %w", err)
+		}
 
-	resp, err := e.Client.Do(req)
-	if err != nil {
-		return fmt.Errorf("post to discord webhook: %w", err)
+		err = e.Retry.Do(ctx, func() error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.WebhookURL, bytes.NewReader(bodyBytes))
+			if err != nil {
+				return fmt.Errorf("new request: %w", secret.StripURL(err))
+			}
+			req.Header.Set("Content-Type", "application/json")
+			return Send(httpClient(e.Client), "discord webhook", req)
+		})
+		if err != nil {
+			return err
+		}
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
-		if len(b) > 0 {
-			return fmt.Errorf("discord webhook returned status %d: %s", resp.StatusCode, string(b))
+// SplitMessage splits content into chunks of at most max characters (runes, which is
+// how Discord and Telegram count), breaking between lines where possible and never
+// inside a multi-byte character.
+func SplitMessage(content string, max int) []string {
+	var out []string
+	for {
+		limit := runeOffset(content, max)
+		if limit == len(content) {
+			return append(out, content)
 		}
-		return fmt.Errorf("discord webhook returned status %d", resp.StatusCode)
+		cut := strings.LastIndex(content[:limit], "\n")
+		if cut <= 0 {
+			cut = limit
+		}
+		out = append(out, content[:cut])
+		content = strings.TrimPrefix(content[cut:], "\n")
 	}
+}
 
-	e.lastSent = now
-	return nil
+// runeOffset returns the byte offset of the n-th rune of s, or len(s) when s has at
+// most n runes.
+func runeOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
 }
 
-func formatDiscordMessage(res Result) string {
+// FormatMessage renders a Result as the text of a Discord post: alerts, the CPU and
+// SD card blocks, one line per other sample, then collector errors.
+func FormatMessage(res Result) string {
 	collectedAt := time.Now().UTC()
 	for _, s := range res.Samples {
 		if !s.Timestamp.IsZero() {
@@ -84,30 +126,25 @@ func formatDiscordMessage(res Result) string {
 	separator := strings.Repeat("-", constants.DiscordMessageSeparatorLen)
 	lines := fmt.Sprintf("%s\nMetrics (collected at %s):", separator, collectedAt.Format(time.RFC3339))
 
+	if alertBlock := buildAlertsBlock(DetectAlerts(res)); alertBlock != "" {
+		lines += "\n" + alertBlock
+	}
+
 	if cpuBlock := buildCPUUtilizationBlock(res.Samples); cpuBlock != "" {
 		lines += "\n" + cpuBlock
 	}
 
+	if mmcBlock := buildMMCWearBlock(res.Samples); mmcBlock != "" {
+		lines += "\n" + mmcBlock
+	}
+
 	// Print one line per metric sample.
 	for _, s := range res.Samples {
-		if isCPUBlockSample(s) {
+		if isCPUBlockSample(s) || isMMCBlockSample(s) {
 			continue
 		}
 
-		unit := s.Unit
-		if unit == "" {
-			unit = "(no unit)"
-		}
-
-		if unit == "bytes" {
-			lines += fmt.Sprintf("\n%s: %s", s.Name, fmtBytesAsGigabytesWithRawBytes(s.Value))
-			continue
-		}
-		if unit == "celsius" {
-			lines += fmt.Sprintf("\n%s: %.3f celsius", s.Name, s.Value)
-			continue
-		}
-		lines += fmt.Sprintf("\n%s: %.3f %s", s.Name, s.Value, unit)
+		lines += fmt.Sprintf("\n%s: %s", s.Name, FormatSample(s))
 	}
 
 	if len(res.Errors) > 0 {
@@ -158,6 +195,79 @@ func buildCPUUtilizationBlock(samples []Sample) string {
 	return lines
 }
 
+func buildAlertsBlock(alerts []Alert) string {
+	if len(alerts) == 0 {
+		return ""
+	}
+	lines := "Alerts:"
+	for _, a := range alerts {
+		lines += fmt.Sprintf("\n- [%s] %s", strings.ToUpper(a.Severity), a.Message)
+	}
+	return lines
+}
+
+func buildMMCWearBlock(samples []Sample) string {
+	byDevice := make(map[string][]string)
+	var devices []string
+
+	for _, s := range samples {
+		if !isMMCBlockSample(s) {
+			continue
+		}
+		dev := s.Labels["device"]
+		if _, ok := byDevice[dev]; !ok {
+			devices = append(devices, dev)
+			byDevice[dev] = nil
+		}
+
+		var line string
+		switch s.Name {
+		case "mmc_info":
+			line = fmt.Sprintf("card: %s (manfid %s, date %s)", s.Labels["name"], s.Labels["manfid"], s.Labels["date"])
+		case "mmc_life_time_used_percent":
+			line = fmt.Sprintf("life time used (type %s): %.0f%%", s.Labels["type"], s.Value)
+		case "mmc_pre_eol_info":
+			line = fmt.Sprintf("pre-EOL: %s", mmcPreEOLText(s.Value))
+		case "mmc_bytes_written_total":
+			line = fmt.Sprintf("written: %s", fmtBytesAsGigabytesWithRawBytes(s.Value))
+		case "mmc_estimated_writes_per_day_bytes":
+			line = fmt.Sprintf("writes/day: %s", fmtBytesAsGigabytesWithRawBytes(s.Value))
+		}
+		byDevice[dev] = append(byDevice[dev], line)
+	}
+
+	if len(devices) == 0 {
+		return ""
+	}
+
+	sort.Strings(devices)
+	lines := "SD card wear:"
+	for _, dev := range devices {
+		lines += fmt.Sprintf("\n- %s:", dev)
+		for _, l := range byDevice[dev] {
+			lines += "\n  - " + l
+		}
+	}
+	return lines
+}
+
+func isMMCBlockSample(s Sample) bool {
+	return strings.HasPrefix(s.Name, "mmc_")
+}
+
+func mmcPreEOLText(v float64) string {
+	switch v {
+	case 1:
+		return "normal"
+	case 2:
+		return "warning"
+	case 3:
+		return "urgent"
+	default:
+		return fmt.Sprintf("unknown (%.0f)", v)
+	}
+}
+
 func isCPUBlockSample(s Sample) bool {
 	switch s.Name {
 	case "cpu_utilization":
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"rpi-metrics/internal/secret"
+)
+
+// HeartbeatExporter is a dead-man's switch: it pings a healthchecks.io-style URL after
+// collection cycles so a monitor notices when the Pi or the agent goes silent.
+//
+//   - URL             success ping (every cycle that produced samples)
+//   - URL + "/start"  sent once by Start when the agent starts
+//   - URL + "/fail"   a cycle produced no samples but the agent's own (or any errors, with FailOnErrors)
+//
+// POST pings carry a short plain-text status body; GET pings have none.
+type HeartbeatExporter struct {
+	URL    string
+	Method string // http.MethodPost (default) or http.MethodGet
+	Client *http.Client
+
+	// FailOnErrors sends a fail ping when any collector errored, not only when all did.
+	FailOnErrors bool
+
+	// If > 0, limits how often we ping; a change between success and fail is sent immediately.
+	MinInterval time.Duration
+	lastSent    time.Time
+	lastFailed  bool
+}
+
+// Start sends the "/start" ping.
+func (e *HeartbeatExporter) Start(ctx context.Context, body string) error {
+	return e.ping(ctx, "/start", body)
+}
+
+func (e *HeartbeatExporter) Export(ctx context.Context, res Result) error {
+	failed := !collectedAny(res) || (e.FailOnErrors && len(res.Errors) > 0)
+
+	now := time.Now()
+	if e.MinInterval > 0 && !e.lastSent.IsZero() && now.Sub(e.lastSent) < e.MinInterval && failed == e.lastFailed {
+		return nil // too soon; skip
+	}
+
+	suffix := ""
+	if failed {
+		suffix = "/fail"
+	}
+	if err := e.ping(ctx, suffix, heartbeatStatus(res)); err != nil {
+		return err
+	}
+
+	e.lastSent = now
+	e.lastFailed = failed
+	return nil
+}
+
+func (e *HeartbeatExporter) ping(ctx context.Context, suffix, body string) error {
+	if e.URL == "" {
+		return fmt.Errorf("heartbeat url is empty")
+	}
+	if e.Client == nil {
+		e.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	method := e.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	var reqBody io.Reader
+	if method == http.MethodPost {
+		reqBody = strings.NewReader(body)
+	}
+
+	url := strings.TrimRight(e.URL, "/") + suffix
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return fmt.Errorf("new request: %w", secret.StripURL(err))
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("heartbeat ping: %w", secret.StripURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("heartbeat ping returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// collectedAny reports whether res has samples besides the agent's own: the agent
+// collector and the runner's stats report every cycle, even when every real
+// collector fails.
+func collectedAny(res Result) bool {
+	for _, s := range res.Samples {
+		if !strings.HasPrefix(s.Name, "agent_") {
+			return true
+		}
+	}
+	return false
+}
+
+// heartbeatStatus is the short body sent with each ping.
+func heartbeatStatus(res Result) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "samples=%d errors=%d", len(res.Samples), len(res.Errors))
+	for _, s := range res.Samples {
+		if s.Name == "cpu_temperature" {
+			fmt.Fprintf(&b, " cpu_temperature=%.1f", s.Value)
+			break
+		}
+	}
+	for _, e := range res.Errors {
+		fmt.Fprintf(&b, "\n%s: %s", e.CollectorID, e.Error)
+	}
+	return b.String()
+}
@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"rpi-metrics/internal/secret"
+)
+
+// PushResult is one collection cycle as pushed to rpi-metrics-hub.
+type PushResult struct {
+	CollectedAt time.Time        `json:"collected_at"`
+	Samples     []Sample         `json:"samples"`
+	Errors      []CollectorError `json:"errors,omitempty"`
+}
+
+// PushBatch is the body of a push: gzip-compressed JSON, POSTed to the hub's /api/push.
+type PushBatch struct {
+	Results []PushResult `json:"results"`
+}
+
+// PushExporter sends Results to a central rpi-metrics-hub. Results are queued and
+// sent BatchSize at a time, gzip-compressed, with the agent's token as a bearer token.
+// When the hub is unreachable the queue keeps up to MaxQueue Results (oldest dropped)
+// and they are resent with the next batch.
+type PushExporter struct {
+	URL    string // e.g. http://hub:8081/api/push
+	Token  string
+	Client *http.Client
+
+	BatchSize int // Results per push; default 1
+	MaxQueue  int // default 100
+
+	mu    sync.Mutex
+	queue []PushResult
+}
+
+const (
+	defaultPushBatchSize = 1
+	defaultPushMaxQueue  = 100
+)
+
+func (e *PushExporter) Export(ctx context.Context, res Result) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	maxQueue := e.MaxQueue
+	if maxQueue <= 0 {
+		maxQueue = defaultPushMaxQueue
+	}
+	batchSize := e.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultPushBatchSize
+	}
+
+	e.queue = append(e.queue, PushResult{
+		CollectedAt: collectedAt(res),
+		Samples:     res.Samples,
+		Errors:      res.Errors,
+	})
+	if over := len(e.queue) - maxQueue; over > 0 {
+		e.queue = append(e.queue[:0], e.queue[over:]...)
+	}
+	if len(e.queue) < batchSize {
+		return nil
+	}
+
+	return e.flushLocked(ctx)
+}
+
+// Flush sends queued Results without waiting for a full batch.
+func (e *PushExporter) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.queue) == 0 {
+		return nil
+	}
+	return e.flushLocked(ctx)
+}
+
+func (e *PushExporter) flushLocked(ctx context.Context) error {
+	if err := e.send(ctx, e.queue); err != nil {
+		return fmt.Errorf("push %d results: %w", len(e.queue), err)
+	}
+	e.queue = e.queue[:0]
+	return nil
+}
+
+// collectedAt is the time of the newest sample, so replayed Results keep their
+// original time; Results without samples are stamped now.
+func collectedAt(res Result) time.Time {
+	var t time.Time
+	for _, s := range res.Samples {
+		if s.Timestamp.After(t) {
+			t = s.Timestamp
+		}
+	}
+	if t.IsZero() {
+		return time.Now().UTC()
+	}
+	return t.UTC()
+}
+
+// QueueDepth reports how many Results are waiting to be pushed.
+func (e *PushExporter) QueueDepth() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.queue)
+}
+
+func (e *PushExporter) send(ctx context.Context, results []PushResult) error {
+	if e.URL == "" {
+		return fmt.Errorf("push url is empty")
+	}
+	if e.Client == nil {
+		e.Client = &http.Client{Timeout: 15 * time.Second}
+	}
+
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if err := json.NewEncoder(zw).Encode(PushBatch{Results: results}); err != nil {
+		return fmt.Errorf("marshal push: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compress push: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, &body)
+	if err != nil {
+		return fmt.Errorf("new request: %w", secret.StripURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	if e.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+e.Token)
+	}
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post: %w", secret.StripURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub returned status %d: %s", resp.StatusCode, bytes.TrimSpace(b))
+	}
+	return nil
+}
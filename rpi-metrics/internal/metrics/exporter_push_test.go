@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHub records the batches pushed to it and fails while down is set.
+type fakeHub struct {
+	mu      sync.Mutex
+	down    bool
+	batches []PushBatch
+	auth    []string
+}
+
+func (h *fakeHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.auth = append(h.auth, r.Header.Get("Authorization"))
+	if h.down {
+		http.Error(w, "hub down", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Header.Get("Content-Encoding") != "gzip" {
+		http.Error(w, "want gzip", http.StatusBadRequest)
+		return
+	}
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var batch PushBatch
+	if err := json.NewDecoder(zr).Decode(&batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.batches = append(h.batches, batch)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pushed returns the value of the first sample of every pushed Result, batch by batch.
+func (h *fakeHub) pushed() [][]float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out [][]float64
+	for _, b := range h.batches {
+		var vals []float64
+		for _, res := range b.Results {
+			vals = append(vals, res.Samples[0].Value)
+		}
+		out = append(out, vals)
+	}
+	return out
+}
+
+func newFakeHub(t *testing.T) (*fakeHub, string) {
+	t.Helper()
+	h := &fakeHub{}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return h, srv.URL + "/api/push"
+}
+
+func pushResult(v float64) Result {
+	at := time.Date(2026, 10, 18, 12, 0, int(v), 0, time.UTC)
+	return Result{Samples: []Sample{{Name: "cpu_temp_celsius", Value: v, Unit: UnitCelsius, Timestamp: at}}}
+}
+
+func equalBatches(a, b [][]float64) bool {
+	return slices.EqualFunc(a, b, slices.Equal[[]float64])
+}
+
+func TestPushExporterBatches(t *testing.T) {
+	hub, url := newFakeHub(t)
+	e := &PushExporter{URL: url, Token: "tok", BatchSize: 2}
+	ctx := context.Background()
+
+	for v := 1; v <= 5; v++ {
+		if err := e.Export(ctx, pushResult(float64(v))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := [][]float64{{1, 2}, {3, 4}}; !equalBatches(hub.pushed(), want) {
+		t.Errorf("batches = %v, want %v", hub.pushed(), want)
+	}
+	if d := e.QueueDepth(); d != 1 {
+		t.Errorf("QueueDepth = %d, want 1", d)
+	}
+
+	if err := e.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float64{{1, 2}, {3, 4}, {5}}; !equalBatches(hub.pushed(), want) {
+		t.Errorf("after Flush: batches = %v, want %v", hub.pushed(), want)
+	}
+	if err := e.Flush(ctx); err != nil || len(hub.pushed()) != 3 {
+		t.Errorf("Flush of an empty queue: %v, %d batches; want nothing sent", err, len(hub.pushed()))
+	}
+
+	for _, auth := range hub.auth {
+		if auth != "Bearer tok" {
+			t.Errorf("Authorization = %q, want the bearer token", auth)
+		}
+	}
+	got := hub.batches[2].Results[0]
+	if !got.CollectedAt.Equal(pushResult(5).Samples[0].Timestamp) {
+		t.Errorf("collected_at = %s, want the sample's timestamp", got.CollectedAt)
+	}
+}
+
+func TestPushExporterQueueLimit(t *testing.T) {
+	hub, url := newFakeHub(t)
+	hub.down = true
+	e := &PushExporter{URL: url, MaxQueue: 3}
+	ctx := context.Background()
+
+	for v := 1; v <= 5; v++ {
+		if err := e.Export(ctx, pushResult(float64(v))); err == nil {
+			t.Errorf("export %d: no error while the hub is down", v)
+		}
+	}
+	if d := e.QueueDepth(); d != 3 {
+		t.Errorf("QueueDepth = %d, want MaxQueue", d)
+	}
+
+	// The backlog goes out with the next push, oldest results dropped.
+	hub.mu.Lock()
+	hub.down = false
+	hub.mu.Unlock()
+	if err := e.Export(ctx, pushResult(6)); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float64{{4, 5, 6}}; !equalBatches(hub.pushed(), want) {
+		t.Errorf("batches = %v, want %v", hub.pushed(), want)
+	}
+	if d := e.QueueDepth(); d != 0 {
+		t.Errorf("QueueDepth = %d after a successful push, want 0", d)
+	}
+	if len(hub.auth) == 0 || hub.auth[0] != "" {
+		t.Errorf("Authorization = %q without a token, want none", hub.auth)
+	}
+}
@@ -0,0 +1,330 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"rpi-metrics/internal/secret"
+)
+
+// WebhookConfig is one entry of the -webhook-config file. Method, URL, header values
+// and Body are text/templates executed with WebhookData (see WebhookFuncs), so any
+// JSON or form webhook (Slack, Teams, Mattermost, ntfy, Gotify) can be targeted
+// without code. URL and header values may be env:VAR or file:/path references.
+type WebhookConfig struct {
+	Name       string            `json:"name"`
+	Method     string            `json:"method,omitempty"` // default POST
+	URL        string            `json:"url"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	Every      string            `json:"every,omitempty"`       // minimum time between posts, e.g. 10m
+	AlertsOnly bool              `json:"alerts_only,omitempty"` // post only when there are alerts
+
+	every time.Duration
+	tmpl  *webhookTemplates // parsed by prepare; read-only, so exporters can share it
+}
+
+// WebhookFile is the JSON file given to -webhook-config.
+type WebhookFile struct {
+	Webhooks []WebhookConfig `json:"webhooks"`
+}
+
+// LoadWebhookConfig reads a webhook config file, resolves its secrets and checks its
+// templates.
+func LoadWebhookConfig(path string) ([]WebhookConfig, error) {
+	var file WebhookFile
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read webhook config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("parse webhook config %s: %w", path, err)
+	}
+
+	seen := map[string]bool{}
+	for i := range file.Webhooks {
+		c := &file.Webhooks[i]
+		if err := c.prepare(); err != nil {
+			return nil, fmt.Errorf("webhook config %s: webhook %q: %w", path, c.Name, err)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("webhook config %s: duplicate webhook %q", path, c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return file.Webhooks, nil
+}
+
+// prepare validates c, resolves secret references and parses Every and the templates.
+func (c *WebhookConfig) prepare() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is empty")
+	}
+	if c.URL == "" {
+		return fmt.Errorf("url is empty")
+	}
+	var err error
+	if c.URL, err = resolveWebhookSecret(c.URL); err != nil {
+		return fmt.Errorf("url: %w", err)
+	}
+	for k, v := range c.Headers {
+		if c.Headers[k], err = resolveWebhookSecret(v); err != nil {
+			return fmt.Errorf("header %s: %w", k, err)
+		}
+	}
+	if c.Every != "" {
+		if c.every, err = time.ParseDuration(c.Every); err != nil {
+			return fmt.Errorf("every: %w", err)
+		}
+	}
+	c.tmpl, err = c.parse()
+	return err
+}
+
+// resolveWebhookSecret resolves an env: or file: reference and registers the value for
+// redaction. Literal values are registered too: webhook URLs carry tokens.
+func resolveWebhookSecret(v string) (string, error) {
+	resolved, err := secret.Resolve(v)
+	if err != nil {
+		return "", err
+	}
+	if resolved != v || strings.Contains(v, "://") {
+		secret.Register(resolved)
+	}
+	return resolved, nil
+}
+
+// Interval returns the minimum time between posts set by Every (0 when unset).
+func (c WebhookConfig) Interval() time.Duration {
+	return c.every
+}
+
+type webhookTemplates struct {
+	method, url, body *template.Template
+	headers           map[string]*template.Template
+}
+
+func (c WebhookConfig) parse() (*webhookTemplates, error) {
+	parse := func(field, text string) (*template.Template, error) {
+		t, err := template.New(field).Funcs(WebhookFuncs).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s template: %w", field, err)
+		}
+		return t, nil
+	}
+
+	method := c.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	t := &webhookTemplates{headers: map[string]*template.Template{}}
+	var err error
+	if t.method, err = parse("method", method); err != nil {
+		return nil, err
+	}
+	if t.url, err = parse("url", c.URL); err != nil {
+		return nil, err
+	}
+	if t.body, err = parse("body", c.Body); err != nil {
+		return nil, err
+	}
+	for k, v := range c.Headers {
+		if t.headers[k], err = parse("header "+k, v); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// WebhookData is what webhook templates are executed with.
+type WebhookData struct {
+	Time    time.Time // collection time
+	Samples []Sample
+	Errors  []CollectorError
+	Alerts  []Alert
+	Text    string // the message Discord would get, or the notice's text
+	Notice  bool   // a one-off notice (Notify) rather than a snapshot
+}
+
+// WebhookFuncs are the functions available in webhook templates:
+//
+//	filter .Samples "name" ["label=value" | "label!=value" ...]   matching samples
+//	first .Samples "name" [matchers ...]   the first matching sample (zero if none)
+//	value .Sample        the value formatted for its unit, as in Discord messages
+//	bytes 123456789      "123.5 MB" (decimal units)
+//	percent 42.123       "42.1%"
+//	json .Text           a JSON string literal, for JSON bodies
+var WebhookFuncs = template.FuncMap{
+	"filter":  filterSamples,
+	"first":   firstSample,
+	"value":   FormatSample,
+	"bytes":   HumanBytes,
+	"percent": func(v float64) string { return fmt.Sprintf("%.1f%%", v) },
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func filterSamples(samples []Sample, name string, matchers ...string) ([]Sample, error) {
+	var out []Sample
+	for _, s := range samples {
+		if s.Name != name {
+			continue
+		}
+		ok, err := matchLabels(s, matchers)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func firstSample(samples []Sample, name string, matchers ...string) (Sample, error) {
+	matched, err := filterSamples(samples, name, matchers...)
+	if err != nil || len(matched) == 0 {
+		return Sample{}, err
+	}
+	return matched[0], nil
+}
+
+func matchLabels(s Sample, matchers []string) (bool, error) {
+	for _, m := range matchers {
+		k, v, ok := strings.Cut(m, "!=")
+		negate := ok
+		if !ok {
+			if k, v, ok = strings.Cut(m, "="); !ok {
+				return false, fmt.Errorf("invalid matcher %q: want label=value or label!=value", m)
+			}
+		}
+		if (s.Labels[k] == v) == negate {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// HumanBytes renders a byte count with decimal units (252.1 GB), matching the
+// gigabytes of Discord messages.
+func HumanBytes(v float64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB", "PB"}
+	i := 0
+	for v >= 1000 && i < len(units)-1 {
+		v /= 1000
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", v, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", v, units[i])
+}
+
+// WebhookExporter posts Results to a webhook described by a WebhookConfig.
+type WebhookExporter struct {
+	Config WebhookConfig
+	Client *http.Client
+
+	// Retry retries failed posts.
+	Retry Retry
+
+	// IgnoreEvery leaves Config.Every to the caller (replay throttles by recorded time).
+	IgnoreEvery bool
+
+	lastSent time.Time
+}
+
+func (e *WebhookExporter) Export(ctx context.Context, res Result) error {
+	now := time.Now()
+	if !e.IgnoreEvery && throttled(e.lastSent, e.Config.every, now) {
+		return nil // too soon; skip
+	}
+
+	data := WebhookData{Time: now.UTC(), Samples: res.Samples, Errors: res.Errors, Alerts: DetectAlerts(res)}
+	if e.Config.AlertsOnly && len(data.Alerts) == 0 {
+		return nil
+	}
+	for _, s := range res.Samples {
+		if !s.Timestamp.IsZero() {
+			data.Time = s.Timestamp.UTC()
+			break
+		}
+	}
+	data.Text = FormatMessage(res)
+
+	if err := e.post(ctx, data); err != nil {
+		return err
+	}
+	e.lastSent = now
+	return nil
+}
+
+// Notify posts a one-off notice immediately, ignoring Every and AlertsOnly; .Text is
+// the message and .Notice is true.
+func (e *WebhookExporter) Notify(ctx context.Context, msg string) error {
+	return e.post(ctx, WebhookData{Time: time.Now().UTC(), Text: msg, Notice: true})
+}
+
+// post runs on the exporter's queue for Export and on the caller's for Notify, so it
+// only reads e.
+func (e *WebhookExporter) post(ctx context.Context, data WebhookData) error {
+	tmpl := e.Config.tmpl
+	if tmpl == nil {
+		// A config that didn't come from LoadWebhookConfig.
+		t, err := e.Config.parse()
+		if err != nil {
+			return err
+		}
+		tmpl = t
+	}
+
+	target := "webhook " + e.Config.Name
+	render := func(t *template.Template) (string, error) {
+		var b strings.Builder
+		if err := t.Execute(&b, data); err != nil {
+			return "", fmt.Errorf("%s: %w", target, err)
+		}
+		return b.String(), nil
+	}
+	method, err := render(tmpl.method)
+	if err != nil {
+		return err
+	}
+	url, err := render(tmpl.url)
+	if err != nil {
+		return err
+	}
+	body, err := render(tmpl.body)
+	if err != nil {
+		return err
+	}
+	headers := make(map[string]string, len(tmpl.headers))
+	for k, t := range tmpl.headers {
+		if headers[k], err = render(t); err != nil {
+			return err
+		}
+	}
+
+	return e.Retry.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, strings.ToUpper(strings.TrimSpace(method)), strings.TrimSpace(url), strings.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("%s: new request: %w", target, secret.StripURL(err))
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return Send(httpClient(e.Client), target, req)
+	})
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWebhookExporterConcurrentPosts(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	for _, prepared := range []bool{true, false} {
+		bodies = nil
+		c := WebhookConfig{Name: "test", URL: srv.URL, Body: `{{ if .Notice }}notice{{ else }}snapshot{{ end }}`}
+		if prepared {
+			if err := c.prepare(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Export runs on the webhook's queue and Notify on the shutdown queue.
+		e := &WebhookExporter{Config: c}
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 4; i++ {
+				if err := e.Export(context.Background(), Result{}); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 4; i++ {
+				if err := e.Notify(context.Background(), "power is back"); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+		wg.Wait()
+
+		mu.Lock()
+		got := strings.Join(bodies, " ")
+		mu.Unlock()
+		if strings.Count(got, "notice") != 4 || strings.Count(got, "snapshot") != 4 {
+			t.Errorf("prepared %v: bodies = %q, want 4 notices and 4 snapshots", prepared, got)
+		}
+	}
+}
+
+func TestHumanBytes(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0 B"},
+		{999, "999 B"},
+		{1000, "1.0 kB"},
+		{1024, "1.0 kB"},
+		{252_100_000_000, "252.1 GB"},
+		{3.5e15, "3.5 PB"},
+		{2e18, "2000.0 PB"},
+	}
+	for _, tt := range tests {
+		if got := HumanBytes(tt.v); got != tt.want {
+			t.Errorf("HumanBytes(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
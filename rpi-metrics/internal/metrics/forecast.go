@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// StorageForecaster is a Stage that fits a linear trend to storage_used_bytes per
+// mount and emits storage_predicted_full_seconds: the time until storage_available_bytes
+// reaches zero at the current growth rate.
+//
+// There is no history store, so it keeps its own rolling window of points per series.
+// A prediction is only emitted when the window has enough points, usage is growing
+// and the fit explains most of the variance (R² >= MinR2).
+type StorageForecaster struct {
+	Window    time.Duration // default: 6h
+	MinPoints int           // default: 10
+	MinR2     float64       // 0 accepts any fit; negative: the default 0.8
+
+	mu     sync.Mutex
+	series map[string]*forecastSeries
+}
+
+type forecastSeries struct {
+	points []forecastPoint
+	total  float64
+}
+
+type forecastPoint struct {
+	t    time.Time
+	used float64
+}
+
+func init() {
+	MustRegisterDescriptors(Descriptor{
+		Name:   "storage_predicted_full_seconds",
+		Type:   TypeGauge,
+		Help:   "Predicted time until the filesystem is full at the current growth rate; absent when usage is flat, shrinking or noisy.",
+		Unit:   UnitSeconds,
+		Labels: []string{"path", "mount_point", "fs_type", "source"},
+	})
+}
+
+const (
+	defaultForecastWindow    = 6 * time.Hour
+	defaultForecastMinPoints = 10
+	defaultForecastMinR2     = 0.8
+
+	// A drop in usage larger than this fraction of the filesystem size is treated as a
+	// cleanup (deleted logs, rotated files) and restarts the trend from scratch.
+	forecastResetDropFraction = 0.01
+)
+
+func (f *StorageForecaster) Process(res Result) Result {
+	window := f.Window
+	if window <= 0 {
+		window = defaultForecastWindow
+	}
+	minPoints := f.MinPoints
+	if minPoints < 2 {
+		minPoints = defaultForecastMinPoints
+	}
+	minR2 := f.MinR2
+	if minR2 < 0 {
+		minR2 = defaultForecastMinR2
+	}
+
+	avail := make(map[string]float64)
+	totals := make(map[string]float64)
+	for _, s := range res.Samples {
+		switch s.Name {
+		case "storage_available_bytes":
+			avail[SeriesKey("", s.Labels)] = s.Value
+		case "storage_total_bytes":
+			totals[SeriesKey("", s.Labels)] = s.Value
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.series == nil {
+		f.series = make(map[string]*forecastSeries)
+	}
+
+	var derived []Sample
+	var latest time.Time
+	for _, s := range res.Samples {
+		if s.Name != "storage_used_bytes" {
+			continue
+		}
+		key := SeriesKey("", s.Labels)
+		if s.Timestamp.After(latest) {
+			latest = s.Timestamp
+		}
+
+		ser, ok := f.series[key]
+		if !ok {
+			ser = &forecastSeries{}
+			f.series[key] = ser
+		}
+
+		total := totals[key]
+		if n := len(ser.points); n > 0 {
+			last := ser.points[n-1].used
+			resized := total != ser.total
+			cleanedUp := last-s.Value > total*forecastResetDropFraction
+			if resized || cleanedUp {
+				ser.points = ser.points[:0]
+			}
+		}
+		ser.total = total
+		ser.points = append(ser.points, forecastPoint{t: s.Timestamp, used: s.Value})
+
+		cutoff := s.Timestamp.Add(-window)
+		drop := 0
+		for drop < len(ser.points) && ser.points[drop].t.Before(cutoff) {
+			drop++
+		}
+		ser.points = ser.points[drop:]
+
+		a, ok := avail[key]
+		if !ok || len(ser.points) < minPoints {
+			continue
+		}
+		slope, r2 := fitLinear(ser.points)
+		if slope <= 0 || r2 < minR2 {
+			continue
+		}
+
+		derived = append(derived, Sample{
+			Name:      "storage_predicted_full_seconds",
+			Value:     a / slope,
+			Unit:      UnitSeconds,
+			Timestamp: s.Timestamp,
+			Labels:    s.Labels,
+		})
+	}
+
+	// Forget mounts that disappeared (unmounted USB sticks etc.).
+	if !latest.IsZero() {
+		for key, ser := range f.series {
+			if n := len(ser.points); n == 0 || ser.points[n-1].t.Before(latest.Add(-window)) {
+				delete(f.series, key)
+			}
+		}
+	}
+
+	res.Samples = append(res.Samples, derived...)
+	return res
+}
+
+// fitLinear returns the least-squares slope (per second) and coefficient of
+// determination of used bytes over time.
+func fitLinear(points []forecastPoint) (slope, r2 float64) {
+	n := float64(len(points))
+	t0 := points[0].t
+
+	var sumX, sumY float64
+	for _, p := range points {
+		sumX += p.t.Sub(t0).Seconds()
+		sumY += p.used
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxx, sxy, syy float64
+	for _, p := range points {
+		dx := p.t.Sub(t0).Seconds() - meanX
+		dy := p.used - meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return 0, 0
+	}
+	slope = sxy / sxx
+	if syy == 0 {
+		return slope, 0 // perfectly flat: nothing to predict
+	}
+	return slope, (sxy * sxy) / (sxx * syy)
+}
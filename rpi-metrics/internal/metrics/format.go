@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// FormatValue renders a value for humans according to its canonical unit.
+func FormatValue(v float64, unit string) string {
+	switch unit {
+	case UnitBytes:
+		return fmtBytesAsGigabytesWithRawBytes(v)
+	case UnitSeconds:
+		return formatSeconds(v)
+	case UnitTimestamp:
+		return time.Unix(0, int64(v*1e9)).UTC().Format(time.RFC3339)
+	case UnitBool:
+		if v != 0 {
+			return "yes"
+		}
+		return "no"
+	case UnitBitsPerSecond:
+		return fmt.Sprintf("%.1f Mbit/s", v/1e6)
+	case UnitBytesPerSecond:
+		return fmt.Sprintf("%.1f KiB/s", v/1024)
+	case UnitPerSecond:
+		return fmt.Sprintf("%.3f/s", v)
+	case UnitState, UnitCount:
+		return fmt.Sprintf("%.0f", v)
+	case UnitNone:
+		return fmt.Sprintf("%.3f (no unit)", v)
+	default:
+		// celsius, percent and units of externally supplied metrics
+		return fmt.Sprintf("%.3f %s", v, unit)
+	}
+}
+
+// formatSeconds renders a duration: micro- or milliseconds below a second (collector
+// and export durations), and the "~N minutes" hint only once it says more than the seconds.
+func formatSeconds(v float64) string {
+	switch abs := math.Abs(v); {
+	case abs == 0:
+		return "0 seconds"
+	case abs < 1e-3:
+		return fmt.Sprintf("%.0f µs", v*1e6)
+	case abs < 1:
+		return fmt.Sprintf("%.1f ms", v*1e3)
+	case abs < 10:
+		return fmt.Sprintf("%.1f seconds", v)
+	case abs < 120:
+		return fmt.Sprintf("%.0f seconds", v)
+	default:
+		return fmt.Sprintf("%.0f seconds (%s)", v, humanizeDuration(time.Duration(v*float64(time.Second))))
+	}
+}
+
+// FormatSample renders a sample's value, adding the descriptor's range for states
+// (e.g. "2 (of 0-4)").
+func FormatSample(s Sample) string {
+	out := FormatValue(s.Value, s.Unit)
+	if d, ok := LookupDescriptor(s.Name); ok && d.Type == TypeStateSet && d.Range != nil {
+		out += fmt.Sprintf(" (of %g-%g)", d.Range.Min, d.Range.Max)
+	}
+	return out
+}
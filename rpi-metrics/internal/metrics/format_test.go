@@ -0,0 +1,53 @@
+package metrics
+
+import "testing"
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v    float64
+		unit string
+		want string
+	}{
+		{1.5e9, UnitBytes, "1.500 gigabytes (1500000000.000 bytes)"},
+		{-5, UnitBytes, "0.000 gigabytes (0.000 bytes)"},
+		{0, UnitSeconds, "0 seconds"},
+		{0.0005, UnitSeconds, "500 µs"},
+		{0.25, UnitSeconds, "250.0 ms"},
+		{-0.002, UnitSeconds, "-2.0 ms"},
+		{5, UnitSeconds, "5.0 seconds"},
+		{90, UnitSeconds, "90 seconds"},
+		{7200, UnitSeconds, "7200 seconds (~2 hours)"},
+		{1760788800, UnitTimestamp, "2025-10-18T12:00:00Z"},
+		{1, UnitBool, "yes"},
+		{0, UnitBool, "no"},
+		{12.5e6, UnitBitsPerSecond, "12.5 Mbit/s"},
+		{2048, UnitBytesPerSecond, "2.0 KiB/s"},
+		{0.5, UnitPerSecond, "0.500/s"},
+		{2, UnitState, "2"},
+		{3.4, UnitCount, "3"},
+		{1.5, UnitNone, "1.500 (no unit)"},
+		{48.25, UnitCelsius, "48.250 celsius"},
+		{1200, "rpm", "1200.000 rpm"},
+	}
+	for _, tt := range tests {
+		if got := FormatValue(tt.v, tt.unit); got != tt.want {
+			t.Errorf("FormatValue(%v, %q) = %q, want %q", tt.v, tt.unit, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSample(t *testing.T) {
+	tests := []struct {
+		sample Sample
+		want   string
+	}{
+		{Sample{Name: "test_level", Value: 2, Unit: UnitState}, "2 (of 0-4)"},
+		{Sample{Name: "unregistered_level", Value: 2, Unit: UnitState}, "2"},
+		{Sample{Name: "test_bytes_total", Value: 2e9, Unit: UnitBytes}, "2.000 gigabytes (2000000000.000 bytes)"},
+	}
+	for _, tt := range tests {
+		if got := FormatSample(tt.sample); got != tt.want {
+			t.Errorf("FormatSample(%s %v) = %q, want %q", tt.sample.Name, tt.sample.Value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"rpi-metrics/internal/secret"
+)
+
+// The notification exporters (Discord, webhooks, Telegram, email) share throttling and retries.
+
+// throttled reports whether a send at now comes less than minInterval after the last
+// successful one.
+func throttled(lastSent time.Time, minInterval time.Duration, now time.Time) bool {
+	return minInterval > 0 && !lastSent.IsZero() && now.Sub(lastSent) < minInterval
+}
+
+// Retry retries failed notification requests. Network errors, 429 and 5xx responses
+// are tried again, up to Attempts tries in total, Backoff apart and doubling each
+// time; a longer Retry-After from the server wins. The zero value tries once.
+type Retry struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+// maxRetryWait caps a server's Retry-After, so a misbehaving server can't stall the
+// exporter for long.
+const maxRetryWait = time.Minute
+
+// Do calls send until it succeeds, fails permanently or runs out of attempts.
+func (r Retry) Do(ctx context.Context, send func() error) error {
+	wait := r.Backoff
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil || attempt >= r.Attempts || !retryable(err) {
+			return err
+		}
+
+		d := wait
+		var se *StatusError
+		if errors.As(err, &se) && se.RetryAfter > d {
+			d = min(se.RetryAfter, maxRetryWait)
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		wait *= 2
+	}
+}
+
+func retryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusTooManyRequests || se.Code >= 500
+	}
+	var te *TransportError
+	return errors.As(err, &te) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// TransportError is a request that got no response, e.g. the network was down. Retry
+// retries it.
+type TransportError struct{ Err error }
+
+func (e *TransportError) Error() string { return e.Err.Error() }
+func (e *TransportError) Unwrap() error { return e.Err }
+
+// StatusError is a non-2xx response to a notification request.
+type StatusError struct {
+	Target     string // e.g. "discord webhook"
+	Code       int
+	Body       string // start of the response body
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("%s returned status %d: %s", e.Target, e.Code, e.Body)
+	}
+	return fmt.Sprintf("%s returned status %d", e.Target, e.Code)
+}
+
+// defaultHTTPClient is used by exporters whose Client is nil. An http.Client is safe
+// for concurrent use, so an exporter's Export and Notify can share one without a lock.
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// httpClient returns client, or defaultHTTPClient when it is nil.
+func httpClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return defaultHTTPClient
+}
+
+// Send does req with client and turns a non-2xx response into a *StatusError; Retry
+// retries the errors it returns for network failures. The request URL is kept out of
+// errors: webhook URLs carry tokens.
+func Send(client *http.Client, target string, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return &TransportError{fmt.Errorf("post to %s: %w", target, secret.StripURL(err))}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		return nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	se := &StatusError{Target: target, Code: resp.StatusCode, Body: strings.TrimSpace(string(b))}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		se.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return se
+}
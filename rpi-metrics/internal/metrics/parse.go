@@ -0,0 +1,221 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatAuto picks FormatJSON when the input starts with '[' and FormatPrometheus otherwise.
+	FormatAuto = "auto"
+	// FormatJSON is a JSON array of Sample, as found in the console exporter's "samples".
+	FormatJSON = "json"
+	// FormatPrometheus is the Prometheus text exposition format. Plain
+	// `name{label="value"} value` lines without comments are a valid subset of it.
+	FormatPrometheus = "prometheus"
+)
+
+// ParseSamples decodes externally produced metrics (scripts, textfiles) into samples.
+// Samples without a timestamp are left zero so the Runner stamps them.
+func ParseSamples(data []byte, format string) ([]Sample, error) {
+	switch format {
+	case "", FormatAuto:
+		trimmed := bytes.TrimSpace(data)
+		if len(trimmed) > 0 && trimmed[0] == '[' {
+			return parseJSONSamples(trimmed)
+		}
+		return parsePrometheusText(data)
+	case FormatJSON:
+		return parseJSONSamples(data)
+	case FormatPrometheus:
+		return parsePrometheusText(data)
+	default:
+		return nil, fmt.Errorf("unknown metrics format %q (want %s, %s or %s)", format, FormatAuto, FormatJSON, FormatPrometheus)
+	}
+}
+
+func parseJSONSamples(data []byte) ([]Sample, error) {
+	var out []Sample
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("parse json samples: %w", err)
+	}
+	for i, s := range out {
+		if s.Name == "" {
+			return nil, fmt.Errorf("parse json samples: sample %d has no name", i)
+		}
+	}
+	return out, nil
+}
+
+func parsePrometheusText(data []byte) ([]Sample, error) {
+	var out []Sample
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for s.Scan() {
+		lineNo++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue // HELP/TYPE/comments
+		}
+		sample, err := parsePrometheusLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		out = append(out, sample)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// parsePrometheusLine parses `name{k="v",...} value [timestamp_ms]`.
+func parsePrometheusLine(line string) (Sample, error) {
+	var sample Sample
+
+	end := strings.IndexAny(line, "{ \t")
+	if end < 0 {
+		return sample, fmt.Errorf("missing value in %q", line)
+	}
+	sample.Name = line[:end]
+	if !validMetricName(sample.Name) {
+		return sample, fmt.Errorf("invalid metric name %q", sample.Name)
+	}
+	rest := line[end:]
+
+	if strings.HasPrefix(rest, "{") {
+		labels, n, err := parsePrometheusLabels(rest)
+		if err != nil {
+			return sample, fmt.Errorf("%s: %w", sample.Name, err)
+		}
+		if len(labels) > 0 {
+			sample.Labels = labels
+		}
+		rest = rest[n:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return sample, fmt.Errorf("%s: want value and optional timestamp, got %q", sample.Name, strings.TrimSpace(rest))
+	}
+
+	v, err := parsePrometheusFloat(fields[0])
+	if err != nil {
+		return sample, fmt.Errorf("%s: parse value %q: %w", sample.Name, fields[0], err)
+	}
+	sample.Value = v
+
+	if len(fields) == 2 {
+		ms, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return sample, fmt.Errorf("%s: parse timestamp %q: %w", sample.Name, fields[1], err)
+		}
+		sample.Timestamp = time.UnixMilli(ms).UTC()
+	}
+	return sample, nil
+}
+
+// parsePrometheusLabels parses a `{k="v",...}` block at the start of s and returns
+// the labels and the number of bytes consumed.
+func parsePrometheusLabels(s string) (map[string]string, int, error) {
+	labels := make(map[string]string)
+	i := 1 // skip '{'
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("unterminated label set")
+		}
+		if s[i] == '}' {
+			return labels, i + 1, nil
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return nil, 0, fmt.Errorf("label without value")
+		}
+		name := strings.TrimSpace(s[i : i+eq])
+		if !validMetricName(name) {
+			return nil, 0, fmt.Errorf("invalid label name %q", name)
+		}
+		i += eq + 1
+		if i >= len(s) || s[i] != '"' {
+			return nil, 0, fmt.Errorf("label %s: value must be quoted", name)
+		}
+		i++
+
+		var val strings.Builder
+		for {
+			if i >= len(s) {
+				return nil, 0, fmt.Errorf("label %s: unterminated value", name)
+			}
+			c := s[i]
+			if c == '"' {
+				i++
+				break
+			}
+			if c == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					val.WriteByte('\n')
+				default:
+					val.WriteByte(s[i]) // \\ and \"
+				}
+				i++
+				continue
+			}
+			val.WriteByte(c)
+			i++
+		}
+		labels[name] = val.String()
+	}
+}
+
+// parsePrometheusFloat rejects NaN and ±Inf: they cannot be encoded as JSON and
+// would break the console exporter for the whole cycle.
+func parsePrometheusFloat(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("non-finite values are not supported")
+	}
+	return v, nil
+}
+
+func validMetricName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SplitCSV splits a comma-separated option value, trimming spaces and dropping empty
+// items: " a, b,,c " is [a b c].
+func SplitCSV(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
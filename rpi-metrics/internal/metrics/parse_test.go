@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSplitCSV(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{" a, b,,c ", []string{"a", "b", "c"}},
+		{" , ,", nil},
+	}
+	for _, tt := range tests {
+		if got := SplitCSV(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitCSV(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// sampleStrings renders samples as "name|k=v value [unix ms]" for comparison.
+func sampleStrings(samples []Sample) []string {
+	var out []string
+	for _, s := range samples {
+		str := SeriesKey(s.Name, s.Labels) + " " + strconv.FormatFloat(s.Value, 'g', -1, 64)
+		if !s.Timestamp.IsZero() {
+			str += " " + strconv.FormatInt(s.Timestamp.UnixMilli(), 10)
+		}
+		out = append(out, str)
+	}
+	return out
+}
+
+func TestParseSamplesPrometheus(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     []string
+		wantErr  string
+	}{
+		{"plain", "backup_ok 1\n", []string{"backup_ok 1"}, ""},
+		{"comments and blank lines", "# HELP backup_ok Whether it worked.\n# TYPE backup_ok gauge\n\n  backup_ok 1  \n", []string{"backup_ok 1"}, ""},
+		{"timestamp", "jobs_total 3 1760788800000", []string{"jobs_total 3 1760788800000"}, ""},
+		{"float forms", "a 1e3\nb -0.5\nc +2", []string{"a 1000", "b -0.5", "c 2"}, ""},
+		{"labels", `temp{zone="cpu",unit="c"} 48.5`, []string{"temp|unit=c|zone=cpu 48.5"}, ""},
+		{"spaces and trailing comma", `temp{ zone="cpu" , } 1`, []string{"temp|zone=cpu 1"}, ""},
+		{"empty label set", "temp{} 1", []string{"temp 1"}, ""},
+		{"escapes", `m{path="C:\\tmp",msg="say \"hi\"",lines="a\nb"} 1`, []string{"m|lines=a\nb|msg=say \"hi\"|path=C:\\tmp 1"}, ""},
+		{"unknown escape kept", `m{v="a\tb"} 1`, []string{"m|v=atb 1"}, ""},
+		{"blanks and braces in values", `m{v="a b}, c=d"} 1`, []string{"m|v=a b}, c=d 1"}, ""},
+		{"colon in name", "job:rate5m 2", []string{"job:rate5m 2"}, ""},
+		{"empty input", "", nil, ""},
+
+		{"no value", "backup_ok", nil, "line 1: missing value"},
+		{"no value after labels", `backup_ok{job="x"}`, nil, "line 1: backup_ok: want value and optional timestamp"},
+		{"too many fields", "backup_ok 1 2 3", nil, "want value and optional timestamp"},
+		{"bad value", "backup_ok yes", nil, `parse value "yes"`},
+		{"NaN", "backup_ok NaN", nil, "non-finite"},
+		{"Inf", "backup_ok +Inf", nil, "non-finite"},
+		{"float timestamp", "backup_ok 1 17.5", nil, `parse timestamp "17.5"`},
+		{"name starts with a digit", "1backup 1", nil, `invalid metric name "1backup"`},
+		{"dash in name", "backup-ok 1", nil, "invalid metric name"},
+		{"error names the line", "a 1\n\nb x", nil, "line 3: b:"},
+		{"unterminated label set", `m{a="1"`, nil, "unterminated label set"},
+		{"unterminated label value", `m{a="1} 1`, nil, "label a: unterminated value"},
+		{"escaped closing quote", `m{a="1\"} 1`, nil, "unterminated value"},
+		{"trailing backslash", `m{a="1\`, nil, "unterminated value"},
+		{"unquoted label value", `m{a=1} 1`, nil, "label a: value must be quoted"},
+		{"label without value", `m{a} 1`, nil, "label without value"},
+		{"invalid label name", `m{a-b="1"} 1`, nil, `invalid label name "a-b"`},
+	}
+	for _, tt := range tests {
+		for _, format := range []string{FormatPrometheus, FormatAuto} {
+			got, err := ParseSamples([]byte(tt.in), format)
+			switch {
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("%s (%s): error %v, want it to contain %q", tt.name, format, err, tt.wantErr)
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("%s (%s): %v", tt.name, format, err)
+			case !slices.Equal(sampleStrings(got), tt.want):
+				t.Errorf("%s (%s): samples %q, want %q", tt.name, format, sampleStrings(got), tt.want)
+			}
+		}
+	}
+}
+
+func TestParseSamplesJSON(t *testing.T) {
+	tests := []struct {
+		name, format, in string
+		want             []string
+		wantErr          string
+	}{
+		{"auto", FormatAuto, ` [{"name":"backup_ok","value":1,"labels":{"job":"x"}}]`, []string{"backup_ok|job=x 1"}, ""},
+		{"json", FormatJSON, `[{"name":"a","value":2,"ts":"2025-10-18T12:00:00Z"}]`, []string{"a 2 1760788800000"}, ""},
+		{"empty array", FormatJSON, `[]`, nil, ""},
+		{"no name", FormatJSON, `[{"name":"a","value":1},{"value":2}]`, nil, "sample 1 has no name"},
+		{"truncated", FormatAuto, `[{"name":"a"`, nil, "parse json samples"},
+		{"object, not array", FormatJSON, `{"name":"a"}`, nil, "parse json samples"},
+		{"unknown format", "csv", "a,1", nil, `unknown metrics format "csv"`},
+	}
+	for _, tt := range tests {
+		got, err := ParseSamples([]byte(tt.in), tt.format)
+		switch {
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: error %v, want it to contain %q", tt.name, err, tt.wantErr)
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case !slices.Equal(sampleStrings(got), tt.want):
+			t.Errorf("%s: samples %q, want %q", tt.name, sampleStrings(got), tt.want)
+		}
+	}
+}
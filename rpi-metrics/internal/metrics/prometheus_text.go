@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WritePrometheusText writes samples in the Prometheus text exposition format (0.0.4),
+// with HELP and TYPE taken from registered descriptors. Metrics without a descriptor
+// (exec/textfile) are written as untyped.
+func WritePrometheusText(w io.Writer, samples []Sample) error {
+	byName := make(map[string][]Sample)
+	names := make([]string, 0)
+	for _, s := range samples {
+		if _, ok := byName[s.Name]; !ok {
+			names = append(names, s.Name)
+		}
+		byName[s.Name] = append(byName[s.Name], s)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		typ := "untyped"
+		if d, ok := LookupDescriptor(name); ok {
+			if d.Help != "" {
+				bw.WriteString("# HELP " + name + " " + escapePrometheusHelp(d.Help) + "\n")
+			}
+			switch d.Type {
+			case TypeCounter:
+				typ = "counter"
+			default:
+				// The 0.0.4 text format has no state-set type; states are gauges.
+				typ = "gauge"
+			}
+		}
+		bw.WriteString("# TYPE " + name + " " + typ + "\n")
+
+		for _, s := range byName[name] {
+			bw.WriteString(name)
+			if len(s.Labels) > 0 {
+				keys := make([]string, 0, len(s.Labels))
+				for k := range s.Labels {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+
+				bw.WriteByte('{')
+				for i, k := range keys {
+					if i > 0 {
+						bw.WriteByte(',')
+					}
+					bw.WriteString(k + `="` + escapePrometheusLabel(s.Labels[k]) + `"`)
+				}
+				bw.WriteByte('}')
+			}
+			bw.WriteString(" " + strconv.FormatFloat(s.Value, 'g', -1, 64) + "\n")
+		}
+	}
+	return bw.Flush()
+}
+
+var (
+	prometheusHelpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapePrometheusHelp(s string) string  { return prometheusHelpEscaper.Replace(s) }
+func escapePrometheusLabel(s string) string { return prometheusLabelEscaper.Replace(s) }
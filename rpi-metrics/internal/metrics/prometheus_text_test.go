@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheusText(t *testing.T) {
+	samples := []Sample{
+		{Name: "unknown_metric", Value: 1},
+		{Name: "test_level", Value: 2, Unit: UnitState, Labels: map[string]string{"dev": "a\"b\\c\nd"}},
+		{Name: "test_bytes_total", Value: 1.5e10, Unit: UnitBytes, Labels: map[string]string{"dev": "sda", "a": "first"}},
+		{Name: "test_level", Value: 3, Unit: UnitState, Labels: map[string]string{"dev": "x"}},
+		{Name: "test_events_total", Value: 0.25},
+	}
+	want := `# HELP test_bytes_total Test counter.
+# TYPE test_bytes_total counter
+test_bytes_total{a="first",dev="sda"} 1.5e+10
+# HELP test_events_total Test counter.
+# TYPE test_events_total counter
+test_events_total 0.25
+# HELP test_level Test state, 0-4.\nSecond line with a \\ backslash.
+# TYPE test_level gauge
+test_level{dev="a\"b\\c\nd"} 2
+test_level{dev="x"} 3
+# TYPE unknown_metric untyped
+unknown_metric 1
+`
+	var b strings.Builder
+	if err := WritePrometheusText(&b, samples); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != want {
+		t.Errorf("WritePrometheusText:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	// What we write, the textfile and exec collectors read back unchanged.
+	parsed, err := ParseSamples([]byte(b.String()), FormatPrometheus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sampleStrings(parsed), []string{
+		"test_bytes_total|a=first|dev=sda 1.5e+10",
+		"test_events_total 0.25",
+		"test_level|dev=a\"b\\c\nd 2",
+		"test_level|dev=x 3",
+		"unknown_metric 1",
+	}; !slices.Equal(got, want) {
+		t.Errorf("parsed back: %q, want %q", got, want)
+	}
+
+	b.Reset()
+	if err := WritePrometheusText(&b, nil); err != nil || b.Len() != 0 {
+		t.Errorf("no samples: wrote %q, %v", b.String(), err)
+	}
+}
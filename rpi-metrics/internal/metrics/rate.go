@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// RateStage is a Stage that derives a per-second rate for every counter sample (a
+// sample whose descriptor has TypeCounter): foo_total becomes foo_per_second with the
+// same labels. The counter itself is passed through unchanged.
+//
+// Collectors can therefore report raw kernel counters and leave previous-value
+// tracking to the pipeline. The first observation of a series yields no rate. A counter
+// that goes backwards is treated as a 32/64-bit wraparound when it was close to the
+// limit and as a reset (the process or interface restarted from zero) otherwise.
+type RateStage struct {
+	// ExpireAfter forgets a series after this many cycles without a sample, so
+	// vanished interfaces or devices don't leak memory. Default 5.
+	ExpireAfter int
+
+	// Names limits the stage to these counters. Empty derives a rate for every counter.
+	Names []string
+
+	mu     sync.Mutex
+	cycle  uint64
+	series map[string]*rateSeries
+}
+
+type rateSeries struct {
+	last     Sample
+	lastSeen uint64 // cycle
+}
+
+const (
+	defaultRateExpireAfter = 5
+
+	// A counter that drops after reaching this fraction of 2^32 or 2^64 wrapped around.
+	rateWrapFraction = 0.75
+)
+
+var (
+	rateMax32 = math.Pow(2, 32)
+	rateMax64 = math.Pow(2, 64)
+)
+
+func (r *RateStage) Process(res Result) Result {
+	expire := uint64(r.ExpireAfter)
+	if r.ExpireAfter <= 0 {
+		expire = defaultRateExpireAfter
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.series == nil {
+		r.series = make(map[string]*rateSeries)
+	}
+	r.cycle++
+
+	var derived []Sample
+	for _, s := range res.Samples {
+		d, ok := LookupDescriptor(s.Name)
+		if !ok || d.Type != TypeCounter || (len(r.Names) > 0 && !slices.Contains(r.Names, s.Name)) {
+			continue
+		}
+
+		key := SeriesKey(s.Name, s.Labels)
+		ser, ok := r.series[key]
+		if !ok {
+			r.series[key] = &rateSeries{last: s, lastSeen: r.cycle}
+			continue
+		}
+		prev := ser.last
+		ser.last, ser.lastSeen = s, r.cycle
+
+		dt := s.Timestamp.Sub(prev.Timestamp).Seconds()
+		if dt <= 0 {
+			continue
+		}
+		derived = append(derived, Sample{
+			Name:      rateName(s.Name),
+			Value:     counterDelta(prev.Value, s.Value) / dt,
+			Unit:      rateUnit(d.Unit),
+			Timestamp: s.Timestamp,
+			Labels:    s.Labels,
+		})
+	}
+
+	for key, ser := range r.series {
+		if r.cycle-ser.lastSeen >= expire {
+			delete(r.series, key)
+		}
+	}
+
+	res.Samples = append(res.Samples, derived...)
+	return res
+}
+
+// counterDelta is the increase from prev to cur, allowing for wraparound and resets.
+func counterDelta(prev, cur float64) float64 {
+	if cur >= prev {
+		return cur - prev
+	}
+	for _, limit := range []float64{rateMax32, rateMax64} {
+		if prev < limit && prev >= limit*rateWrapFraction {
+			return limit - prev + cur
+		}
+	}
+	return cur // reset: counted up from zero since the last sample
+}
+
+func rateName(counter string) string {
+	return strings.TrimSuffix(counter, "_total") + "_per_second"
+}
+
+func rateUnit(counterUnit string) string {
+	switch counterUnit {
+	case UnitBytes:
+		return UnitBytesPerSecond
+	case UnitSeconds:
+		return UnitNone // seconds per second: the fraction of time spent
+	default:
+		return UnitPerSecond
+	}
+}
+
+// rateDescriptor documents the rate derived from a counter; RegisterDescriptors
+// registers it along with the counter.
+func rateDescriptor(counter Descriptor) Descriptor {
+	return Descriptor{
+		Name:   rateName(counter.Name),
+		Type:   TypeGauge,
+		Help:   "Per-second rate of " + counter.Name + ": " + counter.Help,
+		Unit:   rateUnit(counter.Unit),
+		Labels: counter.Labels,
+	}
+}
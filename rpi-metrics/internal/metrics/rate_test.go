@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func init() {
+	MustRegisterDescriptors(
+		Descriptor{Name: "test_bytes_total", Type: TypeCounter, Help: "Test counter.", Unit: UnitBytes, Labels: []string{"dev"}},
+		Descriptor{Name: "test_events_total", Type: TypeCounter, Help: "Test counter.", Labels: []string{"dev"}},
+		// Registered by collectors.CPUUtilizationProcfs in the agent.
+		Descriptor{Name: CPUTimeCounter, Type: TypeCounter, Help: "Test counter.", Unit: UnitSeconds, Labels: []string{"source", "cpu", "mode"}},
+	)
+}
+
+func TestCounterDelta(t *testing.T) {
+	tests := []struct {
+		name            string
+		prev, cur, want float64
+	}{
+		{"increase", 100, 150, 50},
+		{"unchanged", 100, 100, 0},
+		{"32-bit wrap", math.Pow(2, 32) - 10, 5, 15},
+		{"64-bit wrap", math.Pow(2, 64) - math.Pow(2, 60), math.Pow(2, 10), math.Pow(2, 60) + math.Pow(2, 10)},
+		{"reset well below the 32-bit limit", 1e6, 300, 300},
+		{"reset to zero", 1e6, 0, 0},
+		{"reset between the limits", math.Pow(2, 40), 7, 7},
+	}
+	for _, tt := range tests {
+		if got := counterDelta(tt.prev, tt.cur); got != tt.want {
+			t.Errorf("%s: counterDelta(%v, %v) = %v, want %v", tt.name, tt.prev, tt.cur, got, tt.want)
+		}
+	}
+}
+
+func TestRateStage(t *testing.T) {
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	sample := func(name, dev string, v float64, at time.Duration) Sample {
+		return Sample{Name: name, Value: v, Timestamp: start.Add(at), Labels: map[string]string{"dev": dev}}
+	}
+	rates := func(res Result) map[string]float64 {
+		out := map[string]float64{}
+		for _, s := range res.Samples {
+			if s.Name == "test_bytes_per_second" || s.Name == "test_events_per_second" {
+				out[s.Name+"/"+s.Labels["dev"]] = s.Value
+			}
+		}
+		return out
+	}
+
+	r := &RateStage{ExpireAfter: 2}
+	cycles := []struct {
+		name    string
+		samples []Sample
+		want    map[string]float64
+	}{
+		{"first sight has no rate", []Sample{
+			sample("test_bytes_total", "a", 1000, 0),
+			sample("test_events_total", "a", 10, 0),
+			sample("cpu_temp_celsius", "", 50, 0),
+		}, map[string]float64{}},
+		{"increase", []Sample{
+			sample("test_bytes_total", "a", 3000, 10*time.Second),
+			sample("test_events_total", "a", 15, 10*time.Second),
+			sample("test_bytes_total", "b", 0, 10*time.Second),
+		}, map[string]float64{"test_bytes_per_second/a": 200, "test_events_per_second/a": 0.5}},
+		{"reset and wrap", []Sample{
+			sample("test_bytes_total", "a", 500, 20*time.Second),
+			sample("test_events_total", "a", 5, 20*time.Second),
+			sample("test_bytes_total", "b", 100, 20*time.Second),
+		}, map[string]float64{"test_bytes_per_second/a": 50, "test_events_per_second/a": 0.5, "test_bytes_per_second/b": 10}},
+		{"same timestamp has no rate", []Sample{
+			sample("test_bytes_total", "a", 600, 20*time.Second),
+		}, map[string]float64{}},
+		{"b missing for a second cycle expires", []Sample{
+			sample("test_bytes_total", "a", 700, 30*time.Second),
+		}, map[string]float64{"test_bytes_per_second/a": 10}},
+		{"expired series starts over", []Sample{
+			sample("test_bytes_total", "a", 800, 40*time.Second),
+			sample("test_bytes_total", "b", 200, 40*time.Second),
+		}, map[string]float64{"test_bytes_per_second/a": 10}},
+		{"after expiry", []Sample{
+			sample("test_bytes_total", "b", 400, 50*time.Second),
+		}, map[string]float64{"test_bytes_per_second/b": 20}},
+	}
+	for _, c := range cycles {
+		got := rates(r.Process(Result{Samples: c.samples}))
+		if len(got) != len(c.want) {
+			t.Errorf("%s: rates = %v, want %v", c.name, got, c.want)
+			continue
+		}
+		for k, v := range c.want {
+			if got[k] != v {
+				t.Errorf("%s: %s = %v, want %v", c.name, k, got[k], v)
+			}
+		}
+	}
+	if len(r.series) != 2 {
+		t.Errorf("%d series tracked, want 2 (test_bytes_total a and b)", len(r.series))
+	}
+}
+
+func TestRateStageNames(t *testing.T) {
+	r := &RateStage{Names: []string{"test_events_total"}}
+	at := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	var out Result
+	for i := 0; i < 2; i++ {
+		out = r.Process(Result{Samples: []Sample{
+			{Name: "test_bytes_total", Value: float64(i * 100), Timestamp: at.Add(time.Duration(i) * time.Second)},
+			{Name: "test_events_total", Value: float64(i * 3), Timestamp: at.Add(time.Duration(i) * time.Second)},
+		}})
+	}
+	if len(out.Samples) != 3 || out.Samples[2].Name != "test_events_per_second" || out.Samples[2].Value != 3 {
+		t.Errorf("samples = %+v, want the two counters and the events rate", out.Samples)
+	}
+}
+
+func TestCPUUtilization(t *testing.T) {
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	counters := func(at time.Duration, busy, idle float64) []Sample {
+		var out []Sample
+		for cpu, scale := range map[string]float64{"total": 4, "cpu0": 1} {
+			for mode, v := range map[string]float64{"busy": busy, "idle": idle} {
+				out = append(out, Sample{
+					Name:      CPUTimeCounter,
+					Value:     v * scale,
+					Unit:      UnitSeconds,
+					Timestamp: start.Add(at),
+					Labels:    map[string]string{"source": "procfs", "cpu": cpu, "mode": mode},
+				})
+			}
+		}
+		return out
+	}
+	util := func(res Result) map[string]float64 {
+		out := map[string]float64{}
+		for _, s := range res.Samples {
+			if s.Name == "cpu_utilization" {
+				if _, ok := s.Labels["mode"]; ok {
+					t.Errorf("cpu_utilization has a mode label: %v", s.Labels)
+				}
+				out[s.Labels["cpu"]] = s.Value
+			}
+		}
+		return out
+	}
+	process := func(r *RateStage, samples []Sample) map[string]float64 {
+		return util(CPUUtilization{}.Process(r.Process(Result{Samples: samples})))
+	}
+
+	r := &RateStage{Names: []string{CPUTimeCounter}}
+	if got := process(r, counters(0, 100, 900)); len(got) != 0 {
+		t.Errorf("first cycle: utilization %v, want none", got)
+	}
+	// 10s later: 2.5s busy, 7.5s idle per core.
+	if got := process(r, counters(10*time.Second, 102.5, 907.5)); len(got) != 2 || got["total"] != 25 || got["cpu0"] != 25 {
+		t.Errorf("second cycle: utilization %v, want 25%% for total and cpu0", got)
+	}
+	// No time passed on the counters: no utilization rather than a division by zero.
+	if got := process(r, counters(20*time.Second, 102.5, 907.5)); len(got) != 0 {
+		t.Errorf("idle counters: utilization %v, want none", got)
+	}
+
+	// Without the rates there is nothing to derive from.
+	if got := util(CPUUtilization{}.Process(Result{Samples: counters(0, 1, 1)})); len(got) != 0 {
+		t.Errorf("without RateStage: utilization %v, want none", got)
+	}
+}
@@ -0,0 +1,216 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	RelabelKeep      = "keep"      // keep samples whose joined source labels match Regex
+	RelabelDrop      = "drop"      // drop samples whose joined source labels match Regex
+	RelabelReplace   = "replace"   // set TargetLabel to Replacement (with $1 etc.) when Regex matches
+	RelabelLabelDrop = "labeldrop" // remove labels whose name matches Regex
+
+	// MetricNameLabel addresses the metric name in SourceLabels / TargetLabel, as in Prometheus.
+	MetricNameLabel = "__name__"
+)
+
+// RelabelRule is a Prometheus-style relabel rule. Regexes are fully anchored.
+// A replace that produces an empty value removes the target label.
+type RelabelRule struct {
+	Action       string   `json:"action"`
+	SourceLabels []string `json:"source_labels,omitempty"`
+	Separator    string   `json:"separator,omitempty"`    // default ";"
+	Regex        string   `json:"regex,omitempty"`        // default "(.*)"
+	TargetLabel  string   `json:"target_label,omitempty"` // replace only
+	Replacement  string   `json:"replacement,omitempty"`  // default "$1"
+
+	re *regexp.Regexp
+}
+
+// RelabelConfig is the JSON file given to -relabel-config: rules applied in the runner
+// before any exporter, plus extra rules per exporter name ("console", "discord", ...).
+type RelabelConfig struct {
+	Relabel   []RelabelRule            `json:"relabel,omitempty"`
+	Exporters map[string][]RelabelRule `json:"exporters,omitempty"`
+}
+
+// LoadRelabelConfig reads and compiles a relabel config file.
+func LoadRelabelConfig(path string) (RelabelConfig, error) {
+	var cfg RelabelConfig
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("read relabel config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("parse relabel config %s: %w", path, err)
+	}
+
+	if err := CompileRelabelRules(cfg.Relabel); err != nil {
+		return cfg, fmt.Errorf("relabel config %s: %w", path, err)
+	}
+	for name, rules := range cfg.Exporters {
+		if err := CompileRelabelRules(rules); err != nil {
+			return cfg, fmt.Errorf("relabel config %s: exporter %s: %w", path, name, err)
+		}
+	}
+	return cfg, nil
+}
+
+// CompileRelabelRules validates rules and compiles their regexes in place.
+func CompileRelabelRules(rules []RelabelRule) error {
+	for i := range rules {
+		r := &rules[i]
+		switch r.Action {
+		case RelabelKeep, RelabelDrop:
+			if len(r.SourceLabels) == 0 {
+				return fmt.Errorf("rule %d (%s): source_labels is required", i, r.Action)
+			}
+		case RelabelReplace:
+			if r.TargetLabel == "" {
+				return fmt.Errorf("rule %d (%s): target_label is required", i, r.Action)
+			}
+		case RelabelLabelDrop:
+			if r.Regex == "" {
+				return fmt.Errorf("rule %d (%s): regex is required", i, r.Action)
+			}
+		default:
+			return fmt.Errorf("rule %d: unknown action %q (want keep, drop, replace or labeldrop)", i, r.Action)
+		}
+
+		expr := r.Regex
+		if expr == "" {
+			expr = "(.*)"
+		}
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return fmt.Errorf("rule %d (%s): regex: %w", i, r.Action, err)
+		}
+		r.re = re
+	}
+	return nil
+}
+
+// Relabeler is a Stage applying relabel rules, in order, to every sample.
+type Relabeler struct {
+	Rules []RelabelRule // compiled with CompileRelabelRules
+}
+
+func (r Relabeler) Process(res Result) Result {
+	if len(r.Rules) == 0 {
+		return res
+	}
+	out := make([]Sample, 0, len(res.Samples))
+	for _, s := range res.Samples {
+		if s, ok := relabelSample(s, r.Rules); ok {
+			out = append(out, s)
+		}
+	}
+	res.Samples = out
+	return res
+}
+
+func relabelSample(s Sample, rules []RelabelRule) (Sample, bool) {
+	// Label maps are often shared between samples of one collector; never mutate them.
+	labels := make(map[string]string, len(s.Labels)+1)
+	for k, v := range s.Labels {
+		labels[k] = v
+	}
+	labels[MetricNameLabel] = s.Name
+
+	for _, r := range rules {
+		switch r.Action {
+		case RelabelKeep, RelabelDrop:
+			matched := r.re.MatchString(joinLabelValues(labels, r.SourceLabels, r.Separator))
+			if matched == (r.Action == RelabelDrop) {
+				return s, false
+			}
+		case RelabelReplace:
+			src := joinLabelValues(labels, r.SourceLabels, r.Separator)
+			m := r.re.FindStringSubmatchIndex(src)
+			if m == nil {
+				continue
+			}
+			repl := r.Replacement
+			if repl == "" {
+				repl = "$1"
+			}
+			v := string(r.re.ExpandString(nil, repl, src, m))
+			if v == "" {
+				delete(labels, r.TargetLabel)
+			} else {
+				labels[r.TargetLabel] = v
+			}
+		case RelabelLabelDrop:
+			for k := range labels {
+				if k != MetricNameLabel && r.re.MatchString(k) {
+					delete(labels, k)
+				}
+			}
+		}
+	}
+
+	if name := labels[MetricNameLabel]; name != "" {
+		s.Name = name
+	}
+	delete(labels, MetricNameLabel)
+	if len(labels) == 0 {
+		labels = nil
+	}
+	s.Labels = labels
+	return s, true
+}
+
+func joinLabelValues(labels map[string]string, names []string, sep string) string {
+	if sep == "" {
+		sep = ";"
+	}
+	vals := make([]string, len(names))
+	for i, n := range names {
+		vals[i] = labels[n]
+	}
+	return strings.Join(vals, sep)
+}
+
+// StaticLabels is a Stage that attaches fixed labels (host, site, role, ...) to every
+// sample. Labels already set by a collector win.
+type StaticLabels struct {
+	Labels map[string]string
+}
+
+func (l StaticLabels) Process(res Result) Result {
+	if len(l.Labels) == 0 {
+		return res
+	}
+	for i := range res.Samples {
+		labels := make(map[string]string, len(res.Samples[i].Labels)+len(l.Labels))
+		for k, v := range l.Labels {
+			labels[k] = v
+		}
+		for k, v := range res.Samples[i].Labels {
+			labels[k] = v
+		}
+		res.Samples[i].Labels = labels
+	}
+	return res
+}
+
+// RelabelingExporter applies exporter-specific relabel rules before handing the
+// Result to Next, e.g. to drop the noisy "path" label from Discord posts only.
+type RelabelingExporter struct {
+	Next  Exporter
+	Rules []RelabelRule // compiled with CompileRelabelRules
+}
+
+func (e RelabelingExporter) Export(ctx context.Context, res Result) error {
+	// Relabeler copies samples and labels, so the shared Result is left untouched.
+	return e.Next.Export(ctx, Relabeler{Rules: e.Rules}.Process(res))
+}
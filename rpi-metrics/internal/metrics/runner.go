@@ -7,6 +7,18 @@ import (
 
 type Runner struct {
 	Collectors []Collector
+
+	// Stages post-process every collected Result, in order, before it is returned.
+	Stages []Stage
+
+	// Stats, if set, records per-collector durations and errors.
+	Stats *AgentStats
+}
+
+// Stage derives, rewrites or drops samples of a collected Result.
+// Stages may keep state between cycles and must be safe for concurrent use.
+type Stage interface {
+	Process(res Result) Result
 }
 
 type Result struct {
@@ -24,22 +36,37 @@ func (r Runner) CollectOnce(ctx context.Context) Result {
 
 	now := time.Now().UTC()
 	for _, c := range r.Collectors {
+		// Collectors may return partial samples alongside an error; keep both.
+		start := time.Now()
 		samples, err := c.Collect(ctx)
+		if r.Stats != nil {
+			r.Stats.ObserveCollect(c.ID(), time.Since(start), err)
+		}
 		if err != nil {
 			res.Errors = append(res.Errors, CollectorError{
 				CollectorID: c.ID(),
 				Error:       err.Error(),
 			})
-			continue
 		}
 		// Ensure timestamp is present; collectors may also set their own.
 		for i := range samples {
 			if samples[i].Timestamp.IsZero() {
 				samples[i].Timestamp = now
 			}
+			// Flag (but keep) samples that contradict their declared descriptor.
+			if msg := CheckSample(samples[i]); msg != "" {
+				res.Errors = append(res.Errors, CollectorError{
+					CollectorID: c.ID(),
+					Error:       "descriptor violation: " + msg,
+				})
+			}
 		}
 		res.Samples = append(res.Samples, samples...)
 	}
 
+	for _, st := range r.Stages {
+		res = st.Process(res)
+	}
+
 	return res
 }
@@ -1,6 +1,10 @@
 package metrics
 
-import "time"
+import (
+	"sort"
+	"strings"
+	"time"
+)
 
 type Sample struct {
 	Name      string            `json:"name"`
@@ -9,3 +13,23 @@ type Sample struct {
 	Timestamp time.Time         `json:"ts"`
 	Labels    map[string]string `json:"labels,omitempty"`
 }
+
+// SeriesKey identifies a time series by metric name and sorted label pairs, e.g.
+// "cpu_temp_celsius|host=pi|zone=cpu".
+func SeriesKey(name string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteByte('|')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+	}
+	return b.String()
+}
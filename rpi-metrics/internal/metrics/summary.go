@@ -0,0 +1,445 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"rpi-metrics/constants"
+)
+
+// Summary accumulates the Results of a window (e.g. a day) for a digest: min, avg, p95
+// and max per series, the alerts seen and collector error counts. The zero value is
+// empty and ready to use; Reset starts the next window.
+type Summary struct {
+	Start, End time.Time
+	Cycles     int
+
+	series  []*SeriesSummary // in first-seen order
+	byKey   map[string]*SeriesSummary
+	alerts  map[string]*alertSummary
+	errors  map[string]*errorSummary
+	windows int // completed windows (Reset calls)
+}
+
+// SeriesSummary is one series (name and labels) over a Summary's window.
+type SeriesSummary struct {
+	Name   string
+	Labels map[string]string
+	Unit   string
+
+	Min, Max, Sum float64
+	Count         int
+	MaxAt         time.Time
+
+	// First is the value the window started from: the previous window's Last, so
+	// Last-First is the change since the previous report.
+	First, Last float64
+
+	// TrueFor is how long a bool series was 1.
+	TrueFor time.Duration
+
+	values    []float64 // a uniform sample of at most percentileSamples values
+	lastAt    time.Time
+	lastValue float64
+}
+
+// percentileSamples bounds the values a series keeps for its p95, so a day of 2s
+// cycles doesn't keep 43200 values per series on a Pi Zero.
+const percentileSamples = 1024
+
+// Avg is the mean of the series' values.
+func (s *SeriesSummary) Avg() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// P95 is the 95th percentile of the series' values, estimated from a sample of at
+// most percentileSamples of them.
+func (s *SeriesSummary) P95() float64 {
+	if len(s.values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), s.values...)
+	sort.Float64s(sorted)
+	return sorted[int(math.Ceil(0.95*float64(len(sorted))))-1]
+}
+
+type alertSummary struct {
+	alert  Alert // the latest, whose message has the latest value
+	cycles int
+}
+
+type errorSummary struct {
+	cycles int
+	last   string
+}
+
+// Add adds one collection cycle.
+func (s *Summary) Add(res Result) {
+	if s.byKey == nil {
+		s.byKey = map[string]*SeriesSummary{}
+		s.alerts = map[string]*alertSummary{}
+		s.errors = map[string]*errorSummary{}
+	}
+	at := time.Now()
+	for _, smp := range res.Samples {
+		if !smp.Timestamp.IsZero() {
+			at = smp.Timestamp
+			break
+		}
+	}
+	if s.Start.IsZero() {
+		s.Start = at
+	}
+	s.End = at
+	s.Cycles++
+
+	for _, smp := range res.Samples {
+		ts := smp.Timestamp
+		if ts.IsZero() {
+			ts = at
+		}
+		key := SeriesKey(smp.Name, smp.Labels)
+		ss := s.byKey[key]
+		if ss == nil {
+			ss = &SeriesSummary{Name: smp.Name, Labels: smp.Labels, Unit: smp.Unit, First: smp.Value}
+			s.byKey[key] = ss
+			s.series = append(s.series, ss)
+		}
+		if ss.Count == 0 || smp.Value < ss.Min {
+			ss.Min = smp.Value
+		}
+		if ss.Count == 0 || smp.Value > ss.Max {
+			ss.Max, ss.MaxAt = smp.Value, ts
+		}
+		if ss.Unit == UnitBool && ss.lastValue != 0 && !ss.lastAt.IsZero() {
+			ss.TrueFor += ts.Sub(ss.lastAt)
+		}
+		ss.Sum += smp.Value
+		ss.Count++
+		ss.Last = smp.Value
+		ss.lastAt, ss.lastValue = ts, smp.Value
+
+		if len(ss.values) < percentileSamples {
+			ss.values = append(ss.values, smp.Value)
+		} else if i := rand.IntN(ss.Count); i < percentileSamples {
+			ss.values[i] = smp.Value
+		}
+	}
+
+	for _, a := range DetectAlerts(res) {
+		key := AlertKey(a)
+		if s.alerts[key] == nil {
+			s.alerts[key] = &alertSummary{}
+		}
+		s.alerts[key].alert = a
+		s.alerts[key].cycles++
+	}
+	for _, e := range res.Errors {
+		if s.errors[e.CollectorID] == nil {
+			s.errors[e.CollectorID] = &errorSummary{}
+		}
+		s.errors[e.CollectorID].cycles++
+		s.errors[e.CollectorID].last = e.Error
+	}
+}
+
+// Reset starts the next window where this one ended. Series keep their Last value as
+// the next window's First; series that were not seen in this window are dropped.
+func (s *Summary) Reset() {
+	kept := s.series[:0]
+	for _, ss := range s.series {
+		if ss.Count == 0 {
+			delete(s.byKey, SeriesKey(ss.Name, ss.Labels))
+			continue
+		}
+		*ss = SeriesSummary{
+			Name: ss.Name, Labels: ss.Labels, Unit: ss.Unit,
+			First: ss.Last, Last: ss.Last,
+			values: ss.values[:0], lastAt: ss.lastAt, lastValue: ss.lastValue,
+		}
+		kept = append(kept, ss)
+	}
+	s.series = kept
+	s.alerts = map[string]*alertSummary{}
+	s.errors = map[string]*errorSummary{}
+	s.Start, s.Cycles = s.End, 0
+	s.windows++
+}
+
+// SummarySection is a titled group of lines of a digest. Text and HTML renderings
+// share the sections, so they group like the Discord message.
+type SummarySection struct {
+	Title string // "" for the ungrouped series
+	Lines []string
+}
+
+// Sections groups the summary like FormatMessage: alerts, highlights (peak
+// temperatures, time throttled, storage changes, collector errors), CPU utilization,
+// SD card wear, one line per other series, then collector errors.
+func (s *Summary) Sections() []SummarySection {
+	var out []SummarySection
+	add := func(title string, lines []string) {
+		if len(lines) > 0 {
+			out = append(out, SummarySection{Title: title, Lines: lines})
+		}
+	}
+
+	alerts := make([]*alertSummary, 0, len(s.alerts))
+	for _, a := range s.alerts {
+		alerts = append(alerts, a)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		ai, aj := alerts[i].alert, alerts[j].alert
+		if ai.Severity != aj.Severity {
+			return ai.Severity == SeverityCritical
+		}
+		return ai.Message < aj.Message
+	})
+	var lines []string
+	for _, a := range alerts {
+		lines = append(lines, fmt.Sprintf("[%s] %s (%s)", strings.ToUpper(a.alert.Severity), a.alert.Message, s.cyclesText(a.cycles)))
+	}
+	add("Alerts", lines)
+	add("Highlights", s.highlights())
+
+	var cpu, mmc, other []*SeriesSummary
+	for _, ss := range s.series {
+		switch {
+		case ss.Count == 0: // not seen in this window
+		case ss.Name == "cpu_utilization":
+			cpu = append(cpu, ss)
+		case strings.HasPrefix(ss.Name, "mmc_"):
+			mmc = append(mmc, ss)
+		default:
+			other = append(other, ss)
+		}
+	}
+
+	sort.SliceStable(cpu, func(i, j int) bool { return cpuOrder(cpu[i]) < cpuOrder(cpu[j]) })
+	lines = nil
+	for _, ss := range cpu {
+		name := ss.Labels["cpu"]
+		if name == "" || name == "total" {
+			name = "overall"
+		}
+		lines = append(lines, name+": "+s.formatSeries(ss))
+	}
+	add("CPU Utilization", lines)
+
+	sort.SliceStable(mmc, func(i, j int) bool { return mmc[i].Labels["device"] < mmc[j].Labels["device"] })
+	lines = nil
+	for _, ss := range mmc {
+		lines = append(lines, fmt.Sprintf("%s %s", ss.Labels["device"], mmcSummaryLine(ss)))
+	}
+	add("SD card wear", lines)
+
+	lines = nil
+	for _, ss := range other {
+		lines = append(lines, ss.Name+summaryLabels(ss.Labels)+": "+s.formatSeries(ss))
+	}
+	add("", lines)
+
+	ids := make([]string, 0, len(s.errors))
+	for id := range s.errors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	lines = nil
+	for _, id := range ids {
+		e := s.errors[id]
+		lines = append(lines, fmt.Sprintf("%s: failed in %s, last: %s", id, s.cyclesText(e.cycles), e.last))
+	}
+	add("Errors", lines)
+	return out
+}
+
+// highlights picks what a glance at a digest should tell: peak temperatures and when,
+// how long the Pi was throttled, how storage use changed and the collector errors.
+func (s *Summary) highlights() []string {
+	var lines, throttle []string
+	throttleSeen := false
+	window := s.End.Sub(s.Start)
+	since := "since the previous report"
+	if s.windows == 0 {
+		since = "since the agent started"
+	}
+
+	for _, ss := range s.series {
+		if ss.Count == 0 {
+			continue
+		}
+		switch {
+		case ss.Unit == UnitCelsius:
+			lines = append(lines, fmt.Sprintf("peak %s%s: %s at %s", ss.Name, summaryLabels(ss.Labels), summaryValue(ss.Max, ss.Unit), s.clock(ss.MaxAt)))
+		case ss.Name == "firmware_throttle_active":
+			throttleSeen = true
+			if ss.TrueFor > 0 {
+				text := fmt.Sprintf("%s for %s", strings.ReplaceAll(ss.Labels["condition"], "_", " "), ss.TrueFor.Round(time.Second))
+				if window > 0 {
+					text += fmt.Sprintf(" (%.1f%% of the time)", 100*ss.TrueFor.Seconds()/window.Seconds())
+				}
+				throttle = append(throttle, text)
+			}
+		case ss.Name == "storage_used_bytes":
+			mount := ss.Labels["mount_point"]
+			if mount == "" {
+				mount = ss.Labels["path"]
+			}
+			change := ss.Last - ss.First
+			sign := "+"
+			if change < 0 {
+				sign, change = "-", -change
+			}
+			lines = append(lines, fmt.Sprintf("storage %s: %s%s used %s (%s now)", mount, sign, HumanBytes(change), since, HumanBytes(ss.Last)))
+		}
+	}
+	switch {
+	case len(throttle) > 0:
+		lines = append(lines, throttle...)
+	case throttleSeen:
+		lines = append(lines, "no under-voltage or throttling")
+	}
+
+	total := 0
+	var perCollector []string
+	for id, e := range s.errors {
+		total += e.cycles
+		perCollector = append(perCollector, fmt.Sprintf("%s %d", id, e.cycles))
+	}
+	sort.Strings(perCollector)
+	if total == 0 {
+		lines = append(lines, "no collector errors")
+	} else {
+		lines = append(lines, fmt.Sprintf("collector errors: %d (%s)", total, strings.Join(perCollector, ", ")))
+	}
+	return lines
+}
+
+// clock renders a time in the window: the local time of day, with the date when the
+// window spans more than a day.
+func (s *Summary) clock(t time.Time) string {
+	if s.End.Sub(s.Start) > 24*time.Hour {
+		return t.Local().Format("Jan 2 15:04")
+	}
+	return t.Local().Format("15:04")
+}
+
+// cpuOrder sorts the overall utilization first, then the cores.
+func cpuOrder(ss *SeriesSummary) string {
+	if cpu := ss.Labels["cpu"]; cpu != "" && cpu != "total" {
+		return "1" + cpu
+	}
+	return "0"
+}
+
+func (s *Summary) cyclesText(n int) string {
+	return fmt.Sprintf("%d of %d cycles", n, s.Cycles)
+}
+
+// formatSeries renders min/avg/max, the increase of a counter, or the latest value
+// where those mean nothing.
+func (s *Summary) formatSeries(ss *SeriesSummary) string {
+	if d, ok := LookupDescriptor(ss.Name); ok && d.Type == TypeCounter {
+		return fmt.Sprintf("+%s (%s total)", summaryValue(ss.Last-ss.First, ss.Unit), summaryValue(ss.Last, ss.Unit))
+	}
+	switch ss.Unit {
+	case UnitTimestamp, UnitState:
+		return "latest " + FormatValue(ss.Last, ss.Unit)
+	case UnitBool:
+		if ss.TrueFor > 0 {
+			return fmt.Sprintf("yes for %s (in %.0f%% of cycles)", ss.TrueFor.Round(time.Second), 100*ss.Avg())
+		}
+		return fmt.Sprintf("yes in %.0f%% of cycles", 100*ss.Avg())
+	}
+	return fmt.Sprintf("min %s / avg %s / p95 %s / max %s",
+		summaryValue(ss.Min, ss.Unit), summaryValue(ss.Avg(), ss.Unit), summaryValue(ss.P95(), ss.Unit), summaryValue(ss.Max, ss.Unit))
+}
+
+// summaryValue is a compact FormatValue: digests show three values per line.
+func summaryValue(v float64, unit string) string {
+	switch unit {
+	case UnitBytes:
+		return HumanBytes(v)
+	case UnitCelsius:
+		return fmt.Sprintf("%.1f °C", v)
+	case UnitPercent:
+		return fmt.Sprintf("%.1f%%", v)
+	case UnitSeconds:
+		d := time.Duration(v * float64(time.Second))
+		if d < time.Second {
+			return d.Round(time.Microsecond).String()
+		}
+		return d.Round(time.Second).String()
+	case UnitCount:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return FormatValue(v, unit)
+}
+
+func mmcSummaryLine(ss *SeriesSummary) string {
+	switch ss.Name {
+	case "mmc_info":
+		return fmt.Sprintf("card: %s (manfid %s, date %s)", ss.Labels["name"], ss.Labels["manfid"], ss.Labels["date"])
+	case "mmc_life_time_used_percent":
+		return fmt.Sprintf("life time used (type %s): %.0f%%", ss.Labels["type"], ss.Last)
+	case "mmc_pre_eol_info":
+		return "pre-EOL: " + mmcPreEOLText(ss.Last)
+	case "mmc_bytes_written_total":
+		return fmt.Sprintf("written: %s (+%s)", HumanBytes(ss.Last), HumanBytes(ss.Last-ss.First))
+	case "mmc_estimated_writes_per_day_bytes":
+		return "writes/day: " + HumanBytes(ss.Last)
+	}
+	return ss.Name + ": " + FormatValue(ss.Last, ss.Unit)
+}
+
+// summaryLabels renders labels as {k=v,...}, leaving out host, which every sample of
+// an agent has.
+func summaryLabels(labels map[string]string) string {
+	var parts []string
+	for k, v := range labels {
+		if k != "host" {
+			parts = append(parts, k+"="+v)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	sort.Strings(parts)
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// FormatSummary renders a Summary as text, grouped like a Discord message.
+func FormatSummary(s *Summary) string {
+	return strings.Repeat("-", constants.DiscordMessageSeparatorLen) + "\n" + FormatSections(s.Heading(), s.Sections())
+}
+
+// FormatSections renders sections as in Discord messages: the heading, then per
+// section a "Title:" line and one "- " line each (untitled lines as they are).
+func FormatSections(heading string, sections []SummarySection) string {
+	var b strings.Builder
+	b.WriteString(heading + ":")
+	for _, sec := range sections {
+		prefix := ""
+		if sec.Title != "" {
+			b.WriteString("\n" + sec.Title + ":")
+			prefix = "- "
+		}
+		for _, l := range sec.Lines {
+			b.WriteString("\n" + prefix + l)
+		}
+	}
+	return b.String()
+}
+
+// Heading describes the window, e.g. "Digest 2026-10-17 08:00 CEST to 2026-10-18 08:00 CEST (1440 cycles)".
+func (s *Summary) Heading() string {
+	const layout = "2006-01-02 15:04 MST"
+	return fmt.Sprintf("Digest %s to %s (%d cycles)", s.Start.Local().Format(layout), s.End.Local().Format(layout), s.Cycles)
+}
@@ -0,0 +1,266 @@
+// Package schedule parses report schedules: five-field cron expressions and a few
+// phrases that translate to them.
+//
+//	every day 08:00            0 8 * * *
+//	every weekday 08:00        0 8 * * 1-5
+//	every monday 09:30         30 9 * * 1
+//	every hour                 0 * * * *
+//	every 15m, every 6h        */15 * * * *, 0 */6 * * *
+//
+// Times are local unless the schedule ends in "utc"; a trailing "local" is accepted.
+// As with cron, a time skipped when daylight saving time begins fires right after the
+// clocks spring forward, and a time of day repeated when it ends fires once.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed schedule. The zero value never fires.
+type Schedule struct {
+	spec string
+
+	minute, hour, dom, month, dow uint64 // bit i set: value i matches
+	domAny, dowAny                bool   // the field was "*"
+	utc                           bool
+}
+
+var weekdays = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+var months = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+
+// Parse parses a cron expression ("minute hour day-of-month month day-of-week") or
+// one of the phrases in the package comment.
+func Parse(spec string) (Schedule, error) {
+	fields := strings.Fields(strings.ToLower(spec))
+	s := Schedule{spec: strings.TrimSpace(spec)}
+	if n := len(fields); n > 0 && (fields[n-1] == "utc" || fields[n-1] == "local") {
+		s.utc = fields[n-1] == "utc"
+		fields = fields[:n-1]
+	}
+
+	if len(fields) > 0 && fields[0] == "every" {
+		expr, err := phrase(fields[1:])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("schedule %q: %w", spec, err)
+		}
+		fields = strings.Fields(expr)
+	}
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("schedule %q: want a cron expression (minute hour day month weekday) or e.g. \"every day 08:00\"", spec)
+	}
+
+	var err error
+	parse := func(field string, lo, hi int, names map[string]int) uint64 {
+		if err != nil {
+			return 0
+		}
+		var bits uint64
+		bits, err = parseField(field, lo, hi, names)
+		return bits
+	}
+	s.minute = parse(fields[0], 0, 59, nil)
+	s.hour = parse(fields[1], 0, 23, nil)
+	s.dom = parse(fields[2], 1, 31, nil)
+	s.month = parse(fields[3], 1, 12, months)
+	s.dow = parse(fields[4], 0, 7, weekdays)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("schedule %q: %w", spec, err)
+	}
+	if s.dow&(1<<7) != 0 { // 7 is Sunday too
+		s.dow |= 1
+	}
+	s.domAny, s.dowAny = fields[2] == "*", fields[4] == "*"
+
+	if s.Next(time.Now()).IsZero() {
+		return Schedule{}, fmt.Errorf("schedule %q never fires", spec)
+	}
+	return s, nil
+}
+
+// phrase translates the words after "every" to a cron expression.
+func phrase(words []string) (string, error) {
+	if len(words) == 1 {
+		switch words[0] {
+		case "hour":
+			return "0 * * * *", nil
+		case "minute":
+			return "* * * * *", nil
+		}
+		d, err := time.ParseDuration(words[0])
+		if err != nil {
+			return "", fmt.Errorf("want e.g. \"every day 08:00\", \"every monday 09:30\" or \"every 6h\"")
+		}
+		switch {
+		case d >= time.Minute && d < time.Hour && d%time.Minute == 0 && time.Hour%d == 0:
+			return fmt.Sprintf("*/%d * * * *", d/time.Minute), nil
+		case d >= time.Hour && d <= 24*time.Hour && d%time.Hour == 0 && (24*time.Hour)%d == 0:
+			return fmt.Sprintf("0 */%d * * *", d/time.Hour), nil
+		}
+		return "", fmt.Errorf("every %s: the interval must divide an hour (e.g. 15m) or a day (e.g. 6h)", words[0])
+	}
+	if len(words) != 2 {
+		return "", fmt.Errorf("want e.g. \"every day 08:00\", \"every monday 09:30\" or \"every 6h\"")
+	}
+
+	hm, err := time.Parse("15:04", words[1])
+	if err != nil {
+		return "", fmt.Errorf("invalid time %q: want HH:MM", words[1])
+	}
+	var dow string
+	switch day := strings.TrimSuffix(words[0], "s"); {
+	case day == "day":
+		dow = "*"
+	case day == "weekday":
+		dow = "1-5"
+	case day == "weekend":
+		dow = "0,6"
+	case len(day) >= 3 && isWeekday(day):
+		dow = strconv.Itoa(weekdays[day[:3]])
+	default:
+		return "", fmt.Errorf("unknown day %q: want day, weekday, weekend or a day of the week", words[0])
+	}
+	return fmt.Sprintf("%d %d * * %s", hm.Minute(), hm.Hour(), dow), nil
+}
+
+// isWeekday reports whether day names a day of the week: "mon", "monday", "tues"...
+func isWeekday(day string) bool {
+	for _, name := range []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"} {
+		if strings.HasPrefix(name, day) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseField parses a comma separated list of *, n, a-b and their /step forms.
+func parseField(field string, lo, hi int, names map[string]int) (uint64, error) {
+	value := func(s string) (int, error) {
+		if v, ok := names[s]; ok {
+			return v, nil
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil || v < lo || v > hi {
+			return 0, fmt.Errorf("invalid value %q in %q: want %d-%d", s, field, lo, hi)
+		}
+		return v, nil
+	}
+
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in %q", stepText, field)
+			}
+		}
+
+		first, last := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if first, err = value(a); err != nil {
+				return 0, err
+			}
+			last = first
+			if isRange {
+				if last, err = value(b); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				last = hi // "5/15": from 5 on
+			}
+			if last < first {
+				return 0, fmt.Errorf("invalid range %q in %q", rng, field)
+			}
+		}
+		for v := first; v <= last; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// IsZero reports whether s is the zero Schedule, which never fires.
+func (s Schedule) IsZero() bool { return s.minute == 0 }
+
+// String returns the schedule as it was given.
+func (s Schedule) String() string { return s.spec }
+
+// Next returns the first time after t the schedule fires, or the zero time if it
+// doesn't within five years.
+func (s Schedule) Next(t time.Time) time.Time {
+	if s.IsZero() {
+		return time.Time{}
+	}
+	loc := time.Local
+	if s.utc {
+		loc = time.UTC
+	}
+	t = t.In(loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		var next time.Time
+		switch {
+		case s.month&(1<<uint(m)) == 0:
+			next = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			next = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			next = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		if !next.After(t) { // a daylight saving time change mapped back
+			next = t.Add(time.Minute)
+		}
+		if s.skippedBy(t, next) {
+			return next // as cron does: right after the clocks sprang forward over it
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+// skippedBy reports whether moving from t to next on the same day skips hours the
+// schedule fires in, because daylight saving time began in between.
+func (s Schedule) skippedBy(t, next time.Time) bool {
+	y, m, d := t.Date()
+	ny, nm, nd := next.Date()
+	if ny != y || nm != m || nd != d || s.month&(1<<uint(m)) == 0 || !s.dayMatches(t) {
+		return false
+	}
+	for h := t.Hour() + 1; h < next.Hour(); h++ {
+		if s.hour&(1<<uint(h)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// dayMatches applies cron's rule: when both the day of month and the day of week are
+// restricted, either may match.
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,164 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		spec, want string
+	}{
+		{"", "want a cron expression"},
+		{"* * *", "want a cron expression"},
+		{"61 * * * *", `invalid value "61"`},
+		{"* * * * 8", `invalid value "8"`},
+		{"5-1 * * * *", `invalid range "5-1"`},
+		{"*/0 * * * *", `invalid step "0"`},
+		{"0 0 31 2 *", "never fires"},
+		{"every 7h", "must divide an hour (e.g. 15m) or a day (e.g. 6h)"},
+		{"every 45m", "must divide an hour"},
+		{"every 90s", "must divide an hour"},
+		{"every fortnight", "want e.g."},
+		{"every fortnight 08:00", `unknown day "fortnight"`},
+		{"every day 8am", `invalid time "8am"`},
+		{"every day 08:00 09:00", "want e.g."},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.spec)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want it to contain %q", tt.spec, err, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	utc := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		spec string
+		from string
+		want []string // successive firings after from
+	}{
+		{"every 6h utc", "2026-10-18 07:10", []string{"2026-10-18 12:00", "2026-10-18 18:00", "2026-10-19 00:00"}},
+		{"0 */6 * * * UTC", "2026-10-18 18:00", []string{"2026-10-19 00:00"}},
+		{"every 15m utc", "2026-10-18 10:07", []string{"2026-10-18 10:15", "2026-10-18 10:30"}},
+		{"every hour utc", "2026-10-18 10:00", []string{"2026-10-18 11:00"}},
+		{"every day 08:00 utc", "2026-10-18 08:00", []string{"2026-10-19 08:00"}},
+		{"every weekday 08:00 utc", "2026-10-16 09:00", []string{"2026-10-19 08:00", "2026-10-20 08:00"}},
+		{"every weekends 10:30 utc", "2026-10-16 09:00", []string{"2026-10-17 10:30", "2026-10-18 10:30", "2026-10-24 10:30"}},
+		{"every tues 07:05 utc", "2026-10-18 00:00", []string{"2026-10-20 07:05"}},
+
+		// 0 and 7 are both Sunday.
+		{"0 8 * * 7 utc", "2026-10-17 12:00", []string{"2026-10-18 08:00", "2026-10-25 08:00"}},
+		{"0 8 * * 0 utc", "2026-10-17 12:00", []string{"2026-10-18 08:00"}},
+		{"0 8 * * sun utc", "2026-10-17 12:00", []string{"2026-10-18 08:00"}},
+		{"0 8 * * 5-7 utc", "2026-10-15 12:00", []string{"2026-10-16 08:00", "2026-10-17 08:00", "2026-10-18 08:00", "2026-10-23 08:00"}},
+
+		// Day of month and day of week both restricted: either matches.
+		{"0 9 13 * 5 utc", "2026-10-10 00:00", []string{"2026-10-13 09:00", "2026-10-16 09:00", "2026-10-23 09:00"}},
+		{"0 9 13 * * utc", "2026-10-10 00:00", []string{"2026-10-13 09:00", "2026-11-13 09:00"}},
+		{"0 9 * * 5 utc", "2026-10-10 00:00", []string{"2026-10-16 09:00"}},
+
+		{"0 0 29 2 * utc", "2026-01-01 00:00", []string{"2028-02-29 00:00"}},
+		{"0 0 1 jan,jul * utc", "2026-10-18 00:00", []string{"2027-01-01 00:00", "2027-07-01 00:00"}},
+		{"5-20/5 3 * * * utc", "2026-10-18 03:12", []string{"2026-10-18 03:15", "2026-10-18 03:20", "2026-10-19 03:05"}},
+		{"50/5 * * * * utc", "2026-10-18 03:12", []string{"2026-10-18 03:50", "2026-10-18 03:55", "2026-10-18 04:50"}},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.spec, err)
+			continue
+		}
+		at := utc(tt.from)
+		for _, w := range tt.want {
+			at = s.Next(at)
+			if want := utc(w); !at.Equal(want) {
+				t.Errorf("%q: Next = %s, want %s", tt.spec, at.Format("2006-01-02 15:04 Mon"), w)
+				break
+			}
+		}
+	}
+}
+
+// TestNextDST runs local schedules across Europe/Berlin's 2026 clock changes: the
+// clocks jump from 02:00 to 03:00 on March 29 and fall back from 03:00 to 02:00 on
+// October 25.
+func TestNextDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	local := time.Local
+	time.Local = berlin
+	t.Cleanup(func() { time.Local = local })
+
+	at := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse("2006-01-02 15:04 -07:00", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		name string
+		spec string
+		from string
+		want []string
+	}{
+		{"skipped time fires after the jump", "30 2 * * *", "2026-03-28 12:00 +01:00",
+			[]string{"2026-03-29 03:00 +02:00", "2026-03-30 02:30 +02:00"}},
+		{"skipped hour of a list", "45 1,2 * * *", "2026-03-29 01:50 +01:00",
+			[]string{"2026-03-29 03:00 +02:00", "2026-03-30 01:45 +02:00"}},
+		{"times after the jump are kept", "30 3 * * *", "2026-03-28 12:00 +01:00",
+			[]string{"2026-03-29 03:30 +02:00"}},
+		{"interval across the jump", "*/15 * * * *", "2026-03-29 01:50 +01:00",
+			[]string{"2026-03-29 03:00 +02:00", "2026-03-29 03:15 +02:00"}},
+		{"every 6h across the jump", "every 6h", "2026-03-28 23:00 +01:00",
+			[]string{"2026-03-29 00:00 +01:00", "2026-03-29 06:00 +02:00"}},
+		{"repeated time fires once", "30 2 * * *", "2026-10-24 12:00 +02:00",
+			[]string{"2026-10-25 02:30 +01:00", "2026-10-26 02:30 +01:00"}},
+		{"every 6h across the fall back", "every 6h", "2026-10-24 23:00 +02:00",
+			[]string{"2026-10-25 00:00 +02:00", "2026-10-25 06:00 +01:00"}},
+		{"utc ignores local time", "30 2 * * * utc", "2026-03-28 12:00 +01:00",
+			[]string{"2026-03-29 02:30 +00:00", "2026-03-30 02:30 +00:00"}},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("%s: Parse(%q): %v", tt.name, tt.spec, err)
+			continue
+		}
+		next := at(tt.from)
+		for _, w := range tt.want {
+			next = s.Next(next)
+			if want := at(w); !next.Equal(want) {
+				t.Errorf("%s: %q: Next = %s, want %s", tt.name, tt.spec, next.Format("2006-01-02 15:04 -07:00"), w)
+				break
+			}
+		}
+	}
+}
+
+func TestZeroSchedule(t *testing.T) {
+	var s Schedule
+	if !s.IsZero() || !s.Next(time.Now()).IsZero() {
+		t.Error("the zero Schedule fires")
+	}
+	s, err := Parse(" every day 08:00 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.IsZero() || s.String() != "every day 08:00" {
+		t.Errorf("IsZero = %v, String = %q", s.IsZero(), s.String())
+	}
+}
@@ -0,0 +1,173 @@
+// Package secret resolves credential-bearing options and keeps their values out of
+// logs and error messages.
+//
+// An option value may be a literal, or a reference that keeps the credential off the
+// command line (ps, unit files, shell history):
+//
+//	env:DISCORD_WEBHOOK                              the environment variable's value
+//	file:/run/credentials/rpi-metrics.service/push   the file's contents (systemd
+//	                                                 LoadCredential, Docker secrets)
+//
+// Resolved values are registered, and Redact replaces them in any text.
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces secret values in redacted text.
+const Placeholder = "[REDACTED]"
+
+// minLength is the shortest value Register will redact, so a one-character token
+// doesn't turn every log line into placeholders.
+const minLength = 4
+
+// Resolve returns the value an option refers to. Values without an env: or file:
+// prefix are literals. File contents have trailing newlines trimmed.
+func Resolve(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		name := strings.TrimPrefix(ref, "env:")
+		v, ok := os.LookupEnv(name)
+		if !ok || v == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+	case strings.HasPrefix(ref, "file:"):
+		path := strings.TrimPrefix(ref, "file:")
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read secret: %w", err)
+		}
+		v := strings.TrimRight(string(b), "\r\n")
+		if v == "" {
+			return "", fmt.Errorf("secret file %s is empty", path)
+		}
+		return v, nil
+	default:
+		return ref, nil
+	}
+}
+
+// CheckFileMode returns an error when the file at path is readable by all users.
+func CheckFileMode(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if mode := fi.Mode().Perm(); mode&0o004 != 0 {
+		return fmt.Errorf("secret file %s is world-readable (mode %04o); chmod o-r it", path, uint32(mode))
+	}
+	return nil
+}
+
+var (
+	mu     sync.RWMutex
+	values []string // longest first, so a URL is replaced before its path
+)
+
+// Register adds a value to be redacted. For URLs the path and query are registered
+// too, since client errors and servers often quote just those.
+func Register(v string) {
+	add := []string{v}
+	if u, err := url.Parse(v); err == nil && u.Host != "" {
+		if len(u.Path) > 1 {
+			add = append(add, u.Path)
+		}
+		if u.RawQuery != "" {
+			add = append(add, u.RawQuery)
+		}
+		if p, ok := u.User.Password(); ok {
+			add = append(add, p)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range add {
+		if len(s) >= minLength && !contains(values, s) {
+			values = append(values, s)
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact replaces every registered value in s with Placeholder.
+func Redact(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, v := range values {
+		s = strings.ReplaceAll(s, v, Placeholder)
+	}
+	return s
+}
+
+// Writer redacts what is written to W. Use it for log output: each Write is
+// redacted as a whole, which holds for the log package's one-line writes.
+type Writer struct {
+	W io.Writer
+}
+
+func (w Writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.W, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// StripURL drops the URL from an HTTP client error (*url.Error), which quotes the
+// full request URL: webhook URLs carry their token in the path.
+func StripURL(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return ue.Err
+	}
+	return err
+}
+
+// Flag is a flag.Value for a credential-bearing option. Set resolves env: and file:
+// references into *Dest, registers the value for redaction and reports a
+// world-readable secret file to Warn (if set). String never shows the value.
+type Flag struct {
+	Dest *string
+	Warn func(error)
+}
+
+func (f Flag) String() string {
+	if f.Dest == nil || *f.Dest == "" {
+		return ""
+	}
+	return Placeholder
+}
+
+func (f Flag) Set(ref string) error {
+	v, err := Resolve(ref)
+	if err != nil {
+		return err
+	}
+	if path, ok := strings.CutPrefix(ref, "file:"); ok && f.Warn != nil {
+		if err := CheckFileMode(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			f.Warn(err)
+		}
+	}
+	Register(v)
+	*f.Dest = v
+	return nil
+}
@@ -0,0 +1,251 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/top"
+)
+
+// Bot long-polls for chat commands and answers them from the latest Result:
+//
+//	/status   the full snapshot, as in the Discord message
+//	/temp     temperatures
+//	/disk     storage usage and SD card wear
+//	/top      CPU utilization and the busiest processes
+//	/help     the commands
+//
+// Only chats in ChatIDs get answers; messages from other chats are logged and dropped.
+type Bot struct {
+	Client  Client
+	ChatIDs []int64
+	Latest  func() (metrics.Result, bool)
+
+	procs top.ProcSampler
+}
+
+const (
+	pollWait       = 30 * time.Second
+	pollBackoffMax = time.Minute
+	topProcs       = 10
+	topSampleGap   = time.Second
+)
+
+// Run polls until ctx is done. Errors are logged and retried with a growing pause.
+func (b *Bot) Run(ctx context.Context) {
+	var offset int64
+	backoff := time.Second
+	for ctx.Err() == nil {
+		updates, err := b.Client.GetUpdates(ctx, offset, pollWait)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("telegram poll error: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, pollBackoffMax)
+			continue
+		}
+		backoff = time.Second
+
+		for _, u := range updates {
+			offset = max(offset, u.UpdateID+1)
+			if u.Message == nil || !strings.HasPrefix(u.Message.Text, "/") {
+				continue
+			}
+			b.handle(ctx, u.Message)
+		}
+	}
+}
+
+func (b *Bot) allowed(chatID int64) bool {
+	for _, id := range b.ChatIDs {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bot) handle(ctx context.Context, m *Message) {
+	if !b.allowed(m.Chat.ID) {
+		log.Printf("telegram: ignoring command from chat %d (not in the allowed chat ids)", m.Chat.ID)
+		return
+	}
+	// "/temp@my_bot arg" in groups.
+	cmd, _, _ := strings.Cut(strings.Fields(m.Text)[0], "@")
+
+	text, pre := b.answer(ctx, strings.ToLower(cmd))
+	if err := b.Client.SendMessage(ctx, m.Chat.ID, text, pre); err != nil {
+		log.Printf("telegram reply error: chat %d: %v", m.Chat.ID, err)
+	}
+}
+
+// answer returns the reply to a command and whether it is a monospaced block.
+func (b *Bot) answer(ctx context.Context, cmd string) (string, bool) {
+	switch cmd {
+	case "/start", "/help":
+		return "Commands:\n/status - full snapshot\n/temp - temperatures\n/disk - storage and SD card wear\n/top - CPU and busiest processes", false
+	case "/status", "/temp", "/disk", "/top":
+	default:
+		return fmt.Sprintf("Unknown command %s; try /help", cmd), false
+	}
+
+	res, ok := b.Latest()
+	if !ok {
+		return "No data collected yet.", false
+	}
+	switch cmd {
+	case "/status":
+		return metrics.FormatMessage(res), false
+	case "/temp":
+		return formatTemps(res), false
+	case "/disk":
+		return formatDisk(res), false
+	default:
+		return b.formatTop(ctx, res), true
+	}
+}
+
+func formatTemps(res metrics.Result) string {
+	var lines []string
+	for _, s := range res.Samples {
+		if s.Unit == metrics.UnitCelsius {
+			lines = append(lines, fmt.Sprintf("%s%s: %.1f °C", s.Name, labelSuffix(s, "host"), s.Value))
+		}
+	}
+	if len(lines) == 0 {
+		return "No temperatures collected."
+	}
+	sort.Strings(lines)
+	return "Temperatures" + age(res) + ":\n" + strings.Join(lines, "\n")
+}
+
+func formatDisk(res metrics.Result) string {
+	type mount struct{ used, free, total float64 }
+	mounts := map[string]*mount{}
+	var wear []string
+	for _, s := range res.Samples {
+		mp := s.Labels["mount_point"]
+		get := func() *mount {
+			if mounts[mp] == nil {
+				mounts[mp] = &mount{}
+			}
+			return mounts[mp]
+		}
+		switch s.Name {
+		case "storage_used_percent":
+			get().used = s.Value
+		case "storage_available_bytes":
+			get().free = s.Value
+		case "storage_total_bytes":
+			get().total = s.Value
+		case "mmc_life_time_used_percent":
+			wear = append(wear, fmt.Sprintf("%s life time used (type %s): %.0f%%", s.Labels["device"], s.Labels["type"], s.Value))
+		}
+	}
+	if len(mounts) == 0 && len(wear) == 0 {
+		return "No storage collected."
+	}
+
+	names := make([]string, 0, len(mounts))
+	for mp := range mounts {
+		names = append(names, mp)
+	}
+	sort.Strings(names)
+	lines := []string{"Storage" + age(res) + ":"}
+	for _, mp := range names {
+		m := mounts[mp]
+		lines = append(lines, fmt.Sprintf("%s: %.1f%% used, %s free of %s", mp, m.used, metrics.HumanBytes(m.free), metrics.HumanBytes(m.total)))
+	}
+	if len(wear) > 0 {
+		sort.Strings(wear)
+		lines = append(lines, "SD card wear:")
+		lines = append(lines, wear...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatTop reports CPU utilization from res and the busiest processes over the next
+// second, read from /proc.
+func (b *Bot) formatTop(ctx context.Context, res metrics.Result) string {
+	var sb strings.Builder
+	var cores []metrics.Sample
+	for _, s := range res.Samples {
+		if s.Name != "cpu_utilization" {
+			continue
+		}
+		if cpu := s.Labels["cpu"]; cpu == "" || cpu == "total" {
+			fmt.Fprintf(&sb, "CPU %5.1f%%%s\n", s.Value, age(res))
+		} else {
+			cores = append(cores, s)
+		}
+	}
+	sort.Slice(cores, func(i, j int) bool { return cores[i].Labels["cpu"] < cores[j].Labels["cpu"] })
+	for _, s := range cores {
+		fmt.Fprintf(&sb, "  %-5s %5.1f%%\n", s.Labels["cpu"], s.Value)
+	}
+
+	procs, err := b.sampleProcs(ctx)
+	if err != nil {
+		fmt.Fprintf(&sb, "\nprocesses: %v", err)
+		return sb.String()
+	}
+	top.SortProcs(procs, top.SortCPU)
+	fmt.Fprintf(&sb, "\n%7s %6s %9s  %s\n", "PID", "CPU%", "RSS", "COMMAND")
+	for _, p := range procs[:min(len(procs), topProcs)] {
+		fmt.Fprintf(&sb, "%7d %6.1f %9s  %s\n", p.PID, p.CPUPercent, metrics.HumanBytes(p.RSSBytes), p.Command)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// sampleProcs reads the process table twice, topSampleGap apart, for CPU usage.
+func (b *Bot) sampleProcs(ctx context.Context) ([]top.Proc, error) {
+	if _, err := b.procs.Sample(); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(topSampleGap):
+	}
+	return b.procs.Sample()
+}
+
+// labelSuffix renders a sample's labels, except the given ones, as {k=v,...}.
+func labelSuffix(s metrics.Sample, except ...string) string {
+	var parts []string
+	for k, v := range s.Labels {
+		skip := false
+		for _, e := range except {
+			skip = skip || k == e
+		}
+		if !skip {
+			parts = append(parts, k+"="+v)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	sort.Strings(parts)
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// age says how old res is, e.g. " (12s ago)".
+func age(res metrics.Result) string {
+	for _, s := range res.Samples {
+		if !s.Timestamp.IsZero() {
+			return fmt.Sprintf(" (%s ago)", time.Since(s.Timestamp).Round(time.Second))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+)
+
+func testResult() metrics.Result {
+	return metrics.Result{Samples: []metrics.Sample{
+		{Name: "cpu_temp_celsius", Value: 51.2, Unit: metrics.UnitCelsius, Labels: map[string]string{"host": "pi", "zone": "cpu"}},
+		{Name: "storage_used_percent", Value: 42, Labels: map[string]string{"mount_point": "/"}},
+		{Name: "storage_available_bytes", Value: 8 << 30, Labels: map[string]string{"mount_point": "/"}},
+		{Name: "storage_total_bytes", Value: 16 << 30, Labels: map[string]string{"mount_point": "/"}},
+		{Name: "mmc_life_time_used_percent", Value: 20, Labels: map[string]string{"device": "mmcblk0", "type": "A"}},
+	}}
+}
+
+func TestBotAnswer(t *testing.T) {
+	latest := func() (metrics.Result, bool) { return testResult(), true }
+	tests := []struct {
+		cmd    string
+		latest func() (metrics.Result, bool)
+		want   []string
+	}{
+		{cmd: "/help", latest: latest, want: []string{"Commands:", "/status - full snapshot", "/top"}},
+		{cmd: "/start", latest: latest, want: []string{"Commands:"}},
+		{cmd: "/reboot", latest: latest, want: []string{"Unknown command /reboot; try /help"}},
+		{cmd: "/temp", latest: func() (metrics.Result, bool) { return metrics.Result{}, false }, want: []string{"No data collected yet."}},
+		{cmd: "/temp", latest: func() (metrics.Result, bool) { return metrics.Result{}, true }, want: []string{"No temperatures collected."}},
+		{cmd: "/temp", latest: latest, want: []string{"Temperatures:\ncpu_temp_celsius{zone=cpu}: 51.2 °C"}},
+		{cmd: "/disk", latest: latest, want: []string{"Storage:\n/: 42.0% used, ", "SD card wear:\nmmcblk0 life time used (type A): 20%"}},
+		{cmd: "/status", latest: latest, want: []string{"Metrics (collected at "}},
+	}
+	for _, tt := range tests {
+		b := &Bot{Latest: tt.latest}
+		got, pre := b.answer(context.Background(), tt.cmd)
+		if pre {
+			t.Errorf("%s: answer is a monospaced block", tt.cmd)
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("%s: answer %q does not contain %q", tt.cmd, got, w)
+			}
+		}
+	}
+}
+
+func TestBotRun(t *testing.T) {
+	const updates = `{"ok":true,"result":[
+		{"update_id":10,"message":{"message_id":1,"text":"/Temp","chat":{"id":42}}},
+		{"update_id":11,"message":{"message_id":2,"text":"/status","chat":{"id":99}}},
+		{"update_id":12,"message":{"message_id":3,"text":"hello","chat":{"id":42}}},
+		{"update_id":13,"message":{"message_id":4,"text":"/disk@rpi_metrics_bot now","chat":{"id":42}}}
+	]}`
+	polls := 0
+	api, srv := newFakeAPI(t, func(method string, params map[string]any) (int, string) {
+		if method != "getUpdates" {
+			return http.StatusOK, `{"ok":true,"result":{}}`
+		}
+		polls++
+		if polls == 1 {
+			return http.StatusOK, updates
+		}
+		time.Sleep(10 * time.Millisecond)
+		return http.StatusOK, `{"ok":true,"result":[]}`
+	})
+	b := &Bot{
+		Client:  Client{BaseURL: srv.URL, Token: "t"},
+		ChatIDs: []int64{42},
+		Latest:  func() (metrics.Result, bool) { return testResult(), true },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(api.sent()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the context was canceled")
+	}
+
+	sent := api.sent()
+	if len(sent) != 2 {
+		t.Fatalf("got %d replies, want 2 (/temp and /disk to chat 42)", len(sent))
+	}
+	for i, want := range []string{"Temperatures:", "Storage:"} {
+		if sent[i].params["chat_id"] != 42.0 || !strings.HasPrefix(sent[i].params["text"].(string), want) {
+			t.Errorf("reply %d = %v, want one starting with %q to chat 42", i, sent[i].params, want)
+		}
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	var offsets []any
+	for _, c := range api.calls {
+		if c.method == "getUpdates" {
+			offsets = append(offsets, c.params["offset"])
+		}
+	}
+	if len(offsets) < 2 || offsets[0] != 0.0 || offsets[1] != 14.0 {
+		t.Errorf("getUpdates offsets = %v, want 0 then 14", offsets)
+	}
+}
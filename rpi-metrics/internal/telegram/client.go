@@ -0,0 +1,160 @@
+// Package telegram sends rpi-metrics snapshots, alerts and notices to Telegram chats
+// through the Bot API, and answers chat commands from the latest Result.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"rpi-metrics/constants"
+	"rpi-metrics/internal/metrics"
+	"rpi-metrics/internal/secret"
+)
+
+// MessageMaxLen is the Bot API's limit, in characters, for the text of one message.
+const MessageMaxLen = 4096
+
+// Client calls the Bot API of one bot.
+type Client struct {
+	BaseURL string // default constants.DefaultTelegramAPIURL; a fake server for tests
+	Token   string // from @BotFather
+	HTTP    *http.Client
+
+	// Retry retries failed sends; getUpdates is not retried (the poll loop is).
+	Retry metrics.Retry
+}
+
+// apiResponse is the envelope of every Bot API response.
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// call POSTs params as JSON to the API method and decodes the result into out.
+func (c *Client) call(ctx context.Context, method string, params, out any, timeout time.Duration) error {
+	if c.Token == "" {
+		return fmt.Errorf("telegram bot token is empty")
+	}
+	// Export and Notify call this from different queues: read c, don't fill it in.
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient // the context bounds each call
+	}
+	base := c.BaseURL
+	if base == "" {
+		base = constants.DefaultTelegramAPIURL
+	}
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("marshal telegram %s: %w", method, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(base, "/")+"/bot"+c.Token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", secret.StripURL(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return &metrics.TransportError{Err: fmt.Errorf("telegram %s: %w", method, secret.StripURL(err))}
+	}
+	defer resp.Body.Close()
+
+	var r apiResponse
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(b, &r); err != nil || !r.OK {
+		se := &metrics.StatusError{Target: "telegram " + method, Code: resp.StatusCode, Body: r.Description}
+		if r.Description == "" {
+			se.Body = strings.TrimSpace(string(b[:min(len(b), 4<<10)]))
+		}
+		se.RetryAfter = time.Duration(r.Parameters.RetryAfter) * time.Second
+		return se
+	}
+	if out != nil {
+		if err := json.Unmarshal(r.Result, out); err != nil {
+			return fmt.Errorf("parse telegram %s: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// sendTimeout bounds one sendMessage request.
+const sendTimeout = 10 * time.Second
+
+// SendMessage sends text to a chat, split into several messages at line boundaries
+// when it is too long. With pre, the text is sent as a monospaced block.
+func (c *Client) SendMessage(ctx context.Context, chatID int64, text string, pre bool) error {
+	for _, part := range metrics.SplitMessage(text, MessageMaxLen-len("<pre></pre>")) {
+		params := map[string]any{"chat_id": chatID, "text": part, "disable_web_page_preview": true}
+		if pre {
+			params["text"] = "<pre>" + htmlEscaper.Replace(part) + "</pre>"
+			params["parse_mode"] = "HTML"
+		}
+		err := c.Retry.Do(ctx, func() error {
+			return c.call(ctx, "sendMessage", params, nil, sendTimeout)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// htmlEscaper escapes text for parse_mode HTML, which only knows these entities.
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Update is an incoming update; only messages are requested.
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	Text      string `json:"text"`
+	Chat      struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	From *struct {
+		Username string `json:"username"`
+	} `json:"from"`
+}
+
+// GetUpdates long-polls for updates after offset, waiting up to wait for one.
+func (c *Client) GetUpdates(ctx context.Context, offset int64, wait time.Duration) ([]Update, error) {
+	var updates []Update
+	params := map[string]any{"offset": offset, "timeout": int(wait.Seconds()), "allowed_updates": []string{"message"}}
+	err := c.call(ctx, "getUpdates", params, &updates, wait+10*time.Second)
+	return updates, err
+}
+
+// GetChat confirms the bot can reach a chat, without sending anything.
+func (c *Client) GetChat(ctx context.Context, chatID int64) error {
+	return c.call(ctx, "getChat", map[string]any{"chat_id": chatID}, nil, sendTimeout)
+}
+
+// BotInfo is the result of getMe.
+type BotInfo struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// GetMe returns the bot's identity; it confirms the token without sending anything.
+func (c *Client) GetMe(ctx context.Context) (BotInfo, error) {
+	var me BotInfo
+	err := c.call(ctx, "getMe", map[string]any{}, &me, sendTimeout)
+	return me, err
+}
@@ -0,0 +1,276 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+)
+
+// fakeAPI is a Bot API server that records every call and answers it with reply.
+type fakeAPI struct {
+	reply func(method string, params map[string]any) (int, string)
+
+	mu    sync.Mutex
+	calls []apiCall
+}
+
+type apiCall struct {
+	path   string
+	method string
+	params map[string]any
+}
+
+func newFakeAPI(t *testing.T, reply func(method string, params map[string]any) (int, string)) (*fakeAPI, *httptest.Server) {
+	f := &fakeAPI{reply: reply}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s %s with Content-Type %q, want a JSON POST", r.Method, r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		var params map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Errorf("decode %s: %v", r.URL.Path, err)
+		}
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		f.mu.Lock()
+		f.calls = append(f.calls, apiCall{path: r.URL.Path, method: method, params: params})
+		f.mu.Unlock()
+
+		code, body := f.reply(method, params)
+		w.WriteHeader(code)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeAPI) sent() []apiCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []apiCall
+	for _, c := range f.calls {
+		if c.method == "sendMessage" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func okReply(string, map[string]any) (int, string) {
+	return http.StatusOK, `{"ok":true,"result":{}}`
+}
+
+func TestSendMessage(t *testing.T) {
+	api, srv := newFakeAPI(t, okReply)
+	c := Client{BaseURL: srv.URL + "/", Token: "123:abc"}
+
+	if err := c.SendMessage(context.Background(), 42, "cpu_temp_celsius: 51.2 °C", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SendMessage(context.Background(), -100, "a < b && c > d", true); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := api.sent()
+	if len(sent) != 2 {
+		t.Fatalf("got %d sendMessage calls, want 2", len(sent))
+	}
+	if sent[0].path != "/bot123:abc/sendMessage" {
+		t.Errorf("path = %q, want /bot123:abc/sendMessage", sent[0].path)
+	}
+	if got := sent[0].params["chat_id"]; got != 42.0 {
+		t.Errorf("chat_id = %v, want 42", got)
+	}
+	if got := sent[0].params["text"]; got != "cpu_temp_celsius: 51.2 °C" {
+		t.Errorf("text = %q", got)
+	}
+	if _, ok := sent[0].params["parse_mode"]; ok {
+		t.Errorf("plain message has parse_mode %v", sent[0].params["parse_mode"])
+	}
+	if got, want := sent[1].params["text"], "<pre>a &lt; b &amp;&amp; c &gt; d</pre>"; got != want {
+		t.Errorf("pre text = %q, want %q", got, want)
+	}
+	if got := sent[1].params["parse_mode"]; got != "HTML" {
+		t.Errorf("parse_mode = %v, want HTML", got)
+	}
+}
+
+func TestSendMessageSplitsLongText(t *testing.T) {
+	api, srv := newFakeAPI(t, okReply)
+	c := Client{BaseURL: srv.URL, Token: "t"}
+
+	line := strings.Repeat("é", 99) + "\n" // 100 characters, 199 bytes
+	text := strings.Repeat(line, 60)
+	if err := c.SendMessage(context.Background(), 1, text, true); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := api.sent()
+	if len(sent) != 2 {
+		t.Fatalf("got %d messages, want 2", len(sent))
+	}
+	var joined []string
+	for _, m := range sent {
+		s := m.params["text"].(string)
+		if n := len([]rune(s)); n > MessageMaxLen {
+			t.Errorf("message of %d characters exceeds %d", n, MessageMaxLen)
+		}
+		joined = append(joined, strings.TrimSuffix(strings.TrimPrefix(s, "<pre>"), "</pre>"))
+	}
+	if got := strings.Join(joined, "\n"); got != text {
+		t.Errorf("split messages do not add up to the text")
+	}
+}
+
+func TestSendMessageErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		replies    []string // per attempt: the status code, a space and the body
+		retry      metrics.Retry
+		wantErr    string
+		wantCalls  int
+		retryAfter time.Duration
+	}{
+		{
+			name:      "rejected",
+			replies:   []string{`400 {"ok":false,"description":"Bad Request: chat not found"}`},
+			retry:     metrics.Retry{Attempts: 3},
+			wantErr:   "telegram sendMessage returned status 400: Bad Request: chat not found",
+			wantCalls: 1,
+		},
+		{
+			name:       "rate limited",
+			replies:    []string{`429 {"ok":false,"description":"Too Many Requests: retry after 7","parameters":{"retry_after":7}}`},
+			retry:      metrics.Retry{Attempts: 1},
+			wantErr:    "status 429",
+			wantCalls:  1,
+			retryAfter: 7 * time.Second,
+		},
+		{
+			name:      "not JSON",
+			replies:   []string{`502 <html>Bad Gateway</html>`},
+			retry:     metrics.Retry{Attempts: 1},
+			wantErr:   "status 502: <html>Bad Gateway</html>",
+			wantCalls: 1,
+		},
+		{
+			name:      "retried",
+			replies:   []string{`500 {"ok":false,"description":"Internal Server Error"}`, `200 {"ok":true,"result":{}}`},
+			retry:     metrics.Retry{Attempts: 3, Backoff: time.Millisecond},
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := 0
+			api, srv := newFakeAPI(t, func(string, map[string]any) (int, string) {
+				reply := tt.replies[min(n, len(tt.replies)-1)]
+				n++
+				code, body, _ := strings.Cut(reply, " ")
+				status, _ := strconv.Atoi(code)
+				return status, body
+			})
+			c := Client{BaseURL: srv.URL, Token: "t", Retry: tt.retry}
+
+			err := c.SendMessage(context.Background(), 1, "hi", false)
+			if got := len(api.sent()); got != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", got, tt.wantCalls)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			var se *metrics.StatusError
+			if !errors.As(err, &se) {
+				t.Fatalf("error %T is not a *metrics.StatusError", err)
+			}
+			if se.RetryAfter != tt.retryAfter {
+				t.Errorf("RetryAfter = %s, want %s", se.RetryAfter, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestClientWithoutToken(t *testing.T) {
+	var c Client
+	if err := c.SendMessage(context.Background(), 1, "hi", false); err == nil {
+		t.Fatal("SendMessage without a token succeeded")
+	}
+}
+
+func TestGetUpdatesAndGetMe(t *testing.T) {
+	api, srv := newFakeAPI(t, func(method string, params map[string]any) (int, string) {
+		switch method {
+		case "getUpdates":
+			return http.StatusOK, `{"ok":true,"result":[{"update_id":7,"message":{"message_id":1,"text":"/temp","chat":{"id":42},"from":{"username":"pi_owner"}}}]}`
+		case "getMe":
+			return http.StatusOK, `{"ok":true,"result":{"id":99,"username":"rpi_metrics_bot"}}`
+		}
+		return http.StatusNotFound, `{"ok":false,"description":"Not Found"}`
+	})
+	c := Client{BaseURL: srv.URL, Token: "t"}
+
+	updates, err := c.GetUpdates(context.Background(), 5, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 1 || updates[0].UpdateID != 7 || updates[0].Message.Text != "/temp" ||
+		updates[0].Message.Chat.ID != 42 || updates[0].Message.From.Username != "pi_owner" {
+		t.Errorf("updates = %+v", updates)
+	}
+	if p := api.calls[0].params; p["offset"] != 5.0 || p["timeout"] != 1.0 {
+		t.Errorf("getUpdates params = %v, want offset 5 and timeout 1", p)
+	}
+
+	me, err := c.GetMe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.ID != 99 || me.Username != "rpi_metrics_bot" {
+		t.Errorf("getMe = %+v", me)
+	}
+}
+
+// TestExporterConcurrentNotify sends from the telegram queue (Export) and the
+// shutdown queue (Notify) at once, as the agent does; run it with -race.
+func TestExporterConcurrentNotify(t *testing.T) {
+	api, srv := newFakeAPI(t, okReply)
+	e := &Exporter{Client: Client{BaseURL: srv.URL, Token: "t"}, ChatIDs: []int64{42}, Every: time.Nanosecond}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 3; i++ {
+			if err := e.Export(context.Background(), testResult()); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 3; i++ {
+			if err := e.Notify(context.Background(), "shutting down"); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	if n := len(api.sent()); n != 6 {
+		t.Errorf("got %d sendMessage calls, want 6", n)
+	}
+}
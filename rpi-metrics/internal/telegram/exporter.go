@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+)
+
+// Exporter sends to every chat in ChatIDs: a snapshot every Every (as in the Discord
+// message; 0 sends none), a message as soon as an alert is raised or resolved, and
+// notices (Notify).
+type Exporter struct {
+	Client  Client
+	ChatIDs []int64
+	Every   time.Duration
+
+	lastSent time.Time
+	alerts   map[string]metrics.Alert // active alerts by key, from the last Export
+}
+
+func (e *Exporter) Export(ctx context.Context, res metrics.Result) error {
+	// Remember the alerts only once they are sent, so a failed send is tried again.
+	msg, active := e.alertChanges(metrics.DetectAlerts(res))
+	if msg != "" {
+		if err := e.send(ctx, msg); err != nil {
+			return err
+		}
+	}
+	e.alerts = active
+
+	now := time.Now()
+	if e.Every <= 0 || (!e.lastSent.IsZero() && now.Sub(e.lastSent) < e.Every) {
+		return nil
+	}
+	if err := e.send(ctx, metrics.FormatMessage(res)); err != nil {
+		return err
+	}
+	e.lastSent = now
+	return nil
+}
+
+// Notify sends a one-off notice (a shutdown is about to happen) to every chat.
+func (e *Exporter) Notify(ctx context.Context, msg string) error {
+	return e.send(ctx, msg)
+}
+
+// alertChanges describes the alerts raised or resolved since the last Export ("" when
+// nothing changed) and returns the active ones by key.
+func (e *Exporter) alertChanges(alerts []metrics.Alert) (string, map[string]metrics.Alert) {
+	raised, resolved, active := metrics.AlertChanges(e.alerts, alerts)
+
+	var b strings.Builder
+	if len(raised) > 0 {
+		b.WriteString("Alerts:")
+		for _, a := range raised {
+			fmt.Fprintf(&b, "\n- [%s] %s", strings.ToUpper(a.Severity), a.Message)
+		}
+	}
+	if len(resolved) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("Resolved:")
+		for _, a := range resolved {
+			b.WriteString("\n- " + a.Message)
+		}
+	}
+	return b.String(), active
+}
+
+// send sends msg to every chat, returning the first error.
+func (e *Exporter) send(ctx context.Context, msg string) error {
+	if len(e.ChatIDs) == 0 {
+		return fmt.Errorf("no telegram chat ids")
+	}
+	var first error
+	for _, id := range e.ChatIDs {
+		if err := e.Client.SendMessage(ctx, id, msg, false); err != nil && first == nil {
+			first = fmt.Errorf("chat %d: %w", id, err)
+		}
+	}
+	return first
+}
@@ -0,0 +1,174 @@
+package top
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Proc is one process in the process table.
+type Proc struct {
+	PID        int
+	Command    string
+	State      string
+	CPUPercent float64 // 100 = one core busy, as in top
+	RSSBytes   float64
+}
+
+// ProcSort orders the process table.
+type ProcSort int
+
+const (
+	SortCPU ProcSort = iota
+	SortMemory
+	SortPID
+	SortCommand
+	numProcSorts
+)
+
+func (s ProcSort) String() string {
+	return [...]string{"cpu", "memory", "pid", "command"}[s]
+}
+
+// Next cycles through the sort orders.
+func (s ProcSort) Next() ProcSort { return (s + 1) % numProcSorts }
+
+// SortProcs sorts procs in place.
+func SortProcs(procs []Proc, by ProcSort) {
+	sort.SliceStable(procs, func(i, j int) bool {
+		a, b := procs[i], procs[j]
+		switch by {
+		case SortMemory:
+			return a.RSSBytes > b.RSSBytes
+		case SortPID:
+			return a.PID < b.PID
+		case SortCommand:
+			return a.Command < b.Command
+		default:
+			if a.CPUPercent != b.CPUPercent {
+				return a.CPUPercent > b.CPUPercent
+			}
+			return a.RSSBytes > b.RSSBytes
+		}
+	})
+}
+
+// ProcSampler reads processes from /proc. CPU usage is the share of CPU time a
+// process used since the previous Sample, so the first Sample reports 0 for all.
+type ProcSampler struct {
+	ProcPath string // default: /proc
+
+	lastTotal uint64
+	lastTicks map[int]uint64 // pid -> utime+stime
+}
+
+const defaultProcPath = "/proc"
+
+func (p *ProcSampler) Sample() ([]Proc, error) {
+	root := p.ProcPath
+	if root == "" {
+		root = defaultProcPath
+	}
+
+	total, err := readTotalJiffies(filepath.Join(root, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := filepath.Glob(filepath.Join(root, "[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	ticks := make(map[int]uint64, len(dirs))
+	out := make([]Proc, 0, len(dirs))
+	pageSize := float64(os.Getpagesize())
+	dTotal := float64(total - p.lastTotal)
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(filepath.Base(dir))
+		if err != nil {
+			continue
+		}
+		st, err := readProcStat(filepath.Join(dir, "stat"))
+		if err != nil {
+			continue // the process exited meanwhile
+		}
+		ticks[pid] = st.ticks
+
+		proc := Proc{PID: pid, Command: st.comm, State: st.state, RSSBytes: float64(st.rssPages) * pageSize}
+		if prev, ok := p.lastTicks[pid]; ok && dTotal > 0 && st.ticks >= prev {
+			proc.CPUPercent = float64(st.ticks-prev) / dTotal * 100 * float64(runtime.NumCPU())
+		}
+		out = append(out, proc)
+	}
+	p.lastTotal, p.lastTicks = total, ticks
+	return out, nil
+}
+
+// readTotalJiffies sums the aggregate "cpu" line of /proc/stat.
+func readTotalJiffies(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() {
+		return 0, fmt.Errorf("read %s: empty", path)
+	}
+	fields := strings.Fields(sc.Text())
+	if len(fields) < 2 || fields[0] != "cpu" {
+		return 0, fmt.Errorf("parse %s: unexpected first line", path)
+	}
+	var total uint64
+	for _, f := range fields[1:] {
+		v, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse %s: %w", path, err)
+		}
+		total += v
+	}
+	return total, nil
+}
+
+type procStat struct {
+	comm, state string
+	ticks       uint64 // utime + stime
+	rssPages    uint64
+}
+
+// readProcStat parses /proc/<pid>/stat. The command is in parentheses and may itself
+// contain spaces and parentheses, so fields are counted from the last ')'.
+func readProcStat(path string) (procStat, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return procStat{}, err
+	}
+	s := string(b)
+	open, end := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || end < open {
+		return procStat{}, fmt.Errorf("parse %s: no command", path)
+	}
+	fields := strings.Fields(s[end+1:])
+	// fields[0] is field 3 (state); utime, stime and rss are fields 14, 15 and 24.
+	if len(fields) < 22 {
+		return procStat{}, fmt.Errorf("parse %s: %d fields", path, len(fields))
+	}
+	utime, err1 := strconv.ParseUint(fields[11], 10, 64)
+	stime, err2 := strconv.ParseUint(fields[12], 10, 64)
+	rss, err3 := strconv.ParseInt(fields[21], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return procStat{}, fmt.Errorf("parse %s: bad number", path)
+	}
+	return procStat{
+		comm:     s[open+1 : end],
+		state:    fields[0],
+		ticks:    utime + stime,
+		rssPages: uint64(max(rss, 0)),
+	}, nil
+}
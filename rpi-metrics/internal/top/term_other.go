@@ -0,0 +1,16 @@
+//go:build !linux
+
+package top
+
+import "errors"
+
+// Terminal control is only implemented for Linux; elsewhere top falls back to plain
+// redrawn text with line-based keys.
+
+func IsTerminal(fd int) bool { return false }
+
+func EnableKeys(fd int) (restore func(), err error) {
+	return nil, errors.New("single-key input is only supported on Linux")
+}
+
+func Size(fd int) (cols, rows int, ok bool) { return 0, 0, false }
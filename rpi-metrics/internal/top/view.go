@@ -0,0 +1,392 @@
+// Package top renders the terminal dashboard of `rpi-metrics top`: the web
+// dashboard's cards as text, plus a process table.
+package top
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"rpi-metrics/internal/metrics"
+)
+
+// Frame is everything one screen shows.
+type Frame struct {
+	Host       string
+	Result     metrics.Result
+	Procs      []Proc
+	Interval   time.Duration
+	Sort       ProcSort
+	Paused     bool
+	Collectors []Toggle
+	Width      int // 0 = 80
+	Height     int // 0 = unknown: plainProcRows processes
+}
+
+// Toggle is a collector that can be switched on and off with its number key.
+type Toggle struct {
+	ID      string
+	Enabled bool
+}
+
+// Same bands as the web dashboard.
+const (
+	maxTemp          = 85.0
+	tempWarm         = 50.0
+	tempHot          = 70.0
+	storageWarm      = 70.0
+	storageHot       = 90.0
+	defaultWidth     = 80
+	minProcRows      = 3
+	plainProcRows    = 15
+	maxToggleDisplay = 9
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+
+	ansiHome      = "\x1b[H"
+	ansiClearLine = "\x1b[K"
+	ansiClearDown = "\x1b[J"
+)
+
+// screen accumulates lines. Without ANSI support, color calls are no-ops and bars are
+// drawn in ASCII.
+type screen struct {
+	ansi  bool
+	width int
+	lines []string
+}
+
+func (s *screen) add(format string, args ...any) {
+	s.lines = append(s.lines, fmt.Sprintf(format, args...))
+}
+
+func (s *screen) color(code, text string) string {
+	if !s.ansi || code == "" {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+func (s *screen) bar(percent float64, width int, code string) string {
+	full, empty := "█", "░"
+	if !s.ansi {
+		full, empty = "#", "."
+	}
+	n := int(percent/100*float64(width) + 0.5)
+	n = min(max(n, 0), width)
+	return "[" + s.color(code, strings.Repeat(full, n)) + strings.Repeat(empty, width-n) + "]"
+}
+
+// Render draws f. With ansi it redraws in place from the top-left corner;
+// otherwise it prints a separator and the frame as plain text.
+func Render(w io.Writer, f Frame, ansi bool) error {
+	s := &screen{ansi: ansi, width: f.Width}
+	if s.width <= 0 {
+		s.width = defaultWidth
+	}
+	barWidth := min(max(s.width-44, 10), 40)
+
+	byName := map[string][]metrics.Sample{}
+	for _, sm := range f.Result.Samples {
+		byName[sm.Name] = append(byName[sm.Name], sm)
+	}
+
+	status := fmt.Sprintf("every %s", f.Interval)
+	if f.Paused {
+		status = s.color(ansiYellow, "paused")
+	}
+	title := "rpi-metrics top"
+	if f.Host != "" {
+		title += " - " + f.Host
+	}
+	s.add("%s  %s  %s  sort: %s", s.color(ansiBold, title), time.Now().Format("15:04:05"), status, f.Sort)
+	s.add("")
+
+	renderCPU(s, byName, barWidth)
+	renderStorage(s, byName, barWidth)
+	renderWear(s, byName)
+	renderPower(s, byName)
+	renderNetwork(s, byName)
+
+	if len(f.Result.Errors) > 0 {
+		s.add("%s", s.color(ansiBold, "ERRORS"))
+		for _, e := range f.Result.Errors {
+			s.add("  %s %s", s.color(ansiRed, e.CollectorID+":"), e.Error)
+		}
+		s.add("")
+	}
+
+	footer := footerLines(s, f.Collectors)
+	rows := plainProcRows
+	if f.Height > 0 {
+		// Fill the rest of the terminal: header, process rows, blank line, footer.
+		rows = max(f.Height-len(s.lines)-len(footer)-3, minProcRows)
+	}
+	renderProcs(s, f.Procs, rows, f.Sort)
+	s.add("")
+	s.lines = append(s.lines, footer...)
+
+	var buf bytes.Buffer
+	if ansi {
+		buf.WriteString(ansiHome)
+		for _, line := range s.lines {
+			buf.WriteString(line + ansiClearLine + "\n")
+		}
+		buf.WriteString(ansiClearDown)
+	} else {
+		buf.WriteString(strings.Repeat("-", s.width) + "\n")
+		for _, line := range s.lines {
+			buf.WriteString(line + "\n")
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func renderCPU(s *screen, byName map[string][]metrics.Sample, barWidth int) {
+	if temps := byName["cpu_temperature"]; len(temps) > 0 {
+		t := temps[0].Value
+		code := ansiGreen
+		switch {
+		case t >= tempHot:
+			code = ansiRed
+		case t >= tempWarm:
+			code = ansiYellow
+		}
+		s.add("%-12s %s %s", s.color(ansiBold, "TEMPERATURE"), s.color(code, fmt.Sprintf("%6.1f°C", t)), s.bar(t/maxTemp*100, barWidth, code))
+	}
+
+	var total *metrics.Sample
+	var cores []metrics.Sample
+	for _, sm := range byName["cpu_utilization"] {
+		if sm.Labels["cpu"] == "total" {
+			total = &sm
+		} else {
+			cores = append(cores, sm)
+		}
+	}
+	if total != nil {
+		s.add("%-12s %7.1f%% %s", s.color(ansiBold, "CPU"), total.Value, s.bar(total.Value, barWidth, ansiCyan))
+	}
+	sort.Slice(cores, func(i, j int) bool { return cores[i].Labels["cpu"] < cores[j].Labels["cpu"] })
+	coreBar := max(barWidth/2, 5)
+	for i := 0; i < len(cores); i += 2 {
+		line := fmt.Sprintf("  %-6s %5.1f%% %s", cores[i].Labels["cpu"], cores[i].Value, s.bar(cores[i].Value, coreBar, ansiCyan))
+		if i+1 < len(cores) {
+			line += fmt.Sprintf("   %-6s %5.1f%% %s", cores[i+1].Labels["cpu"], cores[i+1].Value, s.bar(cores[i+1].Value, coreBar, ansiCyan))
+		}
+		s.add("%s", line)
+	}
+
+	if cooling := byName["cooling_state"]; len(cooling) > 0 {
+		level := cooling[0].Value
+		maxLevel := 4.0
+		if d, ok := metrics.LookupDescriptor("cooling_state"); ok && d.Range != nil {
+			maxLevel = d.Range.Max
+		}
+		fan := []string{"off", "low", "medium", "high", "maximum"}
+		s.add("%-12s %3.0f / %.0f   fan: %s", s.color(ansiBold, "COOLING"), level, maxLevel, fan[min(max(int(level), 0), len(fan)-1)])
+	}
+	s.add("")
+}
+
+func renderStorage(s *screen, byName map[string][]metrics.Sample, barWidth int) {
+	type mount struct{ used, usedBytes, total float64 }
+	mounts := map[string]*mount{}
+	get := func(sm metrics.Sample) *mount {
+		key := sm.Labels["mount_point"]
+		if key == "" {
+			key = sm.Labels["path"]
+		}
+		if mounts[key] == nil {
+			mounts[key] = &mount{}
+		}
+		return mounts[key]
+	}
+	for _, sm := range byName["storage_used_percent"] {
+		get(sm).used = sm.Value
+	}
+	for _, sm := range byName["storage_used_bytes"] {
+		get(sm).usedBytes = sm.Value
+	}
+	for _, sm := range byName["storage_total_bytes"] {
+		get(sm).total = sm.Value
+	}
+	if len(mounts) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(mounts))
+	for name := range mounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s.add("%s", s.color(ansiBold, "STORAGE"))
+	for _, name := range names {
+		m := mounts[name]
+		code := ansiGreen
+		switch {
+		case m.used > storageHot:
+			code = ansiRed
+		case m.used > storageWarm:
+			code = ansiYellow
+		}
+		s.add("  %-12s %s %s  %s / %s", name, s.color(code, fmt.Sprintf("%5.1f%%", m.used)), s.bar(m.used, barWidth, code),
+			metrics.HumanBytes(m.usedBytes), metrics.HumanBytes(m.total))
+	}
+	s.add("")
+}
+
+func renderWear(s *screen, byName map[string][]metrics.Sample) {
+	eol := byName["mmc_pre_eol_info"]
+	written := byName["mmc_bytes_written_total"]
+	if len(eol) == 0 && len(written) == 0 {
+		return
+	}
+	s.add("%s", s.color(ansiBold, "SD CARD / eMMC"))
+	devices := map[string]string{}
+	for _, sm := range written {
+		devices[sm.Labels["device"]] = "written " + metrics.HumanBytes(sm.Value)
+	}
+	texts := map[float64]string{1: "normal", 2: "warning", 3: "urgent"}
+	for _, sm := range eol {
+		code := map[float64]string{1: ansiGreen, 2: ansiYellow, 3: ansiRed}[sm.Value]
+		state := s.color(code, texts[sm.Value])
+		if d := devices[sm.Labels["device"]]; d != "" {
+			state += "  " + d
+		}
+		devices[sm.Labels["device"]] = "pre-EOL " + state
+	}
+	for _, dev := range sortedKeys(devices) {
+		s.add("  %-12s %s", dev, devices[dev])
+	}
+	s.add("")
+}
+
+func renderPower(s *screen, byName map[string][]metrics.Sample) {
+	capacity := byName["power_supply_capacity_percent"]
+	if len(capacity) == 0 {
+		return
+	}
+	s.add("%s", s.color(ansiBold, "POWER"))
+	statuses := map[string]string{}
+	names := []string{"unknown", "charging", "discharging", "not charging", "full"}
+	for _, sm := range byName["power_supply_status"] {
+		statuses[sm.Labels["supply"]] = names[min(max(int(sm.Value), 0), len(names)-1)]
+	}
+	for _, sm := range capacity {
+		code := ansiGreen
+		switch {
+		case sm.Value < 20:
+			code = ansiRed
+		case sm.Value < 50:
+			code = ansiYellow
+		}
+		s.add("  %-12s %s  %s", sm.Labels["supply"], s.color(code, fmt.Sprintf("%5.1f%%", sm.Value)), statuses[sm.Labels["supply"]])
+	}
+	s.add("")
+}
+
+func renderNetwork(s *screen, byName map[string][]metrics.Sample) {
+	up := byName["network_up"]
+	if len(up) == 0 {
+		return
+	}
+	signal := map[string]float64{}
+	for _, sm := range byName["wifi_signal_dbm"] {
+		signal[sm.Labels["interface"]] = sm.Value
+	}
+	ssid := map[string]string{}
+	for _, sm := range byName["wifi_info"] {
+		ssid[sm.Labels["interface"]] = sm.Labels["ssid"]
+	}
+
+	s.add("%s", s.color(ansiBold, "NETWORK"))
+	sort.Slice(up, func(i, j int) bool { return up[i].Labels["interface"] < up[j].Labels["interface"] })
+	for _, sm := range up {
+		iface := sm.Labels["interface"]
+		line := fmt.Sprintf("  %-12s %s", iface, s.color(ansiGreen, "up"))
+		if sm.Value == 0 {
+			line = fmt.Sprintf("  %-12s %s", iface, s.color(ansiRed, "down"))
+		}
+		if dbm, ok := signal[iface]; ok {
+			code := ansiGreen
+			switch {
+			case dbm < -80:
+				code = ansiRed
+			case dbm < -67:
+				code = ansiYellow
+			}
+			line += "  " + s.color(code, fmt.Sprintf("%.0f dBm", dbm))
+		}
+		if name := ssid[iface]; name != "" {
+			line += "  " + name
+		}
+		s.add("%s", line)
+	}
+	s.add("")
+}
+
+func renderProcs(s *screen, procs []Proc, rows int, by ProcSort) {
+	s.add("%s", s.color(ansiBold, fmt.Sprintf("%7s %6s %9s %s  %s", "PID", "CPU%", "RSS", "S", "COMMAND")))
+	SortProcs(procs, by)
+	for _, p := range procs[:min(rows, len(procs))] {
+		s.add("%7d %6.1f %9s %s  %s", p.PID, p.CPUPercent, metrics.HumanBytes(p.RSSBytes), p.State, p.Command)
+	}
+}
+
+// footerLines lists the keys, wrapped to the screen width.
+func footerLines(s *screen, toggles []Toggle) []string {
+	parts := []string{"q quit", "+/- interval", "s sort", "p pause"}
+	for i, t := range toggles {
+		if i >= maxToggleDisplay {
+			break
+		}
+		label := fmt.Sprintf("%d %s", i+1, t.ID)
+		if !t.Enabled {
+			label += " (off)"
+		}
+		parts = append(parts, label)
+	}
+
+	var lines []string
+	line, width := "", 0
+	for i, part := range parts {
+		if width > 0 && width+2+len(part) > s.width {
+			lines = append(lines, line)
+			line, width = "", 0
+		}
+		if width > 0 {
+			line += "  "
+			width += 2
+		}
+		if i >= 4 && strings.HasSuffix(part, " (off)") {
+			line += s.color(ansiDim, part)
+		} else {
+			line += part
+		}
+		width += len(part)
+	}
+	return append(lines, line)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
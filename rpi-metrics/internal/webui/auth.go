@@ -0,0 +1,106 @@
+package webui
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Credentials is the JSON file given to -auth-file:
+//
+//	{
+//	  "users":  {"admin": "sha256:5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8"},
+//	  "tokens": ["long-random-token"]
+//	}
+//
+// Passwords are plain text or "sha256:" followed by the hex digest of the password.
+// A digest only keeps the password out of plain sight: it is unsalted and fast to
+// compute, so anyone who can read the file can brute-force a weak password offline.
+// Use long random passwords and keep the file private (mode 0600) either way.
+type Credentials struct {
+	Users  map[string]string `json:"users,omitempty"`
+	Tokens []string          `json:"tokens,omitempty"`
+}
+
+// LoadCredentials reads a credentials file. At least one user or token is required.
+func LoadCredentials(path string) (Credentials, error) {
+	var c Credentials
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return c, fmt.Errorf("read auth file: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return c, fmt.Errorf("parse auth file %s: %w", path, err)
+	}
+	if len(c.Users) == 0 && len(c.Tokens) == 0 {
+		return c, fmt.Errorf("auth file %s: no users or tokens", path)
+	}
+	for user, pw := range c.Users {
+		if user == "" || pw == "" {
+			return c, fmt.Errorf("auth file %s: empty user or password", path)
+		}
+		if digest, ok := strings.CutPrefix(pw, "sha256:"); ok {
+			if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+				return c, fmt.Errorf("auth file %s: user %s: bad sha256 digest", path, user)
+			}
+		}
+	}
+	return c, nil
+}
+
+// RequireAuth accepts requests with valid HTTP basic auth or a bearer token and
+// answers everything else with 401. When limit is set, rejected requests count
+// against it and clients over the limit get 429, so passwords cannot be guessed
+// faster than the limit allows.
+func RequireAuth(c Credentials, limit *RateLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.allowed(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if limit != nil && !limit.allowRequest(r) {
+			limit.reject(w)
+			return
+		}
+		if len(c.Users) > 0 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="rpi-metrics", charset="UTF-8"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="rpi-metrics"`)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+func (c Credentials) allowed(r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && token != "" {
+		for _, want := range c.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+				return true
+			}
+		}
+		return false
+	}
+
+	user, pw, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	want, ok := c.Users[user]
+	if !ok {
+		return false
+	}
+	if digest, ok := strings.CutPrefix(want, "sha256:"); ok {
+		sum := sha256.Sum256([]byte(pw))
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(digest))) == 1
+	}
+	return subtle.ConstantTimeCompare([]byte(pw), []byte(want)) == 1
+}
@@ -0,0 +1,112 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sha256 of "password"
+const passwordDigest = "sha256:5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8"
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestRequireAuth(t *testing.T) {
+	c := Credentials{
+		Users:  map[string]string{"admin": passwordDigest, "pi": "plain-pw"},
+		Tokens: []string{"long-random-token"},
+	}
+	upper := Credentials{Users: map[string]string{"admin": "sha256:" + strings.ToUpper(passwordDigest[len("sha256:"):])}}
+	tests := []struct {
+		name  string
+		creds Credentials
+		set   func(r *http.Request)
+		want  int
+	}{
+		{"no credentials", c, func(r *http.Request) {}, http.StatusUnauthorized},
+		{"digest password", c, func(r *http.Request) { r.SetBasicAuth("admin", "password") }, http.StatusOK},
+		{"wrong password", c, func(r *http.Request) { r.SetBasicAuth("admin", "passw0rd") }, http.StatusUnauthorized},
+		{"digest given as the password", c, func(r *http.Request) { r.SetBasicAuth("admin", passwordDigest) }, http.StatusUnauthorized},
+		{"upper-case digest", upper, func(r *http.Request) { r.SetBasicAuth("admin", "password") }, http.StatusOK},
+		{"plain password", c, func(r *http.Request) { r.SetBasicAuth("pi", "plain-pw") }, http.StatusOK},
+		{"unknown user", c, func(r *http.Request) { r.SetBasicAuth("root", "plain-pw") }, http.StatusUnauthorized},
+		{"token", c, func(r *http.Request) { r.Header.Set("Authorization", "Bearer long-random-token") }, http.StatusOK},
+		{"wrong token", c, func(r *http.Request) { r.Header.Set("Authorization", "Bearer short") }, http.StatusUnauthorized},
+		{"password as token", c, func(r *http.Request) { r.Header.Set("Authorization", "Bearer plain-pw") }, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/metrics", nil)
+		tt.set(req)
+		rec := httptest.NewRecorder()
+		RequireAuth(tt.creds, nil, okHandler).ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if rec.Code == http.StatusUnauthorized && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Basic ") {
+			t.Errorf("%s: WWW-Authenticate = %q, want a basic auth challenge", tt.name, rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	RequireAuth(Credentials{Tokens: []string{"t"}}, nil, okHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := rec.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, "Bearer ") {
+		t.Errorf("tokens only: WWW-Authenticate = %q, want a bearer challenge", got)
+	}
+}
+
+func TestRequireAuthLimitsFailures(t *testing.T) {
+	c := Credentials{Users: map[string]string{"admin": "plain-pw"}}
+	h := RequireAuth(c, &RateLimiter{Rate: 0.001, Burst: 2}, okHandler)
+	do := func(pw string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth("admin", pw)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	got := []int{do("guess1"), do("guess2"), do("guess3"), do("plain-pw")}
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusOK}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d: status %d, want %d", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestLoadCredentials(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, content, wantErr string
+	}{
+		{"users and tokens", `{"users":{"admin":"` + passwordDigest + `"},"tokens":["t"]}`, ""},
+		{"tokens only", `{"tokens":["t"]}`, ""},
+		{"empty", `{}`, "no users or tokens"},
+		{"unknown field", `{"user":{"admin":"pw"}}`, "unknown field"},
+		{"empty password", `{"users":{"admin":""}}`, "empty user or password"},
+		{"short digest", `{"users":{"admin":"sha256:5e88"}}`, "bad sha256 digest"},
+		{"not hex", `{"users":{"admin":"sha256:` + strings.Repeat("z", 64) + `"}}`, "bad sha256 digest"},
+		{"not JSON", `users: admin`, "parse auth file"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".json")
+		if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadCredentials(path)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: error %v, want it to contain %q", tt.name, err, tt.wantErr)
+		}
+	}
+	if _, err := LoadCredentials(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("missing file: no error")
+	}
+}
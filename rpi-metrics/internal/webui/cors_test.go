@@ -0,0 +1,58 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowed   []string
+		method    string
+		origin    string
+		preflight bool
+		wantCode  int
+		wantAllow string
+		wantVary  string
+	}{
+		{"same origin", nil, http.MethodGet, "", false, http.StatusOK, "", ""},
+		{"no origins allowed", nil, http.MethodGet, "https://evil.example", false, http.StatusOK, "", ""},
+		{"listed origin", []string{"https://dash.example"}, http.MethodGet, "https://dash.example", false, http.StatusOK, "https://dash.example", "Origin"},
+		{"unlisted origin", []string{"https://dash.example"}, http.MethodGet, "https://evil.example", false, http.StatusOK, "", ""},
+		{"any origin", []string{"*"}, http.MethodGet, "https://evil.example", false, http.StatusOK, "*", ""},
+		{"preflight", []string{"https://dash.example"}, http.MethodOptions, "https://dash.example", true, http.StatusNoContent, "https://dash.example", "Origin"},
+		// Passed on, so auth still answers it.
+		{"preflight from an unlisted origin", []string{"https://dash.example"}, http.MethodOptions, "https://evil.example", true, http.StatusUnauthorized, "", ""},
+		{"OPTIONS without a requested method", []string{"*"}, http.MethodOptions, "https://dash.example", false, http.StatusUnauthorized, "*", ""},
+	}
+	for _, tt := range tests {
+		h := CORS(tt.allowed, RequireAuth(Credentials{Tokens: []string{"t"}}, nil, okHandler))
+		req := httptest.NewRequest(tt.method, "/api/metrics", nil)
+		if tt.method == http.MethodGet {
+			req.Header.Set("Authorization", "Bearer t")
+		}
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.preflight {
+			req.Header.Set("Access-Control-Request-Method", "GET")
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.wantCode)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllow {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want %q", tt.name, got, tt.wantAllow)
+		}
+		if got := rec.Header().Get("Vary"); got != tt.wantVary {
+			t.Errorf("%s: Vary = %q, want %q", tt.name, got, tt.wantVary)
+		}
+		if tt.wantCode == http.StatusNoContent && rec.Header().Get("Access-Control-Allow-Headers") != "Authorization" {
+			t.Errorf("%s: preflight does not allow the Authorization header", tt.name)
+		}
+	}
+}
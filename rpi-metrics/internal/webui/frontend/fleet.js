@@ -0,0 +1,150 @@
+// Configuration
+const CONFIG = {
+    apiEndpoint: '/api/hosts',
+    refreshInterval: 5000, // 5 seconds
+};
+
+// State
+let state = {
+    isPaused: false,
+    intervalId: null,
+};
+
+// DOM Elements
+const elements = {
+    connectionStatus: document.getElementById('connection-status'),
+    fleetSummary: document.getElementById('fleet-summary'),
+    lastUpdate: document.getElementById('last-update'),
+    fleetGrid: document.getElementById('fleet-grid'),
+    pauseBtn: document.getElementById('pause-btn'),
+    refreshInterval: document.getElementById('refresh-interval'),
+};
+
+// Initialize
+document.addEventListener('DOMContentLoaded', () => {
+    elements.refreshInterval.textContent = CONFIG.refreshInterval / 1000;
+    elements.pauseBtn.addEventListener('click', togglePause);
+    startPolling();
+});
+
+// Start polling for the fleet overview
+function startPolling() {
+    fetchHosts(); // Fetch immediately
+    state.intervalId = setInterval(fetchHosts, CONFIG.refreshInterval);
+}
+
+// Toggle pause/resume
+function togglePause() {
+    state.isPaused = !state.isPaused;
+
+    if (state.isPaused) {
+        clearInterval(state.intervalId);
+        elements.pauseBtn.textContent = 'Resume';
+        elements.pauseBtn.classList.add('paused');
+    } else {
+        startPolling();
+        elements.pauseBtn.textContent = 'Pause';
+        elements.pauseBtn.classList.remove('paused');
+    }
+}
+
+// Fetch the fleet overview from the hub
+async function fetchHosts() {
+    try {
+        const response = await fetch(CONFIG.apiEndpoint);
+
+        if (!response.ok) {
+            throw new Error(`HTTP error! status: ${response.status}`);
+        }
+
+        const hosts = await response.json();
+        updateFleet(hosts);
+        setConnectionStatus(true);
+
+    } catch (error) {
+        console.error('Failed to fetch hosts:', error);
+        setConnectionStatus(false);
+    }
+}
+
+// Update connection status indicator
+function setConnectionStatus(isOnline) {
+    elements.connectionStatus.className = `status-indicator ${isOnline ? 'online' : 'offline'}`;
+}
+
+// Render one tile per host; the hub sorts them worst status first
+function updateFleet(hosts) {
+    elements.lastUpdate.textContent = `Last update: ${new Date().toLocaleTimeString()}`;
+
+    const counts = {};
+    hosts.forEach(h => { counts[h.status] = (counts[h.status] || 0) + 1; });
+    const notOK = ['critical', 'warning', 'stale']
+        .filter(status => counts[status])
+        .map(status => `${counts[status]} ${status}`);
+    elements.fleetSummary.textContent = `${hosts.length} hosts${notOK.length ? ` (${notOK.join(', ')})` : ''}`;
+
+    if (hosts.length === 0) {
+        elements.fleetGrid.innerHTML = '<p class="error-message">No agents configured</p>';
+        return;
+    }
+
+    elements.fleetGrid.innerHTML = hosts.map(h => {
+        // A zero last_seen (0001-01-01) means the agent never pushed
+        let lastSeen = '';
+        if (h.status === 'stale') {
+            lastSeen = h.last_seen.startsWith('0001') ? 'never pushed' : `last seen ${formatAgo(new Date(h.last_seen))}`;
+        }
+
+        const problems = [
+            ...(h.alerts || []).map(a => a.message),
+            ...(h.errors || []).map(e => `${e.collector}: ${e.error}`),
+        ];
+
+        return `
+            <a class="card host-tile ${h.status}" href="/?host=${encodeURIComponent(h.host)}">
+                <div class="mount-header">
+                    <span class="mount-path">${escapeHTML(h.host)}</span>
+                    <span class="mount-percent ${statusClass(h.status)}">${h.status}</span>
+                </div>
+                <div class="mount-details">
+                    <span>CPU: ${formatOptional(h.cpu_temperature, v => `${v.toFixed(1)}°C`)} / ${formatOptional(h.cpu_utilization, v => `${v.toFixed(1)}%`)}</span>
+                    <span>Root disk: ${formatOptional(h.root_used_percent, v => `${v.toFixed(1)}% used`)}</span>
+                    ${lastSeen ? `<span>${lastSeen}</span>` : ''}
+                </div>
+                ${problems.length ? `<ul class="host-alerts">${problems.map(p => `<li>${escapeHTML(p)}</li>`).join('')}</ul>` : ''}
+            </a>
+        `;
+    }).join('');
+}
+
+function statusClass(status) {
+    switch (status) {
+        case 'ok':
+            return 'temp-cool';
+        case 'warning':
+            return 'temp-warm';
+        case 'critical':
+            return 'temp-hot';
+        default:
+            return '';
+    }
+}
+
+function formatOptional(value, format) {
+    return value === undefined || value === null ? '--' : format(value);
+}
+
+// Format a past time as a coarse "x ago"
+function formatAgo(date) {
+    const seconds = Math.max(0, (Date.now() - date.getTime()) / 1000);
+    if (seconds < 120) return `${Math.round(seconds)} s ago`;
+    if (seconds < 7200) return `${Math.round(seconds / 60)} min ago`;
+    return `${Math.round(seconds / 3600)} h ago`;
+}
+
+// Escape text for use in innerHTML
+function escapeHTML(text) {
+    const div = document.createElement('div');
+    div.textContent = text;
+    return div.innerHTML;
+}
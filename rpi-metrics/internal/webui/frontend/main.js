@@ -0,0 +1,508 @@
+// Served by rpi-metrics-hub as /?host=<name>: show that host's latest push
+const HOST = new URLSearchParams(window.location.search).get('host');
+
+// Configuration
+const CONFIG = {
+    apiEndpoint: HOST ? `/api/hosts/${encodeURIComponent(HOST)}/metrics` : '/api/metrics',
+    descriptorsEndpoint: '/api/descriptors',
+    refreshInterval: 2000, // 2 seconds
+    maxTemp: 85, // Maximum temperature for progress bar
+};
+
+// State
+let state = {
+    isPaused: false,
+    intervalId: null,
+    lastData: null,
+    descriptors: {}, // metric name -> descriptor (unit, range, help)
+};
+
+// DOM Elements
+const elements = {
+    connectionStatus: document.getElementById('connection-status'),
+    lastUpdate: document.getElementById('last-update'),
+    cpuTempValue: document.getElementById('cpu-temp-value'),
+    cpuTempBar: document.getElementById('cpu-temp-bar'),
+    cpuTempCard: document.getElementById('cpu-temp-card'),
+    cpuUtilValue: document.getElementById('cpu-util-value'),
+    cpuUtilBar: document.getElementById('cpu-util-bar'),
+    cpuCores: document.getElementById('cpu-cores'),
+    coolingValue: document.getElementById('cooling-value'),
+    coolingMax: document.getElementById('cooling-max'),
+    coolingStatus: document.getElementById('cooling-status'),
+    storageMounts: document.getElementById('storage-mounts'),
+    wearDevices: document.getElementById('wear-devices'),
+    networkInterfaces: document.getElementById('network-interfaces'),
+    hostName: document.getElementById('host-name'),
+    pauseBtn: document.getElementById('pause-btn'),
+    refreshInterval: document.getElementById('refresh-interval'),
+};
+
+// Initialize
+document.addEventListener('DOMContentLoaded', () => {
+    elements.refreshInterval.textContent = CONFIG.refreshInterval / 1000;
+    elements.pauseBtn.addEventListener('click', togglePause);
+    if (HOST) {
+        document.title = `${HOST} - Raspberry Pi Metrics`;
+        elements.hostName.innerHTML = `<a href="/">← Fleet</a> / ${escapeHTML(HOST)}`;
+    }
+    fetchDescriptors();
+    startPolling();
+});
+
+// Fetch metric descriptors once; values are formatted by their canonical unit
+async function fetchDescriptors() {
+    try {
+        const response = await fetch(CONFIG.descriptorsEndpoint);
+        if (!response.ok) {
+            throw new Error(`HTTP error! status: ${response.status}`);
+        }
+        const descriptors = await response.json();
+        descriptors.forEach(d => { state.descriptors[d.name] = d; });
+    } catch (error) {
+        console.error('Failed to fetch descriptors:', error);
+    }
+}
+
+// Start polling for metrics
+function startPolling() {
+    fetchMetrics(); // Fetch immediately
+    state.intervalId = setInterval(fetchMetrics, CONFIG.refreshInterval);
+}
+
+// Toggle pause/resume
+function togglePause() {
+    state.isPaused = !state.isPaused;
+    
+    if (state.isPaused) {
+        clearInterval(state.intervalId);
+        elements.pauseBtn.textContent = 'Resume';
+        elements.pauseBtn.classList.add('paused');
+    } else {
+        startPolling();
+        elements.pauseBtn.textContent = 'Pause';
+        elements.pauseBtn.classList.remove('paused');
+    }
+}
+
+// Fetch metrics from API
+async function fetchMetrics() {
+    try {
+        const response = await fetch(CONFIG.apiEndpoint);
+        
+        if (!response.ok) {
+            throw new Error(`HTTP error! status: ${response.status}`);
+        }
+        
+        const data = await response.json();
+        state.lastData = data;
+        
+        updateUI(data);
+        setConnectionStatus(true);
+        
+    } catch (error) {
+        console.error('Failed to fetch metrics:', error);
+        setConnectionStatus(false);
+    }
+}
+
+// Update UI with new data
+function updateUI(data) {
+    updateLastUpdateTime(data.timestamp);
+    
+    if (data.metrics) {
+        updateCPUTemp(data.metrics.cpu_temp);
+        updateCPUUtilization(data.metrics.cpu_utilization);
+        updateCooling(data.metrics.cpu_cooling_device);
+        updateStorage(data.metrics.storage_usage);
+        updateWear(data.metrics.mmc_health);
+        updateNetwork(data.metrics.network);
+    } else if (data.samples) {
+        // The hub serves one flat list of samples per host
+        const byPrefix = prefix => data.samples.filter(s => s.name.startsWith(prefix));
+        updateCPUTemp(byPrefix('cpu_temperature'));
+        updateCPUUtilization(byPrefix('cpu_utilization'));
+        updateCooling(byPrefix('cooling_state'));
+        updateStorage(byPrefix('storage_'));
+        updateWear(byPrefix('mmc_'));
+        updateNetwork([...byPrefix('network_'), ...byPrefix('wifi_')]);
+    }
+}
+
+// Update connection status indicator
+function setConnectionStatus(isOnline) {
+    elements.connectionStatus.className = `status-indicator ${isOnline ? 'online' : 'offline'}`;
+}
+
+// Update last update timestamp
+function updateLastUpdateTime(timestamp) {
+    const date = new Date(timestamp);
+    const timeStr = date.toLocaleTimeString();
+    elements.lastUpdate.textContent = `Last update: ${timeStr}`;
+}
+
+// Update CPU Temperature
+function updateCPUTemp(samples) {
+    if (!samples || samples.length === 0) {
+        elements.cpuTempValue.textContent = '--';
+        return;
+    }
+    
+    const tempSample = samples.find(s => s.name === 'cpu_temperature');
+    if (!tempSample) return;
+    
+    const temp = tempSample.value;
+    const tempRounded = temp.toFixed(1);
+    
+    elements.cpuTempValue.textContent = tempRounded;
+    
+    // Update progress bar
+    const percentage = Math.min((temp / CONFIG.maxTemp) * 100, 100);
+    elements.cpuTempBar.style.width = `${percentage}%`;
+    
+    // Update temperature color class
+    elements.cpuTempValue.className = '';
+    if (temp < 50) {
+        elements.cpuTempValue.classList.add('temp-cool');
+    } else if (temp < 70) {
+        elements.cpuTempValue.classList.add('temp-warm');
+    } else {
+        elements.cpuTempValue.classList.add('temp-hot');
+    }
+}
+
+// Update CPU Utilization
+function updateCPUUtilization(samples) {
+    if (!samples || samples.length === 0) {
+        elements.cpuUtilValue.textContent = '--';
+        elements.cpuCores.innerHTML = '';
+        return;
+    }
+    
+    // Find total CPU utilization
+    const totalSample = samples.find(s => s.labels && s.labels.cpu === 'total');
+    if (totalSample) {
+        const util = totalSample.value;
+        elements.cpuUtilValue.textContent = util.toFixed(1);
+        elements.cpuUtilBar.style.width = `${Math.min(util, 100)}%`;
+    }
+    
+    // Update individual cores
+    const coreSamples = samples.filter(s => s.labels && s.labels.cpu && s.labels.cpu.startsWith('cpu'));
+    
+    if (coreSamples.length > 0) {
+        elements.cpuCores.innerHTML = coreSamples.map(sample => {
+            const cpuName = sample.labels.cpu;
+            const value = sample.value.toFixed(1);
+            return `
+                <div class="core-item">
+                    <div class="core-name">${cpuName.toUpperCase()}</div>
+                    <div class="core-value">${value}%</div>
+                    <div class="mini-bar">
+                        <div class="mini-fill" style="width: ${Math.min(sample.value, 100)}%"></div>
+                    </div>
+                </div>
+            `;
+        }).join('');
+    }
+}
+
+// Update Cooling State
+function updateCooling(samples) {
+    if (!samples || samples.length === 0) {
+        elements.coolingValue.textContent = '--';
+        return;
+    }
+    
+    const coolingSample = samples.find(s => s.name === 'cooling_state');
+    if (!coolingSample) return;
+    
+    const coolingLevel = coolingSample.value;
+    const maxState = state.descriptors.cooling_state?.range?.max ?? 4;
+    elements.coolingValue.textContent = formatValue(coolingLevel, coolingSample.unit);
+    elements.coolingMax.textContent = `/ ${maxState}`;
+    
+    // Update cooling level indicators
+    const levels = document.querySelectorAll('.cooling-level');
+    levels.forEach(level => {
+        const levelNum = parseInt(level.dataset.level);
+        if (levelNum <= coolingLevel) {
+            level.classList.add('active');
+        } else {
+            level.classList.remove('active');
+        }
+    });
+    
+    // Update status text
+    const statusTexts = ['Fan: Off', 'Fan: Low', 'Fan: Medium', 'Fan: High', 'Fan: Maximum'];
+    elements.coolingStatus.textContent = statusTexts[Math.min(coolingLevel, statusTexts.length - 1)];
+}
+
+// Update Storage
+function updateStorage(samples) {
+    if (!samples || samples.length === 0) {
+        elements.storageMounts.innerHTML = '<p class="error-message">No storage data available</p>';
+        return;
+    }
+    
+    // Group samples by mount point
+    const mounts = {};
+    
+    samples.forEach(sample => {
+        const mountPoint = sample.labels?.mount_point || sample.labels?.path || 'unknown';
+        
+        if (!mounts[mountPoint]) {
+            mounts[mountPoint] = {};
+        }
+        
+        mounts[mountPoint][sample.name] = sample;
+    });
+    
+    // Generate HTML for each mount
+    const mountsHTML = Object.entries(mounts).map(([mountPoint, data]) => {
+        const usedPercent = data.storage_used_percent?.value || 0;
+        const totalBytes = data.storage_total_bytes?.value || 0;
+        const usedBytes = data.storage_used_bytes?.value || 0;
+        const availBytes = data.storage_avail_bytes?.value || 0;
+        
+        const usedStr = formatBytes(usedBytes);
+        const totalStr = formatBytes(totalBytes);
+        const availStr = formatBytes(availBytes);
+        
+        // Determine color based on usage
+        let colorClass = '';
+        if (usedPercent > 90) {
+            colorClass = 'temp-hot';
+        } else if (usedPercent > 70) {
+            colorClass = 'temp-warm';
+        }
+        
+        return `
+            <div class="mount-item">
+                <div class="mount-header">
+                    <span class="mount-path">${mountPoint}</span>
+                    <span class="mount-percent ${colorClass}">${usedPercent.toFixed(1)}%</span>
+                </div>
+                <div class="progress-bar">
+                    <div class="progress-fill storage" style="width: ${Math.min(usedPercent, 100)}%"></div>
+                </div>
+                <div class="mount-details">
+                    <span>Used: ${usedStr}</span>
+                    <span>Free: ${availStr}</span>
+                    <span>Total: ${totalStr}</span>
+                </div>
+            </div>
+        `;
+    }).join('');
+    
+    elements.storageMounts.innerHTML = mountsHTML;
+}
+
+// Update SD card / eMMC wear
+function updateWear(samples) {
+    if (!samples || samples.length === 0) {
+        elements.wearDevices.innerHTML = '<p class="error-message">No SD card / eMMC found</p>';
+        return;
+    }
+
+    // Group samples by device
+    const devices = {};
+
+    samples.forEach(sample => {
+        const device = sample.labels?.device || 'unknown';
+
+        if (!devices[device]) {
+            devices[device] = { lifeTime: [] };
+        }
+
+        if (sample.name === 'mmc_life_time_used_percent') {
+            devices[device].lifeTime.push(sample);
+        } else {
+            devices[device][sample.name] = sample;
+        }
+    });
+
+    const eolTexts = { 1: 'Normal', 2: 'Warning', 3: 'Urgent' };
+
+    const devicesHTML = Object.entries(devices).map(([device, data]) => {
+        const info = data.mmc_info?.labels || {};
+        const worstLife = Math.max(0, ...data.lifeTime.map(s => s.value));
+        const eol = data.mmc_pre_eol_info?.value;
+        const written = data.mmc_bytes_written_total?.value;
+        const perDay = data.mmc_estimated_writes_per_day_bytes?.value;
+
+        // Determine color based on wear
+        let colorClass = '';
+        if (eol >= 3 || worstLife >= 90) {
+            colorClass = 'temp-hot';
+        } else if (eol === 2 || worstLife >= 70) {
+            colorClass = 'temp-warm';
+        }
+
+        const lifeText = data.lifeTime.length > 0
+            ? data.lifeTime.map(s => `${s.labels.type}: ${s.value.toFixed(0)}%`).join(' / ')
+            : 'n/a';
+
+        return `
+            <div class="mount-item">
+                <div class="mount-header">
+                    <span class="mount-path">${device}${info.name ? ` (${info.name})` : ''}</span>
+                    <span class="mount-percent ${colorClass}">${eol ? eolTexts[eol] || eol : '--'}</span>
+                </div>
+                <div class="progress-bar">
+                    <div class="progress-fill storage" style="width: ${Math.min(worstLife, 100)}%"></div>
+                </div>
+                <div class="mount-details">
+                    <span>Life used: ${lifeText}</span>
+                    <span>Written: ${written !== undefined ? formatBytes(written) : '--'}</span>
+                    <span>Per day: ${perDay !== undefined ? formatBytes(perDay) : '--'}</span>
+                </div>
+            </div>
+        `;
+    }).join('');
+
+    elements.wearDevices.innerHTML = devicesHTML;
+}
+
+// Update network interfaces and Wi-Fi signal
+function updateNetwork(samples) {
+    if (!samples || samples.length === 0) {
+        elements.networkInterfaces.innerHTML = '<p class="error-message">No network data available</p>';
+        return;
+    }
+
+    // Group samples by interface
+    const interfaces = {};
+
+    samples.forEach(sample => {
+        const iface = sample.labels?.interface || 'unknown';
+
+        if (!interfaces[iface]) {
+            interfaces[iface] = { bitrate: {} };
+        }
+
+        if (sample.name === 'wifi_bitrate_bits_per_second') {
+            interfaces[iface].bitrate[sample.labels.direction] = sample.value;
+        } else {
+            interfaces[iface][sample.name] = sample;
+        }
+    });
+
+    const interfacesHTML = Object.entries(interfaces).map(([iface, data]) => {
+        const up = data.network_up?.value === 1;
+        const signal = data.wifi_signal_dbm?.value;
+        const ssid = data.wifi_info?.labels?.ssid;
+        const changes = data.network_carrier_changes_total?.value;
+        const speed = data.network_speed_bits_per_second?.value;
+
+        let status = up ? 'Up' : 'Down';
+        let colorClass = up ? 'temp-cool' : 'temp-hot';
+        if (up && signal !== undefined) {
+            status = `${signal.toFixed(0)} dBm`;
+            const bars = signalBars(signal);
+            colorClass = bars >= 3 ? 'temp-cool' : bars === 2 ? 'temp-warm' : 'temp-hot';
+        }
+
+        const details = [];
+        if (ssid) details.push(`SSID: ${escapeHTML(ssid)}`);
+        if (data.bitrate.rx !== undefined) details.push(`RX: ${formatBitrate(data.bitrate.rx)}`);
+        if (data.bitrate.tx !== undefined) details.push(`TX: ${formatBitrate(data.bitrate.tx)}`);
+        if (speed !== undefined) details.push(`Speed: ${formatBitrate(speed)}`);
+        if (data.wifi_link_quality) details.push(`Quality: ${data.wifi_link_quality.value.toFixed(0)}/70`);
+        if (changes !== undefined) details.push(`Link changes: ${changes.toFixed(0)}`);
+
+        return `
+            <div class="mount-item">
+                <div class="mount-header">
+                    <span class="mount-path">${escapeHTML(iface)}${signal !== undefined ? renderSignalBars(signalBars(signal)) : ''}</span>
+                    <span class="mount-percent ${colorClass}">${status}</span>
+                </div>
+                <div class="mount-details">
+                    ${details.map(d => `<span>${d}</span>`).join('')}
+                </div>
+            </div>
+        `;
+    }).join('');
+
+    elements.networkInterfaces.innerHTML = interfacesHTML;
+}
+
+// Map a Wi-Fi signal level to 0-4 bars
+function signalBars(dbm) {
+    if (dbm >= -55) return 4;
+    if (dbm >= -67) return 3;
+    if (dbm >= -75) return 2;
+    if (dbm >= -85) return 1;
+    return 0;
+}
+
+function renderSignalBars(bars) {
+    const items = [1, 2, 3, 4].map(i => `<span class="signal-bar${i <= bars ? ' active' : ''}"></span>`).join('');
+    return `<span class="signal-bars" title="${bars}/4">${items}</span>`;
+}
+
+// Format bits per second
+function formatBitrate(bps) {
+    if (bps >= 1e9) return `${(bps / 1e9).toFixed(1)} Gbit/s`;
+    return `${(bps / 1e6).toFixed(1)} Mbit/s`;
+}
+
+// Format a value according to its canonical unit (see internal/metrics/descriptor.go)
+function formatValue(value, unit) {
+    switch (unit) {
+        case 'bytes':
+            return formatBytes(value);
+        case 'percent':
+            return `${value.toFixed(1)}%`;
+        case 'celsius':
+            return `${value.toFixed(1)}°C`;
+        case 'volts':
+            return `${value.toFixed(2)} V`;
+        case 'amperes':
+            return `${value.toFixed(3)} A`;
+        case 'dBm':
+            return `${value.toFixed(0)} dBm`;
+        case 'bits_per_second':
+            return formatBitrate(value);
+        case 'bytes_per_second':
+            return `${formatBytes(value)}/s`;
+        case 'per_second':
+            return `${value.toFixed(3)}/s`;
+        case 'seconds':
+            return formatDuration(value);
+        case 'timestamp':
+            return new Date(value * 1000).toLocaleString();
+        case 'bool':
+            return value ? 'yes' : 'no';
+        case 'state':
+        case 'count':
+            return value.toFixed(0);
+        default:
+            return unit ? `${value.toFixed(2)} ${unit}` : value.toFixed(2);
+    }
+}
+
+// Format seconds as a coarse duration
+function formatDuration(seconds) {
+    if (seconds < 3600) return `${Math.round(seconds / 60)} min`;
+    if (seconds < 172800) return `${Math.round(seconds / 3600)} h`;
+    return `${Math.round(seconds / 86400)} days`;
+}
+
+// Escape text for use in innerHTML
+function escapeHTML(text) {
+    const div = document.createElement('div');
+    div.textContent = text;
+    return div.innerHTML;
+}
+
+// Format bytes to human readable
+function formatBytes(bytes) {
+    if (bytes === 0) return '0 B';
+    
+    const k = 1024;
+    const sizes = ['B', 'KB', 'MB', 'GB', 'TB'];
+    const i = Math.floor(Math.log(bytes) / Math.log(k));
+    
+    return parseFloat((bytes / Math.pow(k, i)).toFixed(2)) + ' ' + sizes[i];
+}
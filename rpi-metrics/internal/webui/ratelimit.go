@@ -0,0 +1,89 @@
+package webui
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket per client IP: Rate requests per second on average,
+// bursts of up to Burst.
+type RateLimiter struct {
+	Rate  float64
+	Burst int
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Forget buckets of clients that have been idle this long.
+const rateLimitIdle = 10 * time.Minute
+
+// Allow reports whether a request from key may proceed now.
+func (l *RateLimiter) Allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.buckets == nil {
+		l.buckets = make(map[string]*bucket)
+	}
+	if len(l.buckets) > 1000 {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > rateLimitIdle {
+				delete(l.buckets, k)
+			}
+		}
+	}
+
+	burst := float64(max(l.Burst, 1))
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Middleware answers 429 to clients over their limit. Clients are keyed by the
+// connection's remote IP; X-Forwarded-For is not trusted.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.allowRequest(r) {
+			l.reject(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *RateLimiter) allowRequest(r *http.Request) bool {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return l.Allow(ip, time.Now())
+}
+
+func (l *RateLimiter) reject(w http.ResponseWriter) {
+	retry := 1
+	if l.Rate > 0 && l.Rate < 1 {
+		retry = int(math.Ceil(1 / l.Rate))
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retry))
+	http.Error(w, "too many requests", http.StatusTooManyRequests)
+}
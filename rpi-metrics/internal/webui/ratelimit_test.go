@@ -0,0 +1,60 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	l := &RateLimiter{Rate: 2, Burst: 3}
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		name string
+		key  string
+		at   time.Duration
+		want bool
+	}{
+		{"burst 1", "a", 0, true},
+		{"burst 2", "a", 0, true},
+		{"burst 3", "a", 0, true},
+		{"burst used up", "a", 0, false},
+		{"other client has its own bucket", "b", 0, true},
+		{"half a token refilled", "a", 250 * time.Millisecond, false},
+		{"one token refilled", "a", 500 * time.Millisecond, true},
+		{"refill is capped at Burst", "a", time.Hour, true},
+		{"capped 2", "a", time.Hour, true},
+		{"capped 3", "a", time.Hour, true},
+		{"capped, used up", "a", time.Hour, false},
+	}
+	for _, s := range steps {
+		if got := l.Allow(s.key, start.Add(s.at)); got != s.want {
+			t.Errorf("%s: Allow = %v, want %v", s.name, got, s.want)
+		}
+	}
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	h := (&RateLimiter{Rate: 0.1, Burst: 1}).Middleware(okHandler)
+	do := func(remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/metrics", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-Forwarded-For", remote+"0") // not trusted
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("192.0.2.1:4000"); rec.Code != http.StatusOK {
+		t.Errorf("first request: status %d", rec.Code)
+	}
+	// Same IP, different port.
+	rec := do("192.0.2.1:4001")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "10" {
+		t.Errorf("over the limit: status %d, Retry-After %q; want 429 and 10", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := do("192.0.2.2:4000"); rec.Code != http.StatusOK {
+		t.Errorf("other client: status %d", rec.Code)
+	}
+}
@@ -0,0 +1,20 @@
+// Package webui embeds the dashboard frontend shared by rpi-metrics-ui (single host)
+// and rpi-metrics-hub (fleet overview with drill-down into the single-host view).
+package webui
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed frontend/*
+var frontendFS embed.FS
+
+// FS returns the frontend files rooted at the frontend directory.
+func FS() fs.FS {
+	sub, err := fs.Sub(frontendFS, "frontend")
+	if err != nil {
+		panic(err) // the embedded directory always exists
+	}
+	return sub
+}